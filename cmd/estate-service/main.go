@@ -2,12 +2,27 @@ package main
 
 import (
 	"avito/internal/config"
+	"avito/internal/lib/blobstore"
 	"avito/internal/lib/logger"
+	"avito/internal/lib/publisher"
+	"avito/internal/lib/sender"
+	"avito/internal/repositories/authRepo"
+	"avito/internal/repositories/flatRepo"
+	"avito/internal/repositories/outboxRepo"
+	"avito/internal/services/authService"
+	"avito/internal/services/flatService"
+	"avito/internal/services/outboxService"
 	"avito/internal/setup"
 	"avito/internal/storage"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 )
 
 func main() {
@@ -37,8 +52,29 @@ func main() {
 		}
 	}()
 
-	authH, houseH, flatH := setup.InitLayers(conn, cfg, log)
-	router := setup.SetupRouter(authH, houseH, flatH, log)
+	// Subscribers notifications are delivered in background
+	notifier := sender.NewAsyncSender(
+		sender.NewFileSender(cfg.Notifier.FilePath, log),
+		cfg.Notifier.Workers,
+		cfg.Notifier.QueueSize,
+		cfg.Notifier.MaxRetries,
+		cfg.Notifier.RetryDelay,
+		log,
+	)
+	defer notifier.Close()
+
+	// Flat photos are kept on the local disk and served by this service
+	photos := blobstore.NewLocalStore(cfg.Media.Dir, cfg.Media.URLPrefix)
+
+	// Revoked access tokens are checked in memory and synced with the database in background
+	denylist := authService.NewDenylist(authRepo.NewRepository(conn, log), cfg.Auth.DenylistRefresh, log)
+	if err := denylist.Reload(context.Background()); err != nil {
+		log.Error("Could not load revoked tokens", "error", err)
+		panic(err)
+	}
+
+	authH, houseH, flatH, searchH := setup.InitLayers(conn, cfg, notifier, photos, denylist, log)
+	router := setup.SetupRouter(authH, houseH, flatH, searchH, photos, log)
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
@@ -48,12 +84,59 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Outbox relay publishes domain events written by repositories
+	relay := outboxService.NewRelay(
+		outboxRepo.NewRepository(conn, log),
+		publisher.NewLogPublisher(log),
+		cfg.Outbox.PollInterval,
+		cfg.Outbox.BatchSize,
+		log,
+	)
+
+	// Reaper returns flats with expired moderation lease to the queue
+	reaper := flatService.NewLeaseReaper(flatRepo.NewRepository(conn, log), cfg.Moderation.ReaperInterval, log)
+
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		relay.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		reaper.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		denylist.Run(ctx)
+	}()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		<-ctx.Done()
+		log.Info("Shutting down server...")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.Timeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error("Server shutdown error", "error", err)
+		}
+	}()
+
 	log.Info("Starting server on port ", slog.String("port", cfg.Server.Port))
 
 	err = srv.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Error("Server startup error", "error", err)
 	}
+
+	stop()
+	<-serverDone
+	workers.Wait()
 }
 
 /*
@@ -10,10 +10,14 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Logger   LoggerConfig   `yaml:"logger"`
-	Auth     AuthConfig     `yaml:"auth"`
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Logger     LoggerConfig     `yaml:"logger"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Notifier   NotifierConfig   `yaml:"notifier"`
+	Outbox     OutboxConfig     `yaml:"outbox"`
+	Moderation ModerationConfig `yaml:"moderation"`
+	Media      MediaConfig      `yaml:"media"`
 }
 
 type ServerConfig struct {
@@ -34,8 +38,49 @@ type LoggerConfig struct {
 	Level string `yaml:"level"`
 }
 
+// AuthConfig DenylistRefresh is how often revoked access tokens are reloaded from the database
 type AuthConfig struct {
-	JWTSecret string `yaml:"jwt_secret"`
+	JWTSecret       string        `yaml:"jwt_secret"`
+	AccessTTL       time.Duration `yaml:"access_ttl" env-default:"15m"`
+	RefreshTTL      time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+	DenylistRefresh time.Duration `yaml:"denylist_refresh" env-default:"30s"`
+	InviteTTL       time.Duration `yaml:"invite_ttl" env-default:"72h"`
+	Lockout         LockoutConfig `yaml:"lockout"`
+}
+
+// LockoutConfig After AccountAttempts failed logins of an account, or IPAttempts from one address,
+// each further failure locks them out for BaseDelay, doubling up to MaxDelay. Failures are forgotten
+// after Window without one.
+type LockoutConfig struct {
+	AccountAttempts int           `yaml:"account_attempts" env-default:"5"`
+	IPAttempts      int           `yaml:"ip_attempts" env-default:"50"`
+	BaseDelay       time.Duration `yaml:"base_delay" env-default:"1s"`
+	MaxDelay        time.Duration `yaml:"max_delay" env-default:"15m"`
+	Window          time.Duration `yaml:"window" env-default:"15m"`
+}
+
+type NotifierConfig struct {
+	FilePath   string        `yaml:"file_path"`
+	Workers    int           `yaml:"workers" env-default:"2"`
+	QueueSize  int           `yaml:"queue_size" env-default:"100"`
+	MaxRetries int           `yaml:"max_retries" env-default:"3"`
+	RetryDelay time.Duration `yaml:"retry_delay" env-default:"1s"`
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+}
+
+type ModerationConfig struct {
+	LeaseTTL       time.Duration `yaml:"lease_ttl" env-default:"30m"`
+	ReaperInterval time.Duration `yaml:"reaper_interval" env-default:"1m"`
+}
+
+// MediaConfig is where uploaded flat photos are kept and the URL path they are served under
+type MediaConfig struct {
+	Dir       string `yaml:"dir" env-default:"./media"`
+	URLPrefix string `yaml:"url_prefix" env-default:"/media"`
 }
 
 func MustLoad() *Config {
@@ -2,7 +2,6 @@ package custommiddleware
 
 import (
 	"avito/internal/domain/models"
-	"avito/internal/handlers/authHandler"
 	"context"
 	"log/slog"
 	"net/http"
@@ -13,7 +12,13 @@ type ContextKey string
 
 const ClaimsContextKey ContextKey = "claims"
 
-func AuthMiddleware(authH authHandler.AuthHandler, logger *slog.Logger) func(next http.Handler) http.Handler {
+// TokenValidator checks access tokens. It is implemented by the auth handler.
+type TokenValidator interface {
+	ValidateToken(tokenStr string) (*models.Claims, error)
+	IsTokenRevoked(claims *models.Claims) bool
+}
+
+func AuthMiddleware(authH TokenValidator, logger *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			const op = "middleware.AuthMiddleware"
@@ -39,6 +44,12 @@ func AuthMiddleware(authH authHandler.AuthHandler, logger *slog.Logger) func(nex
 				return
 			}
 
+			if authH.IsTokenRevoked(claims) {
+				logger.Warn("Revoked token", slog.String("op", op), slog.String("user_id", claims.UserID))
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
 			// Put claims in context
 			ctx := context.WithValue(r.Context(), ClaimsContextKey, claims)
 			r = r.WithContext(ctx)
@@ -48,7 +59,7 @@ func AuthMiddleware(authH authHandler.AuthHandler, logger *slog.Logger) func(nex
 	}
 }
 
-func RoleMiddleware(allowedRoles []string, logger *slog.Logger) func(next http.Handler) http.Handler {
+func RoleMiddleware(allowedRoles []models.Role, logger *slog.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			const op = "middleware.RoleMiddleware"
@@ -62,14 +73,14 @@ func RoleMiddleware(allowedRoles []string, logger *slog.Logger) func(next http.H
 
 			roleValid := false
 			for _, role := range allowedRoles {
-				if strings.EqualFold(role, claims.Role) {
+				if strings.EqualFold(string(role), string(claims.Role)) {
 					roleValid = true
 					break
 				}
 			}
 
 			if !roleValid {
-				logger.Warn("Forbidden access attempt", slog.String("op", op), slog.String("role", claims.Role))
+				logger.Warn("Forbidden access attempt", slog.String("op", op), slog.String("role", string(claims.Role)))
 				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 				return
 			}
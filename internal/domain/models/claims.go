@@ -4,8 +4,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims of an access token. RegisteredClaims.ID is the jti claim, the token ID used to revoke it.
 type Claims struct {
 	UserID string `json:"user_id"`
-	Role   string `json:"role"`
+	Role   Role   `json:"role"`
 	jwt.RegisteredClaims
 }
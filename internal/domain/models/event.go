@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventFlatCreated       = "flat.created"
+	EventFlatStatusChanged = "flat.status_changed"
+	EventFlatUpdated       = "flat.updated"
+	EventFlatArchived      = "flat.archived"
+	EventFlatRestored      = "flat.restored"
+	EventHouseCreated      = "house.created"
+	EventHouseUpdated      = "house.updated"
+	EventHouseArchived     = "house.archived"
+	EventHouseRestored     = "house.restored"
+)
+
+// Event is a domain event stored in the outbox table.
+type Event struct {
+	ID          int64
+	Type        string
+	AggregateID int
+	Payload     json.RawMessage
+	CreatedAt   time.Time
+}
@@ -1,11 +1,186 @@
 package models
 
+import (
+	"math"
+	"time"
+)
+
+// Flat moderation statuses
+const (
+	StatusCreated      = "created"
+	StatusOnModeration = "on moderation"
+	StatusApproved     = "approved"
+	StatusDeclined     = "declined"
+)
+
+// Reasons a moderator may give when declining a flat
+const (
+	DeclineReasonIncorrectData = "incorrect_data"
+	DeclineReasonWrongPrice    = "wrong_price"
+	DeclineReasonDuplicate     = "duplicate"
+	DeclineReasonProhibited    = "prohibited"
+	DeclineReasonOther         = "other"
+)
+
+// Renovation types
+const (
+	RenovationNone     = "none"
+	RenovationCosmetic = "cosmetic"
+	RenovationEuro     = "euro"
+	RenovationDesigner = "designer"
+)
+
+// IsRenovation reports whether s is a known renovation type
+func IsRenovation(s string) bool {
+	switch s {
+	case RenovationNone, RenovationCosmetic, RenovationEuro, RenovationDesigner:
+		return true
+	}
+	return false
+}
+
+// Kinds of flat photos
+const (
+	PhotoKindPhoto     = "photo"
+	PhotoKindFloorPlan = "floor_plan"
+)
+
+// Sort keys of flat listings
+const (
+	FlatSortID    = "id"
+	FlatSortPrice = "price"
+	FlatSortRooms = "rooms"
+)
+
 type Flat struct {
-	ID          int
-	HouseID     int
-	FlatNumber  *int
-	Price       int
-	Rooms       int
-	Status      string
-	ModeratorID *string
+	ID                  int
+	HouseID             int
+	FlatNumber          *int
+	Price               int
+	Rooms               int
+	Status              string
+	ModeratorID         *string
+	ModerationExpiresAt *time.Time
+	CreatedAt           time.Time
+	CreatedBy           *string
+	DeclineReason       *string // set only while the flat is declined
+	DeclineComment      *string
+	ArchivedAt          *time.Time
+	PreviousPrice       *int // the price before the last change
+	PriceChangedAt      *time.Time
+	FlatDetails
+	Photos []FlatPhoto // in display order
+}
+
+// FlatPhoto is an image attached to a flat. Key and ThumbnailKey locate the image and its thumbnail in the blob store.
+type FlatPhoto struct {
+	ID           int
+	FlatID       int
+	Kind         string
+	Key          string
+	ThumbnailKey string
+	URL          string
+	ThumbnailURL string
+	ContentType  string
+	Size         int64
+	Position     int
+	CreatedAt    time.Time
+}
+
+// FlatDetails describe the flat beyond price and rooms. Every field is optional.
+type FlatDetails struct {
+	TotalArea   *float64 // m²
+	LivingArea  *float64 // m²
+	Floor       *int
+	TotalFloors *int
+	Balcony     *bool
+	Renovation  *string
+	Description *string
+}
+
+// PricePerSquareMeter is the price divided by the total area, rounded. ok is false if the area is unknown.
+func (f Flat) PricePerSquareMeter() (price int, ok bool) {
+	if f.TotalArea == nil || *f.TotalArea <= 0 {
+		return 0, false
+	}
+	return int(math.Round(float64(f.Price) / *f.TotalArea)), true
+}
+
+// StatusUpdate is a compare-and-set request: the flat is moved to To only if it is still in From
+// and, when From is "on moderation", is still held by ActorID.
+// LeaseTTL is how long the moderator holds the flat when To is "on moderation".
+// DeclineReason and Comment are stored on the flat when To is "declined".
+type StatusUpdate struct {
+	FlatID        int
+	From          string
+	To            string
+	ModeratorID   *string
+	ActorID       string
+	Comment       *string
+	DeclineReason *string
+	LeaseTTL      time.Duration
+}
+
+// FlatUpdate is an owner's edit of a flat: the new values of the editable fields and what changed.
+// Like StatusUpdate it is applied only if the flat is still in status From; the flat is moved to To.
+type FlatUpdate struct {
+	FlatID     int
+	From       string
+	To         string
+	EditorID   string
+	FlatNumber *int
+	Price      int
+	Rooms      int
+	FlatDetails
+	Changes map[string]FieldChange
+}
+
+// FieldChange is the old and the new value of an edited field
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// FlatEdit is a recorded owner's edit of a flat
+type FlatEdit struct {
+	ID        int64
+	FlatID    int
+	EditorID  string
+	Changes   map[string]FieldChange
+	CreatedAt time.Time
+}
+
+// PriceChange is a price the flat was offered at since ChangedAt
+type PriceChange struct {
+	Price     int
+	ChangedBy *string
+	ChangedAt time.Time
+}
+
+// StatusChange is a moderation history record of a flat
+type StatusChange struct {
+	ID             int64
+	FlatID         int
+	PreviousStatus string
+	NewStatus      string
+	ActorID        string // empty when the change was made by the service itself
+	Comment        *string
+	ChangedAt      time.Time
+}
+
+// FlatPage is a keyset page of a flat listing: flats ordered by SortBy (ties broken by id)
+// that come after the After position
+type FlatPage struct {
+	SortBy string
+	Desc   bool
+	Limit  int
+	After  *FlatCursor
+}
+
+// FlatCursor is the position of the last flat of a page
+type FlatCursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d"`
+	Value  int    `json:"v"`
+	ID     int    `json:"id"`
 }
@@ -3,10 +3,43 @@ package models
 import "time"
 
 type House struct {
-	ID            int
-	Address       string
-	YearBuilt     int
-	Builder       *string
-	CreatedAt     time.Time
-	LastFlatAdded *time.Time
+	ID                int
+	Address           string
+	NormalizedAddress string // canonical form of Address used to find duplicates, empty for old houses
+	YearBuilt         int
+	Builder           *string
+	Latitude          *float64
+	Longitude         *float64
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	LastFlatAdded     *time.Time
+	ArchivedAt        *time.Time
+}
+
+// HouseFilter selects houses in the house list. Nil fields are not filtered on.
+type HouseFilter struct {
+	YearFrom        *int
+	YearTo          *int
+	Developer       *string
+	Address         *string // substring of the address, case-insensitive
+	IncludeArchived bool
+	Limit           int
+	Offset          int
+}
+
+// GeoPoint is a WGS 84 location in degrees
+type GeoPoint struct {
+	Lat float64
+	Lon float64
+}
+
+// Valid reports whether the point is inside the coordinate ranges
+func (p GeoPoint) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// NearbyHouse is a house found around a point with the distance to it
+type NearbyHouse struct {
+	House
+	DistanceM float64
 }
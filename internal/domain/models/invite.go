@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Invite allows registering a user with Role. Only the hash of the code is stored;
+// Code is set only on a freshly minted invite.
+type Invite struct {
+	ID        int
+	Code      string
+	CodeHash  string
+	Role      Role
+	CreatedBy string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedBy    *string
+	UsedAt    *time.Time
+}
@@ -0,0 +1,24 @@
+package models
+
+// Role is the access level of a user
+type Role string
+
+const (
+	RoleClient    Role = "client"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin" // manages users and has every moderator permission
+)
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	switch r {
+	case RoleClient, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// CanModerate reports whether users with the role moderate flats and see all of them
+func (r Role) CanModerate() bool {
+	return r == RoleModerator || r == RoleAdmin
+}
@@ -0,0 +1,22 @@
+package models
+
+// FlatSearch filters flats across all houses. Nil and empty fields are not filtered on.
+type FlatSearch struct {
+	PriceFrom  *int
+	PriceTo    *int
+	Rooms      []int // any of the listed room counts
+	AreaFrom   *float64
+	AreaTo     *float64
+	FloorFrom  *int
+	FloorTo    *int
+	Balcony    *bool
+	Renovation *string
+	YearFrom   *int // year the house was built
+	YearTo     *int
+	Developer  *string
+	Address    *string // substring of the house address, case-insensitive
+	UserID     string
+	Role       Role
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored refresh token. Only the hash of the token is kept.
+// Tokens issued by rotation share the FamilyID of the token issued at login.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// TokenPair is issued at login and on refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration // lifetime of the access token
+}
@@ -1,9 +1,23 @@
 package models
 
+import "time"
+
 type User struct {
-	ID       string `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
-	Token    string `json:"token"`
+	ID                string     `json:"id"`
+	Email             string     `json:"email"`
+	Password          string     `json:"password"`
+	Role              Role       `json:"role"`
+	Token             string     `json:"token"`
+	CreatedAt         time.Time  `json:"created_at"`
+	DisabledAt        *time.Time `json:"disabled_at,omitempty"`
+	SessionsRevokedAt *time.Time `json:"-"` // access tokens issued before are rejected
+}
+
+// UserFilter selects users in the admin user list. Nil fields are not filtered on.
+type UserFilter struct {
+	Role     *Role
+	Email    *string // substring of the email, case-insensitive
+	Disabled *bool
+	Limit    int
+	Offset   int
 }
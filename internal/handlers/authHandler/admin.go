@@ -0,0 +1,150 @@
+package authHandler
+
+import (
+	"avito/internal/custommiddleware"
+	"avito/internal/domain/models"
+	"avito/internal/handlers/common"
+	"avito/internal/handlers/response"
+	"avito/internal/repositories"
+	"avito/internal/services/authService"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListUsers returns users ordered by email.
+// Ex. ?role=moderator&email=example&disabled=true&limit=20&offset=40
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	const op = "authHandler.ListUsers"
+
+	var filter models.UserFilter
+	var err error
+
+	filter.Limit, filter.Offset, err = common.ParseLimitOffset(r)
+	if err == nil {
+		filter.Disabled, err = common.ParseOptionalBool(r, "disabled")
+	}
+	if err != nil {
+		h.logger.Error("Invalid query parameters", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if role := models.Role(r.URL.Query().Get("role")); role != "" {
+		filter.Role = &role
+	}
+	if email := r.URL.Query().Get("email"); email != "" {
+		filter.Email = &email
+	}
+
+	users, err := h.authService.ListUsers(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, authService.ErrInvalidRole) {
+			h.logger.Error("Invalid role", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not list users", op, err)
+		return
+	}
+
+	resp := make([]response.UserResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, response.NewUserResponse(user))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"users": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// SetUserRole changes the role of the user and ends their sessions.
+// Ex. {"role": "moderator"}
+func (h *Handler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	const op = "authHandler.SetUserRole"
+
+	var req struct {
+		Role models.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.manageUser(w, r, op, func(adminID, userID string) (*models.User, error) {
+		return h.authService.SetRole(r.Context(), adminID, userID, req.Role)
+	})
+}
+
+// DisableUser rejects every token of the user and forbids them to log in
+func (h *Handler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	const op = "authHandler.DisableUser"
+
+	h.manageUser(w, r, op, func(adminID, userID string) (*models.User, error) {
+		return h.authService.SetDisabled(r.Context(), adminID, userID, true)
+	})
+}
+
+// EnableUser lets a disabled user log in again
+func (h *Handler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	const op = "authHandler.EnableUser"
+
+	h.manageUser(w, r, op, func(adminID, userID string) (*models.User, error) {
+		return h.authService.SetDisabled(r.Context(), adminID, userID, false)
+	})
+}
+
+// LogoutUser ends every session of the user
+func (h *Handler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	const op = "authHandler.LogoutUser"
+
+	userID := chi.URLParam(r, "id")
+	if err := h.authService.ForceLogout(r.Context(), userID); err != nil {
+		h.writeAdminError(w, r, op, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// manageUser applies an admin action to the user of the {id} URL parameter and writes the updated user
+func (h *Handler) manageUser(w http.ResponseWriter, r *http.Request, op string,
+	action func(adminID, userID string) (*models.User, error)) {
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Failed to get claims from context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := action(claims.UserID, chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeAdminError(w, r, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewUserResponse(*user)); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+func (h *Handler) writeAdminError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		h.logger.Warn("User not found", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, authService.ErrInvalidRole):
+		h.logger.Error("Invalid role", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, authService.ErrSelfManagement):
+		h.logger.Warn("Admin tried to manage themselves", slog.String("op", op), "error", err)
+		http.Error(w, authService.ErrSelfManagement.Error(), http.StatusConflict)
+	default:
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not update user", op, err)
+	}
+}
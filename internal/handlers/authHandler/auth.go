@@ -2,10 +2,15 @@ package authHandler
 
 import (
 	"avito/internal/handlers/common"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 
 	"avito/internal/domain/models"
 	"avito/internal/repositories"
@@ -16,7 +21,16 @@ type AuthHandler interface {
 	DummyLogin(w http.ResponseWriter, r *http.Request)
 	Register(w http.ResponseWriter, r *http.Request)
 	Login(w http.ResponseWriter, r *http.Request)
+	RefreshToken(w http.ResponseWriter, r *http.Request)
+	Logout(w http.ResponseWriter, r *http.Request)
+	CreateInvite(w http.ResponseWriter, r *http.Request)
+	ListUsers(w http.ResponseWriter, r *http.Request)
+	SetUserRole(w http.ResponseWriter, r *http.Request)
+	DisableUser(w http.ResponseWriter, r *http.Request)
+	EnableUser(w http.ResponseWriter, r *http.Request)
+	LogoutUser(w http.ResponseWriter, r *http.Request)
 	ValidateToken(tokenStr string) (*models.Claims, error)
+	IsTokenRevoked(claims *models.Claims) bool
 }
 
 type Handler struct {
@@ -32,30 +46,36 @@ func NewHandler(authService authService.AuthService, logger *slog.Logger) AuthHa
 }
 
 // DummyLogin Упрощенный процесс получения токена для дальнейшего прохождения авторизации.
-// Ex. ?user_type=client ("client" или "moderator")
+// Ex. ?user_type=client ("client" или "moderator"). Токены администратора так не выдаются.
 func (h *Handler) DummyLogin(w http.ResponseWriter, r *http.Request) {
 	const op = "authHandler.DummyLogin"
 
-	userType := r.URL.Query().Get("user_type")
+	userType := models.Role(r.URL.Query().Get("user_type"))
 	if userType == "" {
 		h.logger.Error("User type is missing", slog.String("op", op))
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if userType != "client" && userType != "moderator" {
-		h.logger.Error("Invalid user type", slog.String("op", op), slog.String("user_type", userType))
+	if userType != models.RoleClient && userType != models.RoleModerator {
+		h.logger.Error("Invalid user type", slog.String("op", op), slog.String("user_type", string(userType)))
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	token, err := h.authService.GenerateToken("userID", userType)
+	userID, err := newDummyUserID()
+	if err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not generate user ID", op, err)
+		return
+	}
+
+	token, err := h.authService.GenerateToken(userID, userType)
 	if err != nil {
 		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not generate token", op, err)
 		return
 	}
 
-	h.logger.Info("Token generated successfully in DummyLogin", slog.String("op", op), slog.String("user_type", userType))
+	h.logger.Info("Token generated successfully in DummyLogin", slog.String("op", op), slog.String("user_type", string(userType)))
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]string{"token": token}); err != nil {
@@ -63,13 +83,29 @@ func (h *Handler) DummyLogin(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// newDummyUserID returns a random UUID v4, so that flats and moderation actions of dummy users
+// can be stored like those of registered users.
+func newDummyUserID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Register creates a user. Moderators need an invite code minted by another moderator.
+// Ex. {"email": "...", "password": "...", "user_type": "moderator", "invite_code": "..."}
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	const op = "authHandler.Register"
 
 	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-		Role     string `json:"user_type"`
+		Email      string      `json:"email"`
+		Password   string      `json:"password"`
+		Role       models.Role `json:"user_type"`
+		InviteCode string      `json:"invite_code"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -78,14 +114,24 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Role != "client" && req.Role != "moderator" {
-		h.logger.Error("Invalid user type", slog.String("op", op), slog.String("role", req.Role))
+	if !req.Role.Valid() {
+		h.logger.Error("Invalid user type", slog.String("op", op), slog.String("role", string(req.Role)))
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	user, err := h.authService.Register(r.Context(), req.Email, req.Password, req.Role)
+	user, err := h.authService.Register(r.Context(), req.Email, req.Password, req.Role, req.InviteCode)
 	if err != nil {
+		if errors.Is(err, authService.ErrInvalidEmail) || errors.Is(err, authService.ErrWeakPassword) {
+			h.logger.Warn("Invalid credentials", slog.String("op", op), "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, authService.ErrInviteRequired) || errors.Is(err, authService.ErrInvalidInvite) {
+			h.logger.Warn("Moderator registration rejected", slog.String("op", op), "error", err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if errors.Is(err, repositories.ErrUserExists) {
 			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "User already exists", op, err)
 			return
@@ -107,11 +153,14 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Login by email or, for compatibility, by user ID.
+// Ex. {"email": "user@example.com", "password": "..."} or {"id": "...", "password": "..."}
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	const op = "authHandler.Login"
 
 	var req struct {
 		Id       string `json:"id"`
+		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -119,28 +168,59 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if (req.Id == "") == (req.Email == "") {
+		h.logger.Error("Either id or email is required", slog.String("op", op))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
-	user, err := h.authService.Login(r.Context(), req.Id, req.Password)
+	var user *models.User
+	var err error
+	login := slog.String("id", req.Id)
+	if req.Email != "" {
+		login = slog.String("email", req.Email)
+		user, err = h.authService.LoginByEmail(r.Context(), req.Email, req.Password, clientIP(r))
+	} else {
+		user, err = h.authService.Login(r.Context(), req.Id, req.Password, clientIP(r))
+	}
 	if err != nil {
-		h.logger.Error("User not found", slog.String("op", op), slog.String("id", req.Id), "error", err)
+		var lockout *authService.TooManyAttemptsError
+		if errors.As(err, &lockout) {
+			h.logger.Warn("Too many login attempts", slog.String("op", op), login, slog.String("ip", clientIP(r)))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+			http.Error(w, authService.ErrTooManyAttempts.Error(), http.StatusTooManyRequests)
+			return
+		}
+		h.logger.Warn("Invalid credentials", slog.String("op", op), login, "error", err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	token, err := h.authService.GenerateToken(user.ID, user.Role)
+	tokens, err := h.authService.IssueTokens(r.Context(), user)
 	if err != nil {
 		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not generate token", op, err)
 		return
 	}
 
-	h.logger.Info("User logged in successfully", slog.String("op", op), slog.String("id", req.Id))
+	h.logger.Info("User logged in successfully", slog.String("op", op), slog.String("user_id", user.ID))
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]string{"token": token}); err != nil {
-		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	h.writeTokens(w, r, op, tokens)
+}
+
+// clientIP is the address of the peer. Forwarded headers are not trusted: the client could set them
+// to get a fresh attempt budget on every request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
 }
 
 func (h *Handler) ValidateToken(tokenStr string) (*models.Claims, error) {
 	return h.authService.ValidateToken(tokenStr)
 }
+
+func (h *Handler) IsTokenRevoked(claims *models.Claims) bool {
+	return h.authService.IsRevoked(claims)
+}
@@ -0,0 +1,68 @@
+package authHandler
+
+import (
+	"avito/internal/custommiddleware"
+	"avito/internal/domain/models"
+	"avito/internal/handlers/common"
+	"avito/internal/services/authService"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// CreateInvite mints a single-use invite code for registering a moderator or, by admins, an admin.
+// The body is optional. Ex. {"user_type": "moderator"}
+func (h *Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	const op = "authHandler.CreateInvite"
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Failed to get claims from context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	req := struct {
+		Role models.Role `json:"user_type"`
+	}{Role: models.RoleModerator}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("Invalid request", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !req.Role.Valid() || req.Role == models.RoleClient {
+		h.logger.Error("Invalid user type", slog.String("op", op), slog.String("role", string(req.Role)))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	invite, err := h.authService.CreateInvite(r.Context(), claims.UserID, req.Role)
+	if err != nil {
+		if errors.Is(err, authService.ErrInviteNotAllowed) {
+			h.logger.Warn("Invite not allowed", slog.String("op", op), slog.String("user_id", claims.UserID),
+				slog.String("role", string(req.Role)))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not create invite", op, err)
+		return
+	}
+
+	response := struct {
+		Code      string      `json:"invite_code"`
+		Role      models.Role `json:"user_type"`
+		ExpiresAt time.Time   `json:"expires_at"`
+	}{
+		Code:      invite.Code,
+		Role:      invite.Role,
+		ExpiresAt: invite.ExpiresAt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
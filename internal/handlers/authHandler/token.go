@@ -0,0 +1,89 @@
+package authHandler
+
+import (
+	"avito/internal/custommiddleware"
+	"avito/internal/domain/models"
+	"avito/internal/handlers/common"
+	"avito/internal/services/authService"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // seconds
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token.
+// Ex. {"refresh_token": "..."}
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	const op = "authHandler.RefreshToken"
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		h.logger.Error("Invalid request", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.authService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, authService.ErrInvalidRefreshToken) {
+			h.logger.Warn("Invalid refresh token", slog.String("op", op))
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not refresh token", op, err)
+		return
+	}
+
+	h.writeTokens(w, r, op, tokens)
+}
+
+// Logout revokes the access token of the request. The body is optional: with "refresh_token" its session
+// is ended too, with "all": true every session of the user.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "authHandler.Logout"
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Failed to get claims from context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+		All          bool   `json:"all"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("Invalid request", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), claims, req.RefreshToken, req.All); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not log out", op, err)
+		return
+	}
+
+	h.logger.Info("User logged out", slog.String("op", op), slog.String("user_id", claims.UserID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) writeTokens(w http.ResponseWriter, r *http.Request, op string, tokens *models.TokenPair) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+	}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
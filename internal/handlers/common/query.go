@@ -0,0 +1,139 @@
+package common
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidQueryParam = errors.New("invalid query parameter")
+
+// ParseLimit reads the limit query parameter.
+// A missing limit defaults to DefaultLimit, a limit above MaxLimit is capped.
+func ParseLimit(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, ErrInvalidQueryParam
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	return limit, nil
+}
+
+// ParseLimitOffset reads limit and offset query parameters, see ParseLimit.
+func ParseLimitOffset(r *http.Request) (limit, offset int, err error) {
+	limit, err = ParseLimit(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return 0, 0, ErrInvalidQueryParam
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// ParseOptionalInt reads an optional integer query parameter. Returns nil if it is not set.
+func ParseOptionalInt(r *http.Request, name string) (*int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, ErrInvalidQueryParam
+	}
+
+	return &value, nil
+}
+
+// ParseFloat reads a required float query parameter
+func ParseFloat(r *http.Request, name string) (float64, error) {
+	value, err := strconv.ParseFloat(r.URL.Query().Get(name), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, ErrInvalidQueryParam
+	}
+
+	return value, nil
+}
+
+// ParseOptionalFloat reads an optional float query parameter. Returns nil if it is not set.
+func ParseOptionalFloat(r *http.Request, name string) (*float64, error) {
+	if r.URL.Query().Get(name) == "" {
+		return nil, nil
+	}
+
+	value, err := ParseFloat(r, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// ParseOptionalBool reads an optional boolean query parameter. Returns nil if it is not set.
+func ParseOptionalBool(r *http.Request, name string) (*bool, error) {
+	if r.URL.Query().Get(name) == "" {
+		return nil, nil
+	}
+
+	value, err := ParseBool(r, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// ParseBool reads an optional boolean query parameter. Returns false if it is not set.
+func ParseBool(r *http.Request, name string) (bool, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, ErrInvalidQueryParam
+	}
+
+	return value, nil
+}
+
+// ParseIntList reads an optional comma-separated list of integers, e.g. rooms=1,2
+func ParseIntList(r *http.Request, name string) ([]int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var values []int
+	for _, part := range strings.Split(raw, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, ErrInvalidQueryParam
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
@@ -8,13 +8,31 @@ import (
 	"avito/internal/services/flatService"
 	"encoding/json"
 	"errors"
+	"github.com/go-chi/chi/v5"
 	"log/slog"
 	"net/http"
+	"strconv"
 )
 
 type FlatHandler interface {
 	Create(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	MyFlats(w http.ResponseWriter, r *http.Request)
 	Update(w http.ResponseWriter, r *http.Request)
+	Resubmit(w http.ResponseWriter, r *http.Request)
+	Edit(w http.ResponseWriter, r *http.Request)
+	GetEdits(w http.ResponseWriter, r *http.Request)
+	Archive(w http.ResponseWriter, r *http.Request)
+	Restore(w http.ResponseWriter, r *http.Request)
+	GetStatusHistory(w http.ResponseWriter, r *http.Request)
+	GetPrices(w http.ResponseWriter, r *http.Request)
+	RenewModeration(w http.ResponseWriter, r *http.Request)
+	ModerationQueue(w http.ResponseWriter, r *http.Request)
+	ClaimNext(w http.ResponseWriter, r *http.Request)
+	UploadPhoto(w http.ResponseWriter, r *http.Request)
+	DeletePhoto(w http.ResponseWriter, r *http.Request)
+	ReorderPhotos(w http.ResponseWriter, r *http.Request)
+	PhotoAccess(next http.Handler) http.Handler
 }
 
 type Handler struct {
@@ -33,10 +51,17 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	const op = "flatHandler.Create"
 
 	var req struct {
-		HouseID    int  `json:"house_id"`
-		FlatNumber *int `json:"flat_number"`
-		Price      int  `json:"price"`
-		Rooms      int  `json:"rooms"`
+		HouseID     int      `json:"house_id"`
+		FlatNumber  *int     `json:"flat_number"`
+		Price       int      `json:"price"`
+		Rooms       int      `json:"rooms"`
+		TotalArea   *float64 `json:"total_area"`
+		LivingArea  *float64 `json:"living_area"`
+		Floor       *int     `json:"floor"`
+		TotalFloors *int     `json:"total_floors"`
+		Balcony     *bool    `json:"balcony"`
+		Renovation  *string  `json:"renovation"`
+		Description *string  `json:"description"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Invalid request", slog.String("op", op), "error", err)
@@ -44,19 +69,35 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	flat, err := h.flatService.Create(r.Context(), req.HouseID, req.FlatNumber, req.Price, req.Rooms)
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	details := models.FlatDetails{
+		TotalArea:   req.TotalArea,
+		LivingArea:  req.LivingArea,
+		Floor:       req.Floor,
+		TotalFloors: req.TotalFloors,
+		Balcony:     req.Balcony,
+		Renovation:  req.Renovation,
+		Description: req.Description,
+	}
+
+	flat, err := h.flatService.Create(r.Context(), req.HouseID, req.FlatNumber, req.Price, req.Rooms, details, claims.UserID)
 	if err != nil {
+		if errors.Is(err, flatService.ErrInvalidFlatData) {
+			h.logger.Error("Invalid flat data", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
 		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not create flat", op, err)
 		return
 	}
 
-	resp := response.FlatResponse{
-		ID:      flat.ID,
-		HouseID: flat.HouseID,
-		Price:   flat.Price,
-		Rooms:   flat.Rooms,
-		Status:  flat.Status,
-	}
+	resp := response.NewFlatResponse(*flat)
 
 	h.logger.Info("Flat is created", slog.String("op", op), slog.Int("flat_id", flat.ID))
 
@@ -66,12 +107,91 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Get returns a single flat. The decline reason of a declined flat is visible to its owner and moderators.
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.Get"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	includeArchived, err := common.ParseBool(r, "include_archived")
+	if err != nil {
+		h.logger.Error("Invalid include_archived value", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	flat, err := h.flatService.GetFlat(r.Context(), flatID, claims.UserID, claims.Role, includeArchived)
+	if err != nil {
+		if errors.Is(err, flatService.ErrFlatNotFound) {
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not retrieve flat", op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewFlatResponse(*flat)); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// MyFlats lists the caller's own flats in any status.
+func (h *Handler) MyFlats(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.MyFlats"
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	includeArchived, err := common.ParseBool(r, "include_archived")
+	if err != nil {
+		h.logger.Error("Invalid include_archived value", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	flats, err := h.flatService.GetUserFlats(r.Context(), claims.UserID, includeArchived)
+	if err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not retrieve flats", op, err)
+		return
+	}
+
+	resp := make([]response.FlatResponse, 0, len(flats))
+	for _, flat := range flats {
+		resp = append(resp, response.NewFlatResponse(flat))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"flats": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	const op = "flatHandler.Update"
 
 	var req struct {
-		ID     int    `json:"id"`
-		Status string `json:"status"`
+		ID      int     `json:"id"`
+		Status  string  `json:"status"`
+		Comment *string `json:"comment"`
+		Reason  *string `json:"reason"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -80,13 +200,7 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validStatuses := map[string]bool{
-		"created":       true,
-		"approved":      true,
-		"declined":      true,
-		"on moderation": true,
-	}
-	if !validStatuses[req.Status] {
+	if !flatService.IsValidStatus(req.Status) {
 		h.logger.Error("Invalid status value", slog.String("op", op), slog.String("status", req.Status))
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -100,35 +214,455 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID := claims.UserID
-	if claims.Role != "moderator" {
-		h.logger.Error("User is not authorized to update flat status", slog.String("op", op), slog.String("user_id", userID))
-		h.logger.Error("claims.Role DEBUG - ", slog.String("op", op), slog.String("claims.Role", claims.Role))
+	if !claims.Role.CanModerate() {
+		h.logger.Error("User is not authorized to update flat status", slog.String("op", op), slog.String("user_id", userID),
+			slog.String("role", string(claims.Role)))
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	flat, err := h.flatService.UpdateStatus(r.Context(), req.ID, req.Status, userID)
+	flat, err := h.flatService.UpdateStatus(r.Context(), req.ID, req.Status, userID, req.Comment, req.Reason)
 	if err != nil {
-		if errors.Is(err, flatService.ErrFlatBeingModerated) {
+		switch {
+		case errors.Is(err, flatService.ErrFlatBeingModerated):
 			h.logger.Warn("Flat is already being moderated by another user", slog.String("op", op), slog.Int("flat_id", req.ID))
 			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, flatService.ErrLostRace):
+			h.logger.Warn("Flat was changed concurrently", slog.String("op", op), slog.Int("flat_id", req.ID))
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, flatService.ErrInvalidTransition):
+			h.logger.Warn("Status transition is not allowed", slog.String("op", op), slog.Int("flat_id", req.ID), "error", err)
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, flatService.ErrInvalidStatus):
+			h.logger.Error("Invalid status value", slog.String("op", op), slog.String("status", req.Status))
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, flatService.ErrInvalidDeclineReason):
+			h.logger.Error("Invalid decline reason", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, flatService.ErrFlatNotFound):
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", req.ID))
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to update flat status", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	resp := response.NewFlatResponse(*flat)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// Resubmit sends the caller's declined flat back to the moderation queue.
+func (h *Handler) Resubmit(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.Resubmit"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flat, err := h.flatService.Resubmit(r.Context(), flatID, claims.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, flatService.ErrFlatNotFound):
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, flatService.ErrNotFlatOwner):
+			h.logger.Warn("User is not the owner of the flat", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, flatService.ErrInvalidTransition), errors.Is(err, flatService.ErrLostRace):
+			h.logger.Warn("Flat can not be resubmitted", slog.String("op", op), slog.Int("flat_id", flatID), "error", err)
+			w.WriteHeader(http.StatusConflict)
+		default:
+			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not resubmit flat", op, err)
+		}
+		return
+	}
+
+	h.logger.Info("Flat is resubmitted for moderation", slog.String("op", op), slog.Int("flat_id", flat.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewFlatResponse(*flat)); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// Edit changes price, rooms, number or details of the caller's flat.
+func (h *Handler) Edit(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.Edit"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		FlatNumber  *int     `json:"flat_number"`
+		Price       *int     `json:"price"`
+		Rooms       *int     `json:"rooms"`
+		TotalArea   *float64 `json:"total_area"`
+		LivingArea  *float64 `json:"living_area"`
+		Floor       *int     `json:"floor"`
+		TotalFloors *int     `json:"total_floors"`
+		Balcony     *bool    `json:"balcony"`
+		Renovation  *string  `json:"renovation"`
+		Description *string  `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	changes := flatService.FlatChanges{
+		FlatNumber: req.FlatNumber,
+		Price:      req.Price,
+		Rooms:      req.Rooms,
+		FlatDetails: models.FlatDetails{
+			TotalArea:   req.TotalArea,
+			LivingArea:  req.LivingArea,
+			Floor:       req.Floor,
+			TotalFloors: req.TotalFloors,
+			Balcony:     req.Balcony,
+			Renovation:  req.Renovation,
+			Description: req.Description,
+		},
+	}
+	flat, err := h.flatService.UpdateFlat(r.Context(), flatID, claims.UserID, changes)
+	if err != nil {
+		switch {
+		case errors.Is(err, flatService.ErrInvalidFlatData):
+			h.logger.Error("Invalid flat data", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, flatService.ErrFlatNotFound):
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, flatService.ErrNotFlatOwner):
+			h.logger.Warn("User is not the owner of the flat", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, flatService.ErrFlatBeingModerated),
+			errors.Is(err, flatService.ErrLostRace),
+			errors.Is(err, flatService.ErrFlatNumberTaken):
+			h.logger.Warn("Flat can not be edited", slog.String("op", op), slog.Int("flat_id", flatID), "error", err)
+			w.WriteHeader(http.StatusConflict)
+		default:
+			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not update flat", op, err)
+		}
+		return
+	}
+
+	h.logger.Info("Flat is updated", slog.String("op", op), slog.Int("flat_id", flat.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewFlatResponse(*flat)); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// GetEdits lists the owner's edits of a flat with the old and new value of every changed field.
+func (h *Handler) GetEdits(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.GetEdits"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	edits, err := h.flatService.GetFlatEdits(r.Context(), flatID)
+	if err != nil {
+		if errors.Is(err, flatService.ErrFlatNotFound) {
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-		h.logger.Error("Failed to update flat status", slog.String("op", op), "error", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not retrieve flat edits", op, err)
 		return
 	}
 
-	resp := response.FlatResponse{
-		ID:      flat.ID,
-		HouseID: flat.HouseID,
-		Price:   flat.Price,
-		Rooms:   flat.Rooms,
-		Status:  flat.Status,
+	resp := make([]response.FlatEditResponse, 0, len(edits))
+	for _, edit := range edits {
+		resp = append(resp, response.FlatEditResponse{
+			EditorID:  edit.EditorID,
+			Changes:   edit.Changes,
+			CreatedAt: edit.CreatedAt,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"edits": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// Archive hides the flat from every read path. Available to the owner of the flat and moderators.
+func (h *Handler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// Restore brings an archived flat back. Available to the owner of the flat and moderators.
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *Handler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	const op = "flatHandler.setArchived"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var flat *models.Flat
+	if archived {
+		flat, err = h.flatService.Archive(r.Context(), flatID, claims.UserID, claims.Role)
+	} else {
+		flat, err = h.flatService.Restore(r.Context(), flatID, claims.UserID, claims.Role)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, flatService.ErrFlatNotFound):
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, flatService.ErrNotFlatOwner):
+			h.logger.Warn("User is not the owner of the flat", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, flatService.ErrFlatBeingModerated):
+			h.logger.Warn("Flat is being moderated", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusConflict)
+		default:
+			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not archive flat", op, err)
+		}
+		return
+	}
+
+	h.logger.Info("Flat archive state changed", slog.String("op", op), slog.Int("flat_id", flat.ID), slog.Bool("archived", archived))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewFlatResponse(*flat)); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+func (h *Handler) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.GetStatusHistory"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.flatService.GetStatusHistory(r.Context(), flatID)
+	if err != nil {
+		if errors.Is(err, flatService.ErrFlatNotFound) {
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not retrieve status history", op, err)
+		return
+	}
+
+	resp := make([]response.StatusChangeResponse, 0, len(history))
+	for _, change := range history {
+		resp = append(resp, response.StatusChangeResponse{
+			PreviousStatus: change.PreviousStatus,
+			NewStatus:      change.NewStatus,
+			ActorID:        change.ActorID,
+			Comment:        change.Comment,
+			ChangedAt:      change.ChangedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"history": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// GetPrices returns the price history of a flat visible to the caller.
+func (h *Handler) GetPrices(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.GetPrices"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	history, err := h.flatService.GetPriceHistory(r.Context(), flatID, claims.UserID, claims.Role)
+	if err != nil {
+		if errors.Is(err, flatService.ErrFlatNotFound) {
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not retrieve price history", op, err)
+		return
+	}
+
+	resp := make([]response.PriceChangeResponse, 0, len(history))
+	for _, change := range history {
+		resp = append(resp, response.PriceChangeResponse{Price: change.Price, ChangedAt: change.ChangedAt})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"prices": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// RenewModeration extends the moderation lease of a flat held by the caller.
+func (h *Handler) RenewModeration(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.RenewModeration"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flat, err := h.flatService.RenewModeration(r.Context(), flatID, claims.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, flatService.ErrFlatNotFound):
+			h.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, flatService.ErrLeaseNotHeld):
+			h.logger.Warn("Flat is not on moderation by the user", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusConflict)
+		default:
+			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not renew moderation", op, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewFlatResponse(*flat)); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// ModerationQueue lists flats waiting for moderation, oldest first.
+func (h *Handler) ModerationQueue(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.ModerationQueue"
+
+	houseID, err := common.ParseOptionalInt(r, "house_id")
+	if err != nil {
+		h.logger.Error("Invalid house ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := common.ParseLimitOffset(r)
+	if err != nil {
+		h.logger.Error("Invalid pagination parameters", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	flats, err := h.flatService.GetModerationQueue(r.Context(), houseID, limit, offset)
+	if err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not retrieve moderation queue", op, err)
+		return
+	}
+
+	resp := make([]response.FlatResponse, 0, len(flats))
+	for _, flat := range flats {
+		resp = append(resp, response.NewFlatResponse(flat))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"flats": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// ClaimNext puts the oldest waiting flat on moderation for the caller.
+// Responds with 204 if the queue is empty.
+func (h *Handler) ClaimNext(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.ClaimNext"
+
+	houseID, err := common.ParseOptionalInt(r, "house_id")
+	if err != nil {
+		h.logger.Error("Invalid house ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flat, err := h.flatService.ClaimNextFlat(r.Context(), claims.UserID, houseID)
+	if err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not claim flat", op, err)
+		return
+	}
+
+	if flat == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.logger.Info("Flat is claimed for moderation", slog.String("op", op), slog.Int("flat_id", flat.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewFlatResponse(*flat)); err != nil {
 		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
 	}
 }
@@ -0,0 +1,209 @@
+package flatHandler
+
+import (
+	"avito/internal/custommiddleware"
+	"avito/internal/domain/models"
+	"avito/internal/handlers/common"
+	"avito/internal/handlers/response"
+	"avito/internal/services/flatService"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Room for the multipart headers and the other form fields of a photo upload
+const multipartOverhead = 1 << 20
+
+// UploadPhoto attaches an image from the multipart field "file" to the flat.
+// The optional field "kind" is "photo" (default) or "floor_plan".
+func (h *Handler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.UploadPhoto"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, flatService.MaxPhotoSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.logger.Error("Photo is too large", slog.String("op", op), slog.Int("flat_id", flatID))
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.logger.Error("Invalid multipart form", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.logger.Error("Photo file is missing", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	flat, err := h.flatService.AddPhoto(r.Context(), flatID, claims.UserID, r.FormValue("kind"), file)
+	if err != nil {
+		h.writePhotoError(w, r, op, flatID, err)
+		return
+	}
+
+	h.logger.Info("Photo is added", slog.String("op", op), slog.Int("flat_id", flat.ID))
+	h.writeFlat(w, r, op, flat)
+}
+
+// DeletePhoto removes a photo from the flat
+func (h *Handler) DeletePhoto(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.DeletePhoto"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	photoID, err := strconv.Atoi(chi.URLParam(r, "photoID"))
+	if err != nil {
+		h.logger.Error("Invalid photo ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flat, err := h.flatService.DeletePhoto(r.Context(), flatID, photoID, claims.UserID)
+	if err != nil {
+		h.writePhotoError(w, r, op, flatID, err)
+		return
+	}
+
+	h.logger.Info("Photo is deleted", slog.String("op", op), slog.Int("flat_id", flatID), slog.Int("photo_id", photoID))
+	h.writeFlat(w, r, op, flat)
+}
+
+// ReorderPhotos sets the display order of the flat photos: {"photo_ids": [3, 1, 2]}
+func (h *Handler) ReorderPhotos(w http.ResponseWriter, r *http.Request) {
+	const op = "flatHandler.ReorderPhotos"
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid flat ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		PhotoIDs []int `json:"photo_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	flat, err := h.flatService.ReorderPhotos(r.Context(), flatID, claims.UserID, req.PhotoIDs)
+	if err != nil {
+		h.writePhotoError(w, r, op, flatID, err)
+		return
+	}
+
+	h.logger.Info("Photos are reordered", slog.String("op", op), slog.Int("flat_id", flatID))
+	h.writeFlat(w, r, op, flat)
+}
+
+// PhotoAccess passes on requests for the files of flat photos the caller may see, see flatService.CheckPhotoAccess.
+// Other files are not found. The answers depend on the caller, so shared caches must not keep them.
+func (h *Handler) PhotoAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "flatHandler.PhotoAccess"
+
+		flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Warn("Invalid flat ID format", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+		if !ok || claims == nil {
+			h.logger.Error("Claims are missing in context", slog.String("op", op))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		key := fmt.Sprintf("flats/%d/%s", flatID, chi.URLParam(r, "*"))
+		if err := h.flatService.CheckPhotoAccess(r.Context(), flatID, key, claims.UserID, claims.Role); err != nil {
+			if errors.Is(err, flatService.ErrFlatNotFound) || errors.Is(err, flatService.ErrPhotoNotFound) {
+				h.logger.Warn("Photo not found", slog.String("op", op), slog.Int("flat_id", flatID))
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not check photo access", op, err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "private")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) writePhotoError(w http.ResponseWriter, r *http.Request, op string, flatID int, err error) {
+	switch {
+	case errors.Is(err, flatService.ErrInvalidPhoto), errors.Is(err, flatService.ErrInvalidPhotoOrder):
+		h.logger.Error("Invalid photo request", slog.String("op", op), slog.Int("flat_id", flatID), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, flatService.ErrPhotoTooLarge):
+		h.logger.Error("Photo is too large", slog.String("op", op), slog.Int("flat_id", flatID), "error", err)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case errors.Is(err, flatService.ErrFlatNotFound), errors.Is(err, flatService.ErrPhotoNotFound):
+		h.logger.Warn("Flat or photo not found", slog.String("op", op), slog.Int("flat_id", flatID))
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, flatService.ErrNotFlatOwner):
+		h.logger.Warn("User is not the owner of the flat", slog.String("op", op), slog.Int("flat_id", flatID))
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, flatService.ErrFlatBeingModerated),
+		errors.Is(err, flatService.ErrLostRace),
+		errors.Is(err, flatService.ErrTooManyPhotos):
+		h.logger.Warn("Photos can not be changed", slog.String("op", op), slog.Int("flat_id", flatID), "error", err)
+		w.WriteHeader(http.StatusConflict)
+	default:
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not change flat photos", op, err)
+	}
+}
+
+func (h *Handler) writeFlat(w http.ResponseWriter, r *http.Request, op string, flat *models.Flat) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewFlatResponse(*flat)); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
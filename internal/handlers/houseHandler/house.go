@@ -5,6 +5,7 @@ import (
 	"avito/internal/domain/models"
 	"avito/internal/handlers/common"
 	"avito/internal/handlers/response"
+	"avito/internal/repositories"
 	"avito/internal/services/houseService"
 	"encoding/json"
 	"errors"
@@ -17,6 +18,12 @@ import (
 type HouseHandler interface {
 	Create(w http.ResponseWriter, r *http.Request)
 	GetFlatsByHouseID(w http.ResponseWriter, r *http.Request)
+	Subscribe(w http.ResponseWriter, r *http.Request)
+	Archive(w http.ResponseWriter, r *http.Request)
+	Restore(w http.ResponseWriter, r *http.Request)
+	Info(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
 }
 
 type Handler struct {
@@ -35,9 +42,11 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	const op = "houseHandler.Create"
 
 	var req struct {
-		Address   string  `json:"address"`
-		YearBuilt int     `json:"year"`
-		Builder   *string `json:"developer"`
+		Address   string   `json:"address"`
+		YearBuilt int      `json:"year"`
+		Builder   *string  `json:"developer"`
+		Latitude  *float64 `json:"lat"`
+		Longitude *float64 `json:"lon"`
 	}
 
 	h.logger.Debug("Start of creating a house", slog.String("op", op))
@@ -50,25 +59,33 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Debug("Received house creation request", slog.String("op", op), slog.String("address", req.Address))
 
-	house, err := h.houseService.Create(r.Context(), req.Address, req.YearBuilt, req.Builder)
+	// Coordinates come in pairs
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		h.logger.Error("Only one of lat and lon is set", slog.String("op", op))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var location *models.GeoPoint
+	if req.Latitude != nil {
+		location = &models.GeoPoint{Lat: *req.Latitude, Lon: *req.Longitude}
+	}
+
+	house, err := h.houseService.Create(r.Context(), req.Address, req.YearBuilt, req.Builder, location)
 	if err != nil {
-		if errors.Is(err, houseService.ErrValidation) {
+		var dupErr *houseService.DuplicateAddressError
+		switch {
+		case errors.Is(err, houseService.ErrValidation):
 			h.logger.Error("Validation error", slog.String("op", op), "error", err)
 			w.WriteHeader(http.StatusBadRequest)
-		} else {
+		case errors.As(err, &dupErr):
+			h.writeDuplicate(w, op, dupErr)
+		default:
 			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Server error", op, err)
 		}
 		return
 	}
 
-	resp := response.HouseResponse{
-		Id:        house.ID,
-		Address:   house.Address,
-		Year:      house.YearBuilt,
-		Developer: checkString(house.Builder),
-		CreatedAt: house.CreatedAt,
-		UpdateAt:  house.CreatedAt,
-	}
+	resp := response.NewHouseResponse(*house)
 
 	h.logger.Info("House created successfully", slog.String("op", op), slog.Int("house_id", house.ID))
 
@@ -102,8 +119,40 @@ func (h *Handler) GetFlatsByHouseID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	flats, err := h.houseService.GetFlatsByHouseID(r.Context(), houseID, claims.Role)
+	includeArchived, err := common.ParseBool(r, "include_archived")
+	if err != nil {
+		h.logger.Error("Invalid include_archived value", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	listing := houseService.FlatListing{
+		SortBy: r.URL.Query().Get("sort"),
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+	listing.Limit, err = common.ParseLimit(r)
+	if err == nil {
+		switch r.URL.Query().Get("order") {
+		case "", "asc":
+		case "desc":
+			listing.Desc = true
+		default:
+			err = common.ErrInvalidQueryParam
+		}
+	}
+	if err != nil {
+		h.logger.Error("Invalid query parameters", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	flats, nextCursor, err := h.houseService.GetFlatsByHouseID(r.Context(), houseID, claims.UserID, claims.Role, includeArchived, listing)
 	if err != nil {
+		if errors.Is(err, houseService.ErrValidation) || errors.Is(err, houseService.ErrInvalidCursor) {
+			h.logger.Error("Invalid listing parameters", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
 		h.logger.Error("Failed to get flats by house ID", slog.String("op", op), "error", err)
 		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not retrieve flats", op, err)
 		return
@@ -111,24 +160,262 @@ func (h *Handler) GetFlatsByHouseID(w http.ResponseWriter, r *http.Request) {
 
 	var resp []response.FlatResponse
 	for _, flat := range flats {
-		resp = append(resp, response.FlatResponse{
-			ID:      flat.ID,
-			HouseID: flat.HouseID,
-			Price:   flat.Price,
-			Rooms:   flat.Rooms,
-			Status:  flat.Status,
-		})
+		resp = append(resp, response.NewFlatResponse(flat))
+	}
+
+	body := map[string]interface{}{"flats": resp}
+	if nextCursor != "" {
+		body["next_cursor"] = nextCursor
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	const op = "houseHandler.Subscribe"
+
+	houseID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid house ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid request", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := h.houseService.Subscribe(r.Context(), houseID, req.Email); err != nil {
+		switch {
+		case errors.Is(err, houseService.ErrValidation):
+			h.logger.Error("Validation error", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, repositories.ErrHouseNotFound):
+			h.logger.Warn("House not found", slog.String("op", op), slog.Int("house_id", houseID))
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not subscribe to house", op, err)
+		}
+		return
+	}
+
+	h.logger.Info("Subscribed to house", slog.String("op", op), slog.Int("house_id", houseID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Archive hides the house and its flats from every read path
+func (h *Handler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *Handler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	const op = "houseHandler.setArchived"
+
+	houseID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid house ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var house *models.House
+	if archived {
+		house, err = h.houseService.Archive(r.Context(), houseID)
+	} else {
+		house, err = h.houseService.Restore(r.Context(), houseID)
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrHouseNotFound) {
+			h.logger.Warn("House not found", slog.String("op", op), slog.Int("house_id", houseID))
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not archive house", op, err)
+		return
+	}
+
+	resp := response.NewHouseResponse(*house)
+
+	h.logger.Info("House archive state changed", slog.String("op", op), slog.Int("house_id", house.ID), slog.Bool("archived", archived))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// Info returns the house itself, without its flats
+func (h *Handler) Info(w http.ResponseWriter, r *http.Request) {
+	const op = "houseHandler.Info"
+
+	houseID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid house ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
+	includeArchived, err := common.ParseBool(r, "include_archived")
+	if err != nil {
+		h.logger.Error("Invalid include_archived value", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	house, err := h.houseService.GetHouse(r.Context(), houseID, claims.Role, includeArchived)
+	if err != nil {
+		if errors.Is(err, repositories.ErrHouseNotFound) {
+			h.logger.Warn("House not found", slog.String("op", op), slog.Int("house_id", houseID))
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not retrieve house", op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response.NewHouseResponse(*house)); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// List returns a page of houses. Filters: year_from, year_to, developer, address (substring).
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "houseHandler.List"
+
+	var filter models.HouseFilter
+	var err error
+
+	filter.Limit, filter.Offset, err = common.ParseLimitOffset(r)
+	if err == nil {
+		filter.YearFrom, err = common.ParseOptionalInt(r, "year_from")
+	}
+	if err == nil {
+		filter.YearTo, err = common.ParseOptionalInt(r, "year_to")
+	}
+	if err == nil {
+		filter.IncludeArchived, err = common.ParseBool(r, "include_archived")
+	}
+	if err != nil {
+		h.logger.Error("Invalid query parameters", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if developer := r.URL.Query().Get("developer"); developer != "" {
+		filter.Developer = &developer
+	}
+	if address := r.URL.Query().Get("address"); address != "" {
+		filter.Address = &address
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	houses, err := h.houseService.ListHouses(r.Context(), filter, claims.Role)
+	if err != nil {
+		if errors.Is(err, houseService.ErrValidation) {
+			h.logger.Error("Validation error", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not list houses", op, err)
+		return
+	}
+
+	resp := make([]response.HouseResponse, 0, len(houses))
+	for _, house := range houses {
+		resp = append(resp, response.NewHouseResponse(house))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"houses": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// Update changes address, year or developer of the house
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	const op = "houseHandler.Update"
+
+	houseID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.logger.Error("Invalid house ID format", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Address   *string `json:"address"`
+		YearBuilt *int    `json:"year"`
+		Builder   *string `json:"developer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Invalid input data", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	changes := houseService.HouseChanges{Address: req.Address, YearBuilt: req.YearBuilt, Builder: req.Builder}
+	house, err := h.houseService.UpdateHouse(r.Context(), houseID, changes)
+	if err != nil {
+		var dupErr *houseService.DuplicateAddressError
+		switch {
+		case errors.As(err, &dupErr):
+			h.writeDuplicate(w, op, dupErr)
+		case errors.Is(err, houseService.ErrValidation):
+			h.logger.Error("Validation error", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, repositories.ErrHouseNotFound):
+			h.logger.Warn("House not found", slog.String("op", op), slog.Int("house_id", houseID))
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not update house", op, err)
+		}
+		return
+	}
+
+	h.logger.Info("House updated", slog.String("op", op), slog.Int("house_id", house.ID))
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{"flats": resp}); err != nil {
+	if err := json.NewEncoder(w).Encode(response.NewHouseResponse(*house)); err != nil {
 		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
 	}
 }
 
-func checkString(ptr *string) string {
-	if ptr != nil {
-		return *ptr
+// writeDuplicate answers 409 with the ID of the house that already has the address
+func (h *Handler) writeDuplicate(w http.ResponseWriter, op string, dupErr *houseService.DuplicateAddressError) {
+	h.logger.Warn("House with this address already exists", slog.String("op", op), slog.Int("house_id", dupErr.HouseID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	resp := map[string]interface{}{
+		"message":  "дом с таким адресом уже существует",
+		"house_id": dupErr.HouseID,
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("Failed to write response", slog.String("op", op), "error", err)
 	}
-	return ""
 }
@@ -1,20 +1,156 @@
 package response
 
-import "time"
+import (
+	"avito/internal/domain/models"
+	"math"
+	"time"
+)
 
 type FlatResponse struct {
-	ID      int    `json:"id"`
-	HouseID int    `json:"house_id"`
-	Price   int    `json:"price"`
-	Rooms   int    `json:"rooms"`
-	Status  string `json:"status"`
+	ID                  int             `json:"id"`
+	HouseID             int             `json:"house_id"`
+	Price               int             `json:"price"`
+	Rooms               int             `json:"rooms"`
+	Status              string          `json:"status"`
+	ModerationExpiresAt *time.Time      `json:"moderation_expires_at,omitempty"`
+	CreatedAt           time.Time       `json:"created_at"`
+	DeclineReason       *string         `json:"decline_reason,omitempty"`
+	DeclineComment      *string         `json:"decline_comment,omitempty"`
+	ArchivedAt          *time.Time      `json:"archived_at,omitempty"`
+	PreviousPrice       *int            `json:"previous_price,omitempty"`
+	PriceChangedAt      *time.Time      `json:"price_changed_at,omitempty"`
+	TotalArea           *float64        `json:"total_area,omitempty"`
+	LivingArea          *float64        `json:"living_area,omitempty"`
+	Floor               *int            `json:"floor,omitempty"`
+	TotalFloors         *int            `json:"total_floors,omitempty"`
+	Balcony             *bool           `json:"balcony,omitempty"`
+	Renovation          *string         `json:"renovation,omitempty"`
+	Description         *string         `json:"description,omitempty"`
+	PricePerSqm         *int            `json:"price_per_sqm,omitempty"`
+	Photos              []PhotoResponse `json:"photos,omitempty"`
+}
+
+type PhotoResponse struct {
+	ID           int    `json:"id"`
+	Kind         string `json:"kind"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Position     int    `json:"position"`
+}
+
+func NewFlatResponse(flat models.Flat) FlatResponse {
+	resp := FlatResponse{
+		ID:                  flat.ID,
+		HouseID:             flat.HouseID,
+		Price:               flat.Price,
+		Rooms:               flat.Rooms,
+		Status:              flat.Status,
+		ModerationExpiresAt: flat.ModerationExpiresAt,
+		CreatedAt:           flat.CreatedAt,
+		DeclineReason:       flat.DeclineReason,
+		DeclineComment:      flat.DeclineComment,
+		ArchivedAt:          flat.ArchivedAt,
+		PreviousPrice:       flat.PreviousPrice,
+		PriceChangedAt:      flat.PriceChangedAt,
+		TotalArea:           flat.TotalArea,
+		LivingArea:          flat.LivingArea,
+		Floor:               flat.Floor,
+		TotalFloors:         flat.TotalFloors,
+		Balcony:             flat.Balcony,
+		Renovation:          flat.Renovation,
+		Description:         flat.Description,
+	}
+	for _, photo := range flat.Photos {
+		resp.Photos = append(resp.Photos, PhotoResponse{
+			ID:           photo.ID,
+			Kind:         photo.Kind,
+			URL:          photo.URL,
+			ThumbnailURL: photo.ThumbnailURL,
+			Position:     photo.Position,
+		})
+	}
+	if price, ok := flat.PricePerSquareMeter(); ok {
+		resp.PricePerSqm = &price
+	}
+	return resp
 }
 
 type HouseResponse struct {
-	Id        int       `json:"id"`
-	Address   string    `json:"address"`
-	Year      int       `json:"year"`
-	Developer string    `json:"developer,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdateAt  time.Time `json:"update_at"`
+	Id            int        `json:"id"`
+	Address       string     `json:"address"`
+	Year          int        `json:"year"`
+	Developer     string     `json:"developer,omitempty"`
+	Latitude      *float64   `json:"lat,omitempty"`
+	Longitude     *float64   `json:"lon,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdateAt      time.Time  `json:"update_at"`
+	LastFlatAdded *time.Time `json:"last_flat_added,omitempty"`
+	ArchivedAt    *time.Time `json:"archived_at,omitempty"`
+}
+
+func NewHouseResponse(house models.House) HouseResponse {
+	resp := HouseResponse{
+		Id:            house.ID,
+		Address:       house.Address,
+		Year:          house.YearBuilt,
+		Latitude:      house.Latitude,
+		Longitude:     house.Longitude,
+		CreatedAt:     house.CreatedAt,
+		UpdateAt:      house.UpdatedAt,
+		LastFlatAdded: house.LastFlatAdded,
+		ArchivedAt:    house.ArchivedAt,
+	}
+	if house.Builder != nil {
+		resp.Developer = *house.Builder
+	}
+	return resp
+}
+
+type NearbyHouseResponse struct {
+	HouseResponse
+	DistanceM float64 `json:"distance_m"`
+}
+
+func NewNearbyHouseResponse(house models.NearbyHouse) NearbyHouseResponse {
+	return NearbyHouseResponse{
+		HouseResponse: NewHouseResponse(house.House),
+		DistanceM:     math.Round(house.DistanceM),
+	}
+}
+
+type UserResponse struct {
+	ID         string      `json:"id"`
+	Email      string      `json:"email"`
+	Role       models.Role `json:"role"`
+	CreatedAt  time.Time   `json:"created_at"`
+	DisabledAt *time.Time  `json:"disabled_at,omitempty"`
+}
+
+func NewUserResponse(user models.User) UserResponse {
+	return UserResponse{
+		ID:         user.ID,
+		Email:      user.Email,
+		Role:       user.Role,
+		CreatedAt:  user.CreatedAt,
+		DisabledAt: user.DisabledAt,
+	}
+}
+
+type StatusChangeResponse struct {
+	PreviousStatus string    `json:"previous_status"`
+	NewStatus      string    `json:"new_status"`
+	ActorID        string    `json:"actor_id,omitempty"`
+	Comment        *string   `json:"comment,omitempty"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+type PriceChangeResponse struct {
+	Price     int       `json:"price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type FlatEditResponse struct {
+	EditorID  string                        `json:"editor_id"`
+	Changes   map[string]models.FieldChange `json:"changes"`
+	CreatedAt time.Time                     `json:"created_at"`
 }
@@ -0,0 +1,196 @@
+package searchHandler
+
+import (
+	"avito/internal/custommiddleware"
+	"avito/internal/domain/models"
+	"avito/internal/handlers/common"
+	"avito/internal/handlers/response"
+	"avito/internal/services/searchService"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+type SearchHandler interface {
+	SearchFlats(w http.ResponseWriter, r *http.Request)
+	SearchHouses(w http.ResponseWriter, r *http.Request)
+	NearbyHouses(w http.ResponseWriter, r *http.Request)
+}
+
+type Handler struct {
+	searchService searchService.SearchService
+	logger        *slog.Logger
+}
+
+func NewHandler(searchService searchService.SearchService, logger *slog.Logger) SearchHandler {
+	return &Handler{
+		searchService: searchService,
+		logger:        logger,
+	}
+}
+
+// SearchFlats looks for flats in all houses.
+// Filters: price_from, price_to, rooms (comma-separated), year_from, year_to, developer, address (substring).
+func (h *Handler) SearchFlats(w http.ResponseWriter, r *http.Request) {
+	const op = "searchHandler.SearchFlats"
+
+	var search models.FlatSearch
+	var err error
+
+	search.Limit, search.Offset, err = common.ParseLimitOffset(r)
+	if err == nil {
+		search.PriceFrom, err = common.ParseOptionalInt(r, "price_from")
+	}
+	if err == nil {
+		search.PriceTo, err = common.ParseOptionalInt(r, "price_to")
+	}
+	if err == nil {
+		search.Rooms, err = common.ParseIntList(r, "rooms")
+	}
+	if err == nil {
+		search.AreaFrom, err = common.ParseOptionalFloat(r, "area_from")
+	}
+	if err == nil {
+		search.AreaTo, err = common.ParseOptionalFloat(r, "area_to")
+	}
+	if err == nil {
+		search.FloorFrom, err = common.ParseOptionalInt(r, "floor_from")
+	}
+	if err == nil {
+		search.FloorTo, err = common.ParseOptionalInt(r, "floor_to")
+	}
+	if err == nil {
+		search.Balcony, err = common.ParseOptionalBool(r, "balcony")
+	}
+	if err == nil {
+		search.YearFrom, err = common.ParseOptionalInt(r, "year_from")
+	}
+	if err == nil {
+		search.YearTo, err = common.ParseOptionalInt(r, "year_to")
+	}
+	if err != nil {
+		h.logger.Error("Invalid query parameters", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if developer := r.URL.Query().Get("developer"); developer != "" {
+		search.Developer = &developer
+	}
+	if address := r.URL.Query().Get("address"); address != "" {
+		search.Address = &address
+	}
+	if renovation := r.URL.Query().Get("renovation"); renovation != "" {
+		search.Renovation = &renovation
+	}
+
+	claims, ok := r.Context().Value(custommiddleware.ClaimsContextKey).(*models.Claims)
+	if !ok || claims == nil {
+		h.logger.Error("Claims are missing in context", slog.String("op", op))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	search.UserID = claims.UserID
+	search.Role = claims.Role
+
+	flats, err := h.searchService.SearchFlats(r.Context(), search)
+	if err != nil {
+		if errors.Is(err, searchService.ErrValidation) {
+			h.logger.Error("Validation error", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not search flats", op, err)
+		return
+	}
+
+	resp := make([]response.FlatResponse, 0, len(flats))
+	for _, flat := range flats {
+		resp = append(resp, response.NewFlatResponse(flat))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"flats": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// SearchHouses looks for houses by address: q is the search text, e.g. "Ленина улица 5"
+func (h *Handler) SearchHouses(w http.ResponseWriter, r *http.Request) {
+	const op = "searchHandler.SearchHouses"
+
+	limit, offset, err := common.ParseLimitOffset(r)
+	if err != nil {
+		h.logger.Error("Invalid query parameters", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	houses, err := h.searchService.SearchHouses(r.Context(), r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		if errors.Is(err, searchService.ErrValidation) {
+			h.logger.Error("Validation error", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not search houses", op, err)
+		return
+	}
+
+	resp := make([]response.HouseResponse, 0, len(houses))
+	for _, house := range houses {
+		resp = append(resp, response.NewHouseResponse(house))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"houses": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
+
+// NearbyHouses returns houses around a point: lat, lon and radius_m are required, limit is optional
+func (h *Handler) NearbyHouses(w http.ResponseWriter, r *http.Request) {
+	const op = "searchHandler.NearbyHouses"
+
+	var center models.GeoPoint
+	var radiusM float64
+	var limit int
+	var err error
+
+	center.Lat, err = common.ParseFloat(r, "lat")
+	if err == nil {
+		center.Lon, err = common.ParseFloat(r, "lon")
+	}
+	if err == nil {
+		radiusM, err = common.ParseFloat(r, "radius_m")
+	}
+	if err == nil {
+		limit, err = common.ParseLimit(r)
+	}
+	if err != nil {
+		h.logger.Error("Invalid query parameters", slog.String("op", op), "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	houses, err := h.searchService.NearbyHouses(r.Context(), center, radiusM, limit)
+	if err != nil {
+		if errors.Is(err, searchService.ErrValidation) {
+			h.logger.Error("Validation error", slog.String("op", op), "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Could not find nearby houses", op, err)
+		return
+	}
+
+	resp := make([]response.NearbyHouseResponse, 0, len(houses))
+	for _, house := range houses {
+		resp = append(resp, response.NewNearbyHouseResponse(house))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"houses": resp}); err != nil {
+		common.WriteErrorResponse(w, r, h.logger, http.StatusInternalServerError, "Failed to write response", op, err)
+	}
+}
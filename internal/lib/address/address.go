@@ -0,0 +1,188 @@
+package address
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Full names of street types by their common spellings
+var streetTypes = map[string]string{
+	"ул": "улица", "улица": "улица",
+	"пр": "проспект", "пр-т": "проспект", "просп": "проспект", "проспект": "проспект",
+	"пр-д": "проезд", "проезд": "проезд",
+	"пер": "переулок", "переулок": "переулок",
+	"пл": "площадь", "площадь": "площадь",
+	"наб": "набережная", "набережная": "набережная",
+	"б-р": "бульвар", "бул": "бульвар", "бульвар": "бульвар",
+	"ш": "шоссе", "шоссе": "шоссе",
+	"туп": "тупик", "тупик": "тупик",
+	"ал": "аллея", "аллея": "аллея",
+}
+
+// Designators that are dropped: the locality name itself is kept
+var localityTypes = map[string]bool{"г": true, "город": true}
+
+var countries = map[string]bool{"россия": true, "рф": true}
+
+// Building designators followed by a number
+var (
+	houseWords     = map[string]bool{"д": true, "дом": true}
+	buildingWords  = map[string]bool{"к": true, "корп": true, "корпус": true}
+	structureWords = map[string]bool{"с": true, "стр": true, "строение": true}
+)
+
+var (
+	// "д5" -> "д 5"
+	designatorBeforeNumber = regexp.MustCompile(`(^|[^а-я0-9-])(д|дом|к|корп|корпус|с|стр|строение)(\d)`)
+	// "5к2", "12стр1" -> "5 к 2", "12 стр 1"
+	designatorBetweenNumbers = regexp.MustCompile(`(\d)(к|корп|корпус|с|стр|строение)(\d)`)
+	postcode                 = regexp.MustCompile(`^\d{6}$`)
+	// Ordinals are a part of the street name: "1-я Тверская-Ямская"
+	ordinal = regexp.MustCompile(`^\d+-[а-я]+$`)
+)
+
+type parsed struct {
+	// explicitHouse is set when the address marks the house number with "д" or "дом",
+	// then bare numbers are a part of the street name: "ул. 8 Марта, д. 5"
+	explicitHouse bool
+	localities    []string
+	street        string
+	house         string
+	building      string
+	structure     string
+}
+
+// Normalize returns the canonical form of a Russian street address: lower case, full street types
+// placed before the street name, postcode and country dropped and the components in a fixed order —
+// localities, street, дом, корпус, строение. Returns an empty string if nothing is left of the address.
+//
+// "ул. Ленина, д. 5к2, Москва, 125009" and "г Москва, Ленина улица, дом 5, корпус 2"
+// both become "москва, улица ленина, дом 5, корпус 2".
+func Normalize(raw string) string {
+	var parts [][]string
+	for _, part := range strings.Split(prepare(raw), ",") {
+		parts = append(parts, strings.Fields(part))
+	}
+
+	var a parsed
+	for _, tokens := range parts {
+		for i, t := range tokens {
+			if houseWords[t] && i+1 < len(tokens) && isNumber(tokens[i+1]) {
+				a.explicitHouse = true
+			}
+		}
+	}
+	for _, tokens := range parts {
+		a.addPart(tokens)
+	}
+	return a.String()
+}
+
+// prepare lowercases the address, keeps commas as component separators
+// and splits designators glued to numbers
+func prepare(raw string) string {
+	s := strings.ReplaceAll(strings.ToLower(raw), "ё", "е")
+
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '/', r == ',':
+			return r
+		case r == ';':
+			return ','
+		default:
+			return ' '
+		}
+	}, s)
+
+	s = designatorBeforeNumber.ReplaceAllString(s, "$1$2 $3")
+	// Matches may overlap, e.g. "5к2с1"
+	for prev := ""; prev != s; {
+		prev = s
+		s = designatorBetweenNumbers.ReplaceAllString(s, "$1 $2 $3")
+	}
+
+	return s
+}
+
+func (a *parsed) addPart(tokens []string) {
+	var words []string
+	var streetType string
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		hasNumber := i+1 < len(tokens) && isNumber(tokens[i+1])
+
+		switch {
+		case strings.Trim(t, "-") == "", postcode.MatchString(t), countries[t], localityTypes[t]:
+		case streetTypes[t] != "" && streetType == "":
+			streetType = streetTypes[t]
+		case houseWords[t] && hasNumber:
+			i++
+			a.house = tokens[i]
+		case buildingWords[t] && hasNumber:
+			i++
+			a.building = tokens[i]
+		case structureWords[t] && hasNumber:
+			i++
+			a.structure = tokens[i]
+		case isNumber(t) && a.house == "" && !a.explicitHouse && !followedByWords(tokens, i):
+			a.house = t
+		case a.house != "" && i > 0 && tokens[i-1] == a.house && len([]rune(t)) == 1:
+			// Building letter written apart: "12 а"
+			a.house += t
+		default:
+			words = append(words, t)
+		}
+	}
+
+	// "Тверская-Ямская 1-я" and "1-я Тверская-Ямская" are the same street
+	sort.SliceStable(words, func(i, j int) bool {
+		return ordinal.MatchString(words[i]) && !ordinal.MatchString(words[j])
+	})
+
+	switch {
+	case streetType != "" && a.street == "":
+		a.street = strings.TrimSpace(streetType + " " + strings.Join(words, " "))
+	case len(words) > 0:
+		a.localities = append(a.localities, strings.Join(words, " "))
+	}
+}
+
+func (a *parsed) String() string {
+	// Components without a designator may be a city, a district or an untyped street,
+	// their order in the source address means nothing
+	parts := append([]string(nil), a.localities...)
+	sort.Strings(parts)
+	if a.street != "" {
+		parts = append(parts, a.street)
+	}
+	if a.house != "" {
+		parts = append(parts, "дом "+a.house)
+	}
+	if a.building != "" {
+		parts = append(parts, "корпус "+a.building)
+	}
+	if a.structure != "" {
+		parts = append(parts, "строение "+a.structure)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// followedByWords reports whether the number at i is followed by a name, as in "ул. 1905 года",
+// rather than by a building designator or a building letter
+func followedByWords(tokens []string, i int) bool {
+	if i+1 >= len(tokens) {
+		return false
+	}
+	next := tokens[i+1]
+	if buildingWords[next] || structureWords[next] || isNumber(next) || len([]rune(next)) == 1 {
+		return false
+	}
+	return true
+}
+
+func isNumber(s string) bool {
+	return s != "" && unicode.IsDigit([]rune(s)[0]) && !ordinal.MatchString(s)
+}
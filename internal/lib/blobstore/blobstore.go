@@ -0,0 +1,17 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore keeps uploaded files. Keys are slash-separated relative paths, e.g. "flats/5/photo.jpg".
+type BlobStore interface {
+	// Put stores the blob under key and returns the URL it is served from
+	Put(ctx context.Context, key, contentType string, data io.Reader) (url string, err error)
+	// Delete removes the blob. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,107 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps blobs in a directory of the local filesystem and serves them under urlPrefix.
+type LocalStore struct {
+	dir       string
+	urlPrefix string
+}
+
+func NewLocalStore(dir, urlPrefix string) *LocalStore {
+	return &LocalStore{
+		dir:       dir,
+		urlPrefix: strings.TrimSuffix(urlPrefix, "/"),
+	}
+}
+
+// Put writes the blob to a temporary file first, so that a failed upload never leaves a partial file under key.
+func (s *LocalStore) Put(ctx context.Context, key, _ string, data io.Reader) (string, error) {
+	const op = "blobstore.LocalStore.Put"
+
+	filename, err := s.path(key)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, &contextReader{ctx: ctx, r: data}); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.urlPrefix + "/" + key, nil
+}
+
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	const op = "blobstore.LocalStore.Delete"
+
+	filename, err := s.path(key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// URLPrefix is the URL path the blobs are served under
+func (s *LocalStore) URLPrefix() string {
+	return s.urlPrefix
+}
+
+// ServeHTTP serves the stored blobs; the request path is the key. Directory listings are not served.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	http.FileServer(http.Dir(s.dir)).ServeHTTP(w, r)
+}
+
+// path maps the key to a file inside the store directory
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// contextReader stops the copy when the context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,131 @@
+package loginguard
+
+import (
+	"context"
+	"time"
+)
+
+// Policy is how many failed attempts are free and how long the key is locked after each further one.
+// The delay doubles with every failure from BaseDelay up to MaxDelay. Failures are forgotten
+// when there was none for Window.
+type Policy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Window       time.Duration
+}
+
+// delay is the lock after the failures-th failed attempt
+func (p Policy) delay(failures int) time.Duration {
+	extra := failures - p.FreeAttempts
+	if extra <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < extra && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Guard counts failed logins per account and per client IP and locks them out with exponential backoff.
+// A nil Guard allows every attempt.
+//
+// An attempt is counted as failed before the password is checked and forgiven by Succeed, so that
+// parallel guesses can not all pass the check before any of them is counted.
+type Guard struct {
+	store   Store
+	account Policy
+	ip      Policy
+	now     func() time.Time
+}
+
+func NewGuard(store Store, account, ip Policy, now func() time.Time) *Guard {
+	return &Guard{store: store, account: account, ip: ip, now: now}
+}
+
+// Attempt counts a login attempt of the account from the IP as failed, unless they are locked.
+// It returns how long the locked ones stay locked; the attempt may go on only if that is zero.
+// Empty keys are skipped, so the IP can be counted before the account is known.
+func (g *Guard) Attempt(ctx context.Context, account, ip string) (time.Duration, error) {
+	if g == nil {
+		return 0, nil
+	}
+
+	var retryAfter time.Duration
+	for _, key := range g.keys(account, ip) {
+		now := g.now()
+		var locked time.Duration
+		_, err := g.store.Update(ctx, key.name, func(state State) State {
+			if state.LockedUntil.After(now) {
+				locked = state.LockedUntil.Sub(now)
+				return state
+			}
+			if now.Sub(state.LastFailure) > key.policy.Window {
+				state = State{}
+			}
+
+			state.Failures++
+			state.LastFailure = now
+			state.LockedUntil = now.Add(key.policy.delay(state.Failures))
+			state.ExpiresAt = now.Add(key.policy.Window)
+			if state.LockedUntil.After(state.ExpiresAt) {
+				state.ExpiresAt = state.LockedUntil
+			}
+			return state
+		})
+		if err != nil {
+			return 0, err
+		}
+		retryAfter = max(retryAfter, locked)
+	}
+	return retryAfter, nil
+}
+
+// Succeed forgets the failed attempts of the account and forgives the IP the attempt that succeeded.
+// Other failures of the IP are kept: one right password must not unlock guessing other accounts.
+func (g *Guard) Succeed(ctx context.Context, account, ip string) error {
+	if g == nil {
+		return nil
+	}
+
+	if account != "" {
+		if err := g.store.Delete(ctx, accountKey(account)); err != nil {
+			return err
+		}
+	}
+	if ip == "" {
+		return nil
+	}
+
+	_, err := g.store.Update(ctx, ipKey(ip), func(state State) State {
+		if state.Failures == 0 {
+			return state
+		}
+		state.Failures--
+		state.LockedUntil = state.LastFailure.Add(g.ip.delay(state.Failures))
+		return state
+	})
+	return err
+}
+
+type key struct {
+	name   string
+	policy Policy
+}
+
+func (g *Guard) keys(account, ip string) []key {
+	var keys []key
+	if account != "" {
+		keys = append(keys, key{accountKey(account), g.account})
+	}
+	if ip != "" {
+		keys = append(keys, key{ipKey(ip), g.ip})
+	}
+	return keys
+}
+
+func accountKey(account string) string { return "account:" + account }
+
+func ipKey(ip string) string { return "ip:" + ip }
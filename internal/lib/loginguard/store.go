@@ -0,0 +1,76 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// State is the failed login attempts recorded for a key
+type State struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+	ExpiresAt   time.Time // the state can be forgotten after this time
+}
+
+// Store keeps the attempt state by key, e.g. "account:<user id>" or "ip:<address>"
+type Store interface {
+	// Update atomically replaces the state of the key with fn of the current one and returns the new state.
+	// A missing or expired state is passed as the zero State; fn must not block.
+	Update(ctx context.Context, key string, fn func(State) State) (State, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// sweepEvery is how many writes pass between removals of expired states from a MemoryStore
+const sweepEvery = 1024
+
+// MemoryStore keeps the state in memory of a single instance, so each instance counts attempts separately.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	states map[string]State
+	writes int
+}
+
+// NewMemoryStore creates a store that expires states by the now clock
+func NewMemoryStore(now func() time.Time) *MemoryStore {
+	return &MemoryStore{now: now, states: make(map[string]State)}
+}
+
+func (s *MemoryStore) Update(_ context.Context, key string, fn func(State) State) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	state, ok := s.states[key]
+	if !ok || !now.Before(state.ExpiresAt) {
+		state = State{}
+	}
+
+	state = fn(state)
+	if now.Before(state.ExpiresAt) {
+		s.states[key] = state
+	} else {
+		delete(s.states, key)
+	}
+
+	s.writes++
+	if s.writes%sweepEvery == 0 {
+		for k, st := range s.states {
+			if !now.Before(st.ExpiresAt) {
+				delete(s.states, k)
+			}
+		}
+	}
+	return state, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, key)
+	return nil
+}
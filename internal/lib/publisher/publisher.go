@@ -0,0 +1,34 @@
+package publisher
+
+import (
+	"context"
+	"log/slog"
+
+	"avito/internal/domain/models"
+)
+
+// Publisher delivers outbox events to downstream consumers.
+// Implementations must tolerate duplicates: the relay guarantees at-least-once delivery.
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// LogPublisher writes events to the log. It stands in for a message broker in local runs.
+type LogPublisher struct {
+	logger *slog.Logger
+}
+
+func NewLogPublisher(logger *slog.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, event models.Event) error {
+	p.logger.Info("Event published",
+		slog.String("op", "publisher.LogPublisher.Publish"),
+		slog.Int64("event_id", event.ID),
+		slog.String("event_type", event.Type),
+		slog.Int("aggregate_id", event.AggregateID),
+		slog.String("payload", string(event.Payload)),
+	)
+	return nil
+}
@@ -0,0 +1,119 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"avito/internal/domain/models"
+)
+
+var (
+	ErrQueueFull    = errors.New("notification queue is full")
+	ErrSenderClosed = errors.New("sender is closed")
+)
+
+type job struct {
+	recipient string
+	flat      models.Flat
+}
+
+// AsyncSender queues notifications and delivers them through the wrapped Sender
+// in background workers, retrying failed deliveries with exponential backoff.
+// Send never waits for the wrapped Sender.
+type AsyncSender struct {
+	next       Sender
+	jobs       chan job
+	maxRetries int
+	retryDelay time.Duration
+	logger     *slog.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewAsyncSender(next Sender, workers, queueSize, maxRetries int, retryDelay time.Duration, logger *slog.Logger) *AsyncSender {
+	if workers < 1 {
+		workers = 1
+	}
+
+	s := &AsyncSender{
+		next:       next,
+		jobs:       make(chan job, queueSize),
+		maxRetries: maxRetries,
+		retryDelay: retryDelay,
+		logger:     logger,
+	}
+
+	s.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+
+	return s
+}
+
+// Send enqueues the notification. The request context is not propagated to the delivery.
+func (s *AsyncSender) Send(_ context.Context, recipient string, flat models.Flat) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrSenderClosed
+	}
+
+	select {
+	case s.jobs <- job{recipient: recipient, flat: flat}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting notifications and waits until the queued ones are processed.
+func (s *AsyncSender) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.jobs)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *AsyncSender) worker() {
+	defer s.wg.Done()
+
+	for j := range s.jobs {
+		s.deliver(j)
+	}
+}
+
+func (s *AsyncSender) deliver(j job) {
+	const op = "sender.AsyncSender.deliver"
+
+	delay := s.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := s.next.Send(context.Background(), j.recipient, j.flat)
+		if err == nil {
+			return
+		}
+
+		if attempt >= s.maxRetries {
+			s.logger.Error("Notification dropped after retries", slog.String("op", op), slog.String("recipient", j.recipient),
+				slog.Int("flat_id", j.flat.ID), slog.Int("attempts", attempt+1), "error", err)
+			return
+		}
+
+		s.logger.Warn("Notification delivery failed, retrying", slog.String("op", op), slog.String("recipient", j.recipient),
+			slog.Int("flat_id", j.flat.ID), slog.Int("attempt", attempt+1), "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
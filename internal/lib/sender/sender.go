@@ -0,0 +1,79 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"avito/internal/domain/models"
+)
+
+// Sender delivers a notification about a new flat to a house subscriber.
+type Sender interface {
+	Send(ctx context.Context, recipient string, flat models.Flat) error
+}
+
+// FileSender appends notifications as JSON lines to a file, or only logs them when the path is empty.
+// Useful for local runs and tests where no real mailer is available.
+type FileSender struct {
+	path   string
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+func NewFileSender(path string, logger *slog.Logger) *FileSender {
+	return &FileSender{path: path, logger: logger}
+}
+
+func (s *FileSender) Send(ctx context.Context, recipient string, flat models.Flat) error {
+	const op = "sender.FileSender.Send"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	message := fmt.Sprintf("New flat in house %d: %d rooms, price %d", flat.HouseID, flat.Rooms, flat.Price)
+
+	if s.path == "" {
+		s.logger.Info("Notification sent", slog.String("op", op), slog.String("recipient", recipient),
+			slog.Int("flat_id", flat.ID), slog.String("message", message))
+		return nil
+	}
+
+	line, err := json.Marshal(struct {
+		Time      time.Time `json:"time"`
+		Recipient string    `json:"recipient"`
+		FlatID    int       `json:"flat_id"`
+		HouseID   int       `json:"house_id"`
+		Message   string    `json:"message"`
+	}{
+		Time:      time.Now(),
+		Recipient: recipient,
+		FlatID:    flat.ID,
+		HouseID:   flat.HouseID,
+		Message:   message,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Debug("Notification written", slog.String("op", op), slog.String("recipient", recipient), slog.Int("flat_id", flat.ID))
+	return nil
+}
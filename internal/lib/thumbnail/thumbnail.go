@@ -0,0 +1,80 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+)
+
+// Make scales the image down so that its longer side is at most maxSide, keeping the aspect ratio.
+// Each pixel of the thumbnail is the average of the source pixels it covers.
+// Images that already fit are copied as they are.
+func Make(src image.Image, maxSide int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	tw, th := w, h
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			tw, th = maxSide, max(1, h*maxSide/w)
+		} else {
+			tw, th = max(1, w*maxSide/h), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			dst.SetRGBA(x, y, average(src, x0, y0, max(x1, x0+1), max(y1, y0+1)))
+		}
+	}
+
+	return dst
+}
+
+// average is the mean color of the rectangle. JPEG and PNG photos decode to *image.YCbCr
+// and *image.RGBA, these are read directly: a call of At per pixel would dominate the upload.
+func average(src image.Image, x0, y0, x1, y1 int) color.RGBA {
+	switch src := src.(type) {
+	case *image.YCbCr:
+		return averageYCbCr(src, x0, y0, x1, y1)
+	case *image.RGBA:
+		return averageRGBA(src, x0, y0, x1, y1)
+	}
+
+	var r, g, b, a, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			cr, cg, cb, ca := src.At(x, y).RGBA()
+			r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+			n++
+		}
+	}
+	return color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: uint8(a / n >> 8)}
+}
+
+func averageRGBA(src *image.RGBA, x0, y0, x1, y1 int) color.RGBA {
+	var r, g, b, a, n uint64
+	for y := y0; y < y1; y++ {
+		row := src.Pix[src.PixOffset(x0, y):src.PixOffset(x1, y)]
+		for i := 0; i < len(row); i += 4 {
+			r, g, b, a = r+uint64(row[i]), g+uint64(row[i+1]), b+uint64(row[i+2]), a+uint64(row[i+3])
+			n++
+		}
+	}
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)}
+}
+
+func averageYCbCr(src *image.YCbCr, x0, y0, x1, y1 int) color.RGBA {
+	var r, g, b, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			yi, ci := src.YOffset(x, y), src.COffset(x, y)
+			cr, cg, cb := color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+			r, g, b = r+uint64(cr), g+uint64(cg), b+uint64(cb)
+			n++
+		}
+	}
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 0xff}
+}
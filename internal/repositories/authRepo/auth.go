@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/lib/pq"
 	"log/slog"
+	"time"
 
 	"avito/internal/domain/models"
 	"avito/internal/repositories"
@@ -14,7 +15,22 @@ import (
 
 type AuthRepo interface {
 	CreateUser(ctx context.Context, user *models.User) (string, error)
-	GetUserByEmail(ctx context.Context, id string) (*models.User, error)
+	CreateUserWithInvite(ctx context.Context, user *models.User, codeHash string) (string, error)
+	CreateInvite(ctx context.Context, invite *models.Invite) error
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, usedID string, next *models.RefreshToken) error
+	RevokeRefreshFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	GetRevokedAccessTokens(ctx context.Context) (map[string]time.Time, error)
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)
+	SetUserRole(ctx context.Context, userID string, role models.Role) (*models.User, error)
+	SetUserDisabled(ctx context.Context, userID string, disabled bool) (*models.User, error)
+	RevokeUserSessions(ctx context.Context, userID string) (*models.User, error)
+	GetRevokedUsers(ctx context.Context) ([]models.User, error)
 }
 
 type Repository struct {
@@ -30,10 +46,7 @@ func NewRepository(db *sql.DB, logger *slog.Logger) AuthRepo {
 func (r *Repository) CreateUser(ctx context.Context, user *models.User) (string, error) {
 	const op = "repositories.auth.CreateUser"
 
-	query := "INSERT INTO users (email, password_hash, role) VALUES ($1, $2, $3) RETURNING id"
-
-	var userID string
-	err := r.db.QueryRowContext(ctx, query, user.Email, user.Password, user.Role).Scan(&userID)
+	userID, err := insertUser(ctx, r.db, user)
 	if err != nil {
 		var pqErr *pq.Error
 		if errors.As(err, &pqErr) && pqErr.Code == repositories.UniqueViolation {
@@ -47,16 +60,24 @@ func (r *Repository) CreateUser(ctx context.Context, user *models.User) (string,
 	return userID, nil
 }
 
-// GetUserByEmail - Login
-func (r *Repository) GetUserByEmail(ctx context.Context, id string) (*models.User, error) {
-	const op = "repositories.auth.GetUserByEmail"
+func insertUser(ctx context.Context, q queryer, user *models.User) (string, error) {
+	query := "INSERT INTO users (email, password_hash, role) VALUES ($1, $2, $3) RETURNING id"
+
+	var userID string
+	err := q.QueryRowContext(ctx, query, user.Email, user.Password, user.Role).Scan(&userID)
+	return userID, err
+}
+
+// GetUserByID - Login by ID
+func (r *Repository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	const op = "repositories.auth.GetUserByID"
 
-	query := "SELECT id, email, password_hash, role FROM users WHERE id = $1"
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
 
 	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Email, &user.Password, &user.Role)
+	err := scanUser(r.db.QueryRowContext(ctx, query, id), user)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, sql.ErrNoRows) || isInvalidID(err) {
 			r.logger.Warn("User not found", "op", op, "id", id)
 			return nil, fmt.Errorf("%s: %w", op, repositories.ErrUserNotFound)
 		}
@@ -67,3 +88,24 @@ func (r *Repository) GetUserByEmail(ctx context.Context, id string) (*models.Use
 	r.logger.Info("User found", "op", op, "id", id)
 	return user, nil
 }
+
+// GetUserByEmail - Login by email. Emails are compared case-insensitively.
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	const op = "repositories.auth.GetUserByEmail"
+
+	query := "SELECT " + userColumns + " FROM users WHERE lower(email) = lower($1)"
+
+	user := &models.User{}
+	err := scanUser(r.db.QueryRowContext(ctx, query, email), user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.Warn("User not found", "op", op)
+			return nil, fmt.Errorf("%s: %w", op, repositories.ErrUserNotFound)
+		}
+		r.logger.Error("Failed to query user by email", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.logger.Info("User found", "op", op, "id", user.ID)
+	return user, nil
+}
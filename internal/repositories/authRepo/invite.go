@@ -0,0 +1,83 @@
+package authRepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+)
+
+// CreateInvite stores a minted invite; ID and CreatedAt are set on the invite
+func (r *Repository) CreateInvite(ctx context.Context, invite *models.Invite) error {
+	const op = "repositories.auth.CreateInvite"
+
+	query := `
+		INSERT INTO invites (code_hash, role, created_by, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, invite.CodeHash, invite.Role, invite.CreatedBy, invite.ExpiresAt).
+		Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to store invite", "op", op, "error", err, "createdBy", invite.CreatedBy)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// CreateUserWithInvite redeems the invite and creates the user in one transaction.
+// The invite must be unused, unexpired and issued for the user's role, otherwise ErrInvalidInvite is returned.
+// If the user can not be created the invite stays unused.
+func (r *Repository) CreateUserWithInvite(ctx context.Context, user *models.User, codeHash string) (string, error) {
+	const op = "repositories.auth.CreateUserWithInvite"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "op", op, "error", err)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// The row lock taken here makes a concurrent registration with the same code wait and then find it used
+	query := `
+		UPDATE invites SET used_at = CURRENT_TIMESTAMP
+		WHERE code_hash = $1 AND role = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	`
+	res, err := tx.ExecContext(ctx, query, codeHash, user.Role)
+	if err != nil {
+		r.logger.Error("Failed to redeem invite", "op", op, "error", err)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		r.logger.Warn("Invalid invite", "op", op, "email", user.Email)
+		return "", fmt.Errorf("%s: %w", op, repositories.ErrInvalidInvite)
+	}
+
+	userID, err := insertUser(ctx, tx, user)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == repositories.UniqueViolation {
+			r.logger.Warn("User already exists", "op", op, "email", user.Email)
+			return "", fmt.Errorf("%s: %w", op, repositories.ErrUserExists)
+		}
+		r.logger.Error("Failed to execute statement", "op", op, "error", err)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE invites SET used_by = $1 WHERE code_hash = $2", userID, codeHash); err != nil {
+		r.logger.Error("Failed to mark invite used", "op", op, "error", err)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "op", op, "error", err)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
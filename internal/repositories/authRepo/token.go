@@ -0,0 +1,174 @@
+package authRepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+)
+
+// CreateRefreshToken stores a refresh token issued at login; ID and CreatedAt are set on the token.
+// An empty FamilyID starts a new family.
+func (r *Repository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	const op = "repositories.auth.CreateRefreshToken"
+
+	if err := insertRefreshToken(ctx, r.db, token); err != nil {
+		r.logger.Error("Failed to store refresh token", "op", op, "error", err, "userID", token.UserID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetRefreshToken finds a refresh token by its hash. Returns nil if there is no such token.
+func (r *Repository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	const op = "repositories.auth.GetRefreshToken"
+
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, created_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	token := &models.RefreshToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash,
+		&token.ExpiresAt, &token.CreatedAt, &token.RevokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to query refresh token", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken revokes the used token and stores its successor in one transaction.
+// Returns ErrRefreshTokenUsed if the token has already been revoked, e.g. by a concurrent refresh.
+func (r *Repository) RotateRefreshToken(ctx context.Context, usedID string, next *models.RefreshToken) error {
+	const op = "repositories.auth.RotateRefreshToken"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "op", op, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL", usedID)
+	if err != nil {
+		r.logger.Error("Failed to revoke refresh token", "op", op, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		r.logger.Warn("Refresh token was already used", "op", op, "tokenID", usedID)
+		return fmt.Errorf("%s: %w", op, repositories.ErrRefreshTokenUsed)
+	}
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		r.logger.Error("Failed to store refresh token", "op", op, "error", err, "userID", next.UserID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "op", op, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeRefreshFamily revokes every token of the family, ending the session
+func (r *Repository) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	const op = "repositories.auth.RevokeRefreshFamily"
+
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL"
+	if _, err := r.db.ExecContext(ctx, query, familyID); err != nil {
+		r.logger.Error("Failed to revoke refresh tokens", "op", op, "error", err, "familyID", familyID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of the user, ending all their sessions
+func (r *Repository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	const op = "repositories.auth.RevokeUserRefreshTokens"
+
+	query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL"
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		r.logger.Error("Failed to revoke refresh tokens", "op", op, "error", err, "userID", userID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeAccessToken adds the access token to the denylist until it expires
+func (r *Repository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "repositories.auth.RevokeAccessToken"
+
+	query := "INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING"
+	if _, err := r.db.ExecContext(ctx, query, jti, expiresAt); err != nil {
+		r.logger.Error("Failed to revoke access token", "op", op, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetRevokedAccessTokens returns the expiry of every revoked access token that has not expired yet, by jti.
+// Expired entries are deleted.
+func (r *Repository) GetRevokedAccessTokens(ctx context.Context) (map[string]time.Time, error) {
+	const op = "repositories.auth.GetRevokedAccessTokens"
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= CURRENT_TIMESTAMP"); err != nil {
+		r.logger.Error("Failed to delete expired revoked tokens", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT jti, expires_at FROM revoked_tokens")
+	if err != nil {
+		r.logger.Error("Failed to query revoked tokens", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	revoked := make(map[string]time.Time)
+	for rows.Next() {
+		var jti string
+		var expiresAt time.Time
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			r.logger.Error("Failed to scan revoked token", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		revoked[jti] = expiresAt
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Rows iteration error", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertRefreshToken(ctx context.Context, q queryer, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, COALESCE(NULLIF($2, '')::uuid, uuid_generate_v4()), $3, $4)
+		RETURNING id, family_id, created_at
+	`
+
+	return q.QueryRowContext(ctx, query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.FamilyID, &token.CreatedAt)
+}
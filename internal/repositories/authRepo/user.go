@@ -0,0 +1,156 @@
+package authRepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+)
+
+const userColumns = "id, email, password_hash, role, created_at, disabled_at, sessions_revoked_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner, user *models.User) error {
+	return row.Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.CreatedAt, &user.DisabledAt,
+		&user.SessionsRevokedAt)
+}
+
+// isInvalidID reports whether the query failed because the user ID is not a UUID
+func isInvalidID(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == repositories.InvalidTextRepresentation
+}
+
+// ListUsers returns users ordered by email
+func (r *Repository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	const op = "repositories.auth.ListUsers"
+
+	query := "SELECT " + userColumns + " FROM users WHERE TRUE"
+	var args []interface{}
+
+	if filter.Role != nil {
+		args = append(args, *filter.Role)
+		query += fmt.Sprintf(" AND role = $%d", len(args))
+	}
+	if filter.Email != nil {
+		args = append(args, "%"+repositories.EscapeLike(*filter.Email)+"%")
+		query += fmt.Sprintf(" AND email ILIKE $%d", len(args))
+	}
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			query += " AND disabled_at IS NOT NULL"
+		} else {
+			query += " AND disabled_at IS NULL"
+		}
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY email LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	return r.queryUsers(ctx, op, query, args...)
+}
+
+// GetRevokedUsers returns the users that are disabled or had their sessions revoked
+func (r *Repository) GetRevokedUsers(ctx context.Context) ([]models.User, error) {
+	const op = "repositories.auth.GetRevokedUsers"
+
+	query := "SELECT " + userColumns + " FROM users WHERE disabled_at IS NOT NULL OR sessions_revoked_at IS NOT NULL"
+
+	return r.queryUsers(ctx, op, query)
+}
+
+func (r *Repository) queryUsers(ctx context.Context, op, query string, args ...any) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to query users", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			r.logger.Error("Failed to scan user", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error during rows iteration", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// SetUserRole changes the role of the user and ends all their sessions, so that the new role applies at once
+func (r *Repository) SetUserRole(ctx context.Context, userID string, role models.Role) (*models.User, error) {
+	const op = "repositories.auth.SetUserRole"
+
+	return r.updateUser(ctx, op, userID, "role = $2, sessions_revoked_at = CURRENT_TIMESTAMP", true, role)
+}
+
+// SetUserDisabled disables or enables the user. Disabling ends all sessions of the user.
+func (r *Repository) SetUserDisabled(ctx context.Context, userID string, disabled bool) (*models.User, error) {
+	const op = "repositories.auth.SetUserDisabled"
+
+	if disabled {
+		return r.updateUser(ctx, op, userID,
+			"disabled_at = COALESCE(disabled_at, CURRENT_TIMESTAMP), sessions_revoked_at = CURRENT_TIMESTAMP", true)
+	}
+	return r.updateUser(ctx, op, userID, "disabled_at = NULL", false)
+}
+
+// RevokeUserSessions rejects every access token issued to the user until now and revokes their refresh tokens
+func (r *Repository) RevokeUserSessions(ctx context.Context, userID string) (*models.User, error) {
+	const op = "repositories.auth.RevokeUserSessions"
+
+	return r.updateUser(ctx, op, userID, "sessions_revoked_at = CURRENT_TIMESTAMP", true)
+}
+
+// updateUser applies set to the user and, with revokeSessions, revokes their refresh tokens in the same transaction.
+// Extra arguments of set start at $2.
+func (r *Repository) updateUser(ctx context.Context, op, userID, set string, revokeSessions bool, args ...any) (*models.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE users SET " + set + " WHERE id = $1 RETURNING " + userColumns
+
+	user := &models.User{}
+	if err := scanUser(tx.QueryRowContext(ctx, query, append([]any{userID}, args...)...), user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) || isInvalidID(err) {
+			r.logger.Warn("User not found", "op", op, "id", userID)
+			return nil, fmt.Errorf("%s: %w", op, repositories.ErrUserNotFound)
+		}
+		r.logger.Error("Failed to update user", "op", op, "error", err, "id", userID)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if revokeSessions {
+		query := "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL"
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			r.logger.Error("Failed to revoke refresh tokens", "op", op, "error", err, "id", userID)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
@@ -3,10 +3,29 @@ package repositories
 import "errors"
 
 const (
-	UniqueViolation = "23505" // PostgreSQL error
+	UniqueViolation     = "23505" // PostgreSQL error
+	ForeignKeyViolation = "23503" // PostgreSQL error
+	// InvalidTextRepresentation is returned e.g. for a malformed UUID
+	InvalidTextRepresentation = "22P02" // PostgreSQL error
 )
 
 var (
-	ErrUserExists   = errors.New("user already exists")
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists    = errors.New("user already exists")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrHouseNotFound = errors.New("house not found")
+	ErrFlatNotFound  = errors.New("flat not found")
+	// ErrFlatNumberExists means the house already has a flat with this number
+	ErrFlatNumberExists = errors.New("flat number already exists in the house")
+	// ErrFlatStatusChanged means the flat was modified concurrently and the conditional update did not apply
+	ErrFlatStatusChanged = errors.New("flat status was changed concurrently")
+	// ErrHouseExists means another house has the same normalized address
+	ErrHouseExists = errors.New("house with this address already exists")
+	// ErrPhotoNotFound means the flat has no photo with this ID
+	ErrPhotoNotFound = errors.New("photo not found")
+	// ErrTooManyPhotos means the flat already has the maximum number of photos
+	ErrTooManyPhotos = errors.New("flat has too many photos")
+	// ErrRefreshTokenUsed means the refresh token has already been exchanged or revoked
+	ErrRefreshTokenUsed = errors.New("refresh token already used")
+	// ErrInvalidInvite means the invite code is unknown, used, expired or issued for another role
+	ErrInvalidInvite = errors.New("invalid invite code")
 )
@@ -3,17 +3,36 @@ package flatRepo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"log/slog"
+	"time"
 
 	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"avito/internal/repositories/outboxRepo"
 )
 
 type FlatRepo interface {
 	CreateFlat(ctx context.Context, flat *models.Flat) (int, error)
-	GetFlatsByHouseID(ctx context.Context, houseID int) ([]*models.Flat, error)
-	UpdateFlatStatus(ctx context.Context, flatID int, status string, moderatorID *string) (*models.Flat, error)
-	GetFlatByID(ctx context.Context, flatID int) (*models.Flat, error)
+	GetFlatsByHouseID(ctx context.Context, houseID int, includeArchived bool) ([]*models.Flat, error)
+	UpdateFlatStatus(ctx context.Context, update models.StatusUpdate) (*models.Flat, bool, error)
+	UpdateFlat(ctx context.Context, update models.FlatUpdate) (*models.Flat, error)
+	GetFlatEdits(ctx context.Context, flatID int) ([]models.FlatEdit, error)
+	GetFlatByID(ctx context.Context, flatID int, includeArchived bool) (*models.Flat, error)
+	GetFlatsByOwner(ctx context.Context, userID string, includeArchived bool) ([]models.Flat, error)
+	SetFlatArchived(ctx context.Context, flatID int, archived bool) (*models.Flat, error)
+	GetStatusHistory(ctx context.Context, flatID int) ([]models.StatusChange, error)
+	GetPriceHistory(ctx context.Context, flatID int) ([]models.PriceChange, error)
+	RenewModeration(ctx context.Context, flatID int, moderatorID string, ttl time.Duration) (*models.Flat, error)
+	ReleaseExpiredModerations(ctx context.Context) ([]models.Flat, error)
+	GetModerationQueue(ctx context.Context, houseID *int, limit, offset int) ([]models.Flat, error)
+	ClaimNextFlat(ctx context.Context, moderatorID string, houseID *int, ttl time.Duration) (*models.Flat, error)
+	AddFlatPhoto(ctx context.Context, photo *models.FlatPhoto, update models.FlatUpdate, maxPhotos int) (*models.Flat, error)
+	DeleteFlatPhoto(ctx context.Context, flatID, photoID int) (*models.FlatPhoto, error)
+	ReorderFlatPhotos(ctx context.Context, flatID int, photoIDs []int) (*models.Flat, error)
 }
 
 type Repository struct {
@@ -25,31 +44,146 @@ func NewRepository(db *sql.DB, logger *slog.Logger) FlatRepo {
 	return &Repository{db: db, logger: logger}
 }
 
+// flatEvent is the outbox payload of flat events
+type flatEvent struct {
+	ID            int     `json:"id"`
+	HouseID       int     `json:"house_id"`
+	FlatNumber    *int    `json:"flat_number,omitempty"`
+	Price         int     `json:"price"`
+	Rooms         int     `json:"rooms"`
+	Status        string  `json:"status"`
+	ModeratorID   *string `json:"moderator_id,omitempty"`
+	CreatedBy     *string `json:"created_by,omitempty"`
+	DeclineReason *string `json:"decline_reason,omitempty"`
+}
+
+func newFlatEvent(flat *models.Flat) flatEvent {
+	return flatEvent{
+		ID:            flat.ID,
+		HouseID:       flat.HouseID,
+		FlatNumber:    flat.FlatNumber,
+		Price:         flat.Price,
+		Rooms:         flat.Rooms,
+		Status:        flat.Status,
+		ModeratorID:   flat.ModeratorID,
+		CreatedBy:     flat.CreatedBy,
+		DeclineReason: flat.DeclineReason,
+	}
+}
+
+// FlatColumns is the column list matching ScanFlat. The flat's photos are aggregated into a JSON array.
+const FlatColumns = "id, house_id, flat_number, price, rooms, status, moderator_id, moderation_expires_at, created_at, " +
+	"created_by, decline_reason, decline_comment, archived_at, previous_price, price_changed_at, " +
+	"total_area, living_area, floor, total_floors, balcony, renovation, description, " +
+	"(" + photosQuery + ")"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// ScanFlat reads a row selected with FlatColumns
+func ScanFlat(row scanner, flat *models.Flat) error {
+	return row.Scan(
+		&flat.ID,
+		&flat.HouseID,
+		&flat.FlatNumber,
+		&flat.Price,
+		&flat.Rooms,
+		&flat.Status,
+		&flat.ModeratorID,
+		&flat.ModerationExpiresAt,
+		&flat.CreatedAt,
+		&flat.CreatedBy,
+		&flat.DeclineReason,
+		&flat.DeclineComment,
+		&flat.ArchivedAt,
+		&flat.PreviousPrice,
+		&flat.PriceChangedAt,
+		&flat.TotalArea,
+		&flat.LivingArea,
+		&flat.Floor,
+		&flat.TotalFloors,
+		&flat.Balcony,
+		&flat.Renovation,
+		&flat.Description,
+		photoList{&flat.Photos},
+	)
+}
+
+// recordStatusChange writes the status change to flat_status_history and to the outbox within tx.
+// actorID is nil when the change is made by the service itself.
+func recordStatusChange(ctx context.Context, tx *sql.Tx, flat *models.Flat, previousStatus string, actorID, comment *string) error {
+	historyQuery := `
+		INSERT INTO flat_status_history (flat_id, previous_status, new_status, actor_id, comment)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	if _, err := tx.ExecContext(ctx, historyQuery, flat.ID, previousStatus, flat.Status, actorID, comment); err != nil {
+		return fmt.Errorf("write status history: %w", err)
+	}
+
+	if err := outboxRepo.InsertEvent(ctx, tx, models.EventFlatStatusChanged, flat.ID, newFlatEvent(flat)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // CreateFlat - AuthOnly
 func (r *Repository) CreateFlat(ctx context.Context, flat *models.Flat) (int, error) {
 	const op = "repository.flat.CreateFlat"
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "op", op, "error", err)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO flats (house_id, flat_number, price, rooms, status)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
+		INSERT INTO flats (house_id, flat_number, price, rooms, status, created_by,
+		                   total_area, living_area, floor, total_floors, balcony, renovation, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, created_at
 	`
 
 	var flatID int
-	err := r.db.QueryRowContext(ctx, query, flat.HouseID, flat.FlatNumber, flat.Price, flat.Rooms, flat.Status).Scan(&flatID)
+	err = tx.QueryRowContext(ctx, query, flat.HouseID, flat.FlatNumber, flat.Price, flat.Rooms, flat.Status, flat.CreatedBy,
+		flat.TotalArea, flat.LivingArea, flat.Floor, flat.TotalFloors, flat.Balcony, flat.Renovation, flat.Description).
+		Scan(&flatID, &flat.CreatedAt)
 	if err != nil {
 		r.logger.Error("Failed to create flat", "op", op, "error", err, "houseID", flat.HouseID, "flatNumber", flat.FlatNumber)
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := recordPrice(ctx, tx, flatID, flat.Price, flat.CreatedBy); err != nil {
+		r.logger.Error("Failed to record price", "op", op, "error", err, "flatID", flatID)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	event := newFlatEvent(flat)
+	event.ID = flatID
+	if err := outboxRepo.InsertEvent(ctx, tx, models.EventFlatCreated, flatID, event); err != nil {
+		r.logger.Error("Failed to write outbox event", "op", op, "error", err, "flatID", flatID)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "op", op, "error", err)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return flatID, nil
 }
 
-// GetFlatsByHouseID - AuthOnly
-func (r *Repository) GetFlatsByHouseID(ctx context.Context, houseID int) ([]*models.Flat, error) {
+// GetFlatsByHouseID - AuthOnly. Archived flats are returned only if includeArchived is set.
+func (r *Repository) GetFlatsByHouseID(ctx context.Context, houseID int, includeArchived bool) ([]*models.Flat, error) {
 	const op = "repository.flat.GetFlatsByHouseID"
 
-	query := "SELECT id, house_id, flat_number, price, rooms, status FROM flats WHERE house_id = $1"
+	query := "SELECT " + FlatColumns + " FROM flats WHERE house_id = $1"
+	if !includeArchived {
+		query += " AND archived_at IS NULL"
+	}
 
 	rows, err := r.db.QueryContext(ctx, query, houseID)
 	if err != nil {
@@ -61,7 +195,7 @@ func (r *Repository) GetFlatsByHouseID(ctx context.Context, houseID int) ([]*mod
 	var flats []*models.Flat
 	for rows.Next() {
 		flat := &models.Flat{}
-		if err := rows.Scan(&flat.ID, &flat.HouseID, &flat.FlatNumber, &flat.Price, &flat.Rooms, &flat.Status); err != nil {
+		if err := ScanFlat(rows, flat); err != nil {
 			r.logger.Error("Failed to scan flat", "op", op, "error", err)
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
@@ -76,55 +210,285 @@ func (r *Repository) GetFlatsByHouseID(ctx context.Context, houseID int) ([]*mod
 	return flats, nil
 }
 
-// UpdateFlatStatus - OnlyModerator
-func (r *Repository) UpdateFlatStatus(ctx context.Context, flatID int, status string, moderatorID *string) (*models.Flat, error) {
+// GetFlatsByOwner - AuthOnly. Flats created by the user in any status, newest first.
+// Archived flats are returned only if includeArchived is set.
+func (r *Repository) GetFlatsByOwner(ctx context.Context, userID string, includeArchived bool) ([]models.Flat, error) {
+	const op = "repository.flat.GetFlatsByOwner"
+
+	query := "SELECT " + FlatColumns + " FROM flats WHERE created_by = $1"
+	if !includeArchived {
+		query += " AND archived_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		r.logger.Error("Failed to get flats by owner", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var flats []models.Flat
+	for rows.Next() {
+		var flat models.Flat
+		if err := ScanFlat(rows, &flat); err != nil {
+			r.logger.Error("Failed to scan flat", slog.String("op", op), "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		flats = append(flats, flat)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Rows iteration error", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flats, nil
+}
+
+// UpdateFlatStatus - OnlyModerator, or the owner resubmitting a declined flat. The status is changed
+// with a single conditional UPDATE, so of several concurrent moderators only one can succeed;
+// the others get repositories.ErrFlatStatusChanged.
+// The decline reason is kept only while the flat is declined.
+// The change is recorded to flat_status_history in the same transaction.
+// The second result is true only for the flat's first approval, not for re-approvals after re-moderation.
+func (r *Repository) UpdateFlatStatus(ctx context.Context, update models.StatusUpdate) (*models.Flat, bool, error) {
 	const op = "repository.flat.UpdateFlatStatus"
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", slog.String("op", op), "error", err)
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE flats
-		SET status = $1, moderator_id = $2
+		SET status = $1,
+		    moderator_id = $2,
+		    moderation_expires_at = CASE WHEN $1 = 'on moderation' THEN CURRENT_TIMESTAMP + make_interval(secs => $6) END,
+		    decline_reason = CASE WHEN $1 = 'declined' THEN $7 END,
+		    decline_comment = CASE WHEN $1 = 'declined' THEN $8 END
 		WHERE id = $3
-		RETURNING id, house_id, flat_number, price, rooms, status, moderator_id
-	`
+		  AND archived_at IS NULL
+		  AND status = $4
+		  AND (status <> 'on moderation' OR moderator_id = $5)
+		RETURNING ` + FlatColumns
 
 	var flat models.Flat
-	err := r.db.QueryRowContext(ctx, query, status, moderatorID, flatID).Scan(
-		&flat.ID,
-		&flat.HouseID,
-		&flat.FlatNumber,
-		&flat.Price,
-		&flat.Rooms,
-		&flat.Status,
-		&flat.ModeratorID,
-	)
+	row := tx.QueryRowContext(ctx, query, update.To, update.ModeratorID, update.FlatID, update.From, update.ActorID,
+		update.LeaseTTL.Seconds(), update.DeclineReason, update.Comment)
+	err = ScanFlat(row, &flat)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, r.statusUpdateMiss(ctx, tx, update.FlatID)
+		}
+		r.logger.Error("Failed to update and retrieve flat data", slog.String("op", op), "error", err, slog.Int("flatID", update.FlatID))
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var firstApproval bool
+	if flat.Status == models.StatusApproved {
+		if firstApproval, err = markApproved(ctx, tx, flat.ID); err != nil {
+			r.logger.Error("Failed to mark flat approved", slog.String("op", op), "error", err, slog.Int("flatID", update.FlatID))
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := recordStatusChange(ctx, tx, &flat, update.From, &update.ActorID, update.Comment); err != nil {
+		r.logger.Error("Failed to record status change", slog.String("op", op), "error", err, slog.Int("flatID", update.FlatID))
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", slog.String("op", op), "error", err)
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &flat, firstApproval, nil
+}
+
+// UpdateFlat - owner only. Applies the edit if the flat is still in update.From and records it to flat_edits.
+// When the status changes as well, the change goes to flat_status_history, like moderator changes.
+func (r *Repository) UpdateFlat(ctx context.Context, update models.FlatUpdate) (*models.Flat, error) {
+	const op = "repository.flat.UpdateFlat"
 
+	changes, err := json.Marshal(update.Changes)
 	if err != nil {
-		r.logger.Error("Failed to update and retrieve flat data", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	flat, err := r.applyFlatUpdate(ctx, tx, update, changes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flat, nil
+}
+
+// applyFlatUpdate writes the owner's edit in tx: the new field values, the edit record,
+// the status change if any and the outbox event
+func (r *Repository) applyFlatUpdate(ctx context.Context, tx *sql.Tx, update models.FlatUpdate, changes []byte) (*models.Flat, error) {
+	const op = "repository.flat.applyFlatUpdate"
+
+	query := `
+		UPDATE flats
+		SET flat_number = $1, price = $2, rooms = $3, status = $4,
+		    total_area = $7, living_area = $8, floor = $9, total_floors = $10,
+		    balcony = $11, renovation = $12, description = $13
+		WHERE id = $5 AND status = $6 AND archived_at IS NULL
+		RETURNING ` + FlatColumns
+
+	d := update.FlatDetails
+	var flat models.Flat
+	row := tx.QueryRowContext(ctx, query, update.FlatNumber, update.Price, update.Rooms, update.To, update.FlatID, update.From,
+		d.TotalArea, d.LivingArea, d.Floor, d.TotalFloors, d.Balcony, d.Renovation, d.Description)
+	if err := ScanFlat(row, &flat); err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, r.statusUpdateMiss(ctx, tx, update.FlatID)
+		case errors.As(err, &pqErr) && pqErr.Code == repositories.UniqueViolation:
+			r.logger.Warn("Flat number already exists", slog.String("op", op), slog.Int("flatID", update.FlatID))
+			return nil, repositories.ErrFlatNumberExists
+		}
+		r.logger.Error("Failed to update flat", slog.String("op", op), "error", err, slog.Int("flatID", update.FlatID))
+		return nil, err
+	}
+
+	if _, ok := update.Changes["price"]; ok {
+		if err := recordPrice(ctx, tx, flat.ID, flat.Price, &update.EditorID); err != nil {
+			r.logger.Error("Failed to record price", slog.String("op", op), "error", err, slog.Int("flatID", flat.ID))
+			return nil, err
+		}
+		if err := syncPriceChange(ctx, tx, &flat); err != nil {
+			r.logger.Error("Failed to update last price change", slog.String("op", op), "error", err, slog.Int("flatID", flat.ID))
+			return nil, err
+		}
+	}
+
+	editQuery := "INSERT INTO flat_edits (flat_id, editor_id, changes) VALUES ($1, $2, $3)"
+	if _, err := tx.ExecContext(ctx, editQuery, flat.ID, update.EditorID, changes); err != nil {
+		r.logger.Error("Failed to record flat edit", slog.String("op", op), "error", err, slog.Int("flatID", flat.ID))
+		return nil, err
+	}
+
+	if update.From != update.To {
+		comment := "flat edited by owner"
+		if err := recordStatusChange(ctx, tx, &flat, update.From, &update.EditorID, &comment); err != nil {
+			r.logger.Error("Failed to record status change", slog.String("op", op), "error", err, slog.Int("flatID", flat.ID))
+			return nil, err
+		}
+	}
+
+	if err := outboxRepo.InsertEvent(ctx, tx, models.EventFlatUpdated, flat.ID, newFlatEvent(&flat)); err != nil {
+		r.logger.Error("Failed to write outbox event", slog.String("op", op), "error", err, slog.Int("flatID", flat.ID))
+		return nil, err
+	}
+
 	return &flat, nil
 }
 
-func (r *Repository) GetFlatByID(ctx context.Context, flatID int) (*models.Flat, error) {
-	const op = "repository.flat.GetFlatByID"
+// GetFlatEdits - OnlyModerator. Owner's edits of the flat, oldest first.
+func (r *Repository) GetFlatEdits(ctx context.Context, flatID int) ([]models.FlatEdit, error) {
+	const op = "repository.flat.GetFlatEdits"
 
 	query := `
-		SELECT id, house_id, flat_number, price, rooms, status, moderator_id
-		FROM flats
-		WHERE id = $1
+		SELECT id, flat_id, editor_id, changes, created_at
+		FROM flat_edits
+		WHERE flat_id = $1
+		ORDER BY created_at, id
 	`
 
+	rows, err := r.db.QueryContext(ctx, query, flatID)
+	if err != nil {
+		r.logger.Error("Failed to get flat edits", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var edits []models.FlatEdit
+	for rows.Next() {
+		var edit models.FlatEdit
+		var changes []byte
+		if err := rows.Scan(&edit.ID, &edit.FlatID, &edit.EditorID, &changes, &edit.CreatedAt); err != nil {
+			r.logger.Error("Failed to scan flat edit", slog.String("op", op), "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := json.Unmarshal(changes, &edit.Changes); err != nil {
+			r.logger.Error("Failed to decode flat edit", slog.String("op", op), "error", err, slog.Int64("editID", edit.ID))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		edits = append(edits, edit)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Rows iteration error", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return edits, nil
+}
+
+// markApproved stamps the first approval of a flat. The flat row must already be locked by tx,
+// so it reports true only to the transaction that approved the flat first.
+func markApproved(ctx context.Context, tx *sql.Tx, flatID int) (bool, error) {
+	res, err := tx.ExecContext(ctx, "UPDATE flats SET approved_at = CURRENT_TIMESTAMP WHERE id = $1 AND approved_at IS NULL", flatID)
+	if err != nil {
+		return false, fmt.Errorf("mark approved: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("mark approved: %w", err)
+	}
+	return n == 1, nil
+}
+
+// statusUpdateMiss tells apart a missing flat from a lost race when a conditional update matched no rows.
+// Archived flats are reported as missing, like everywhere else.
+func (r *Repository) statusUpdateMiss(ctx context.Context, tx *sql.Tx, flatID int) error {
+	const op = "repository.flat.UpdateFlatStatus"
+
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM flats WHERE id = $1 AND archived_at IS NULL)"
+	if err := tx.QueryRowContext(ctx, query, flatID).Scan(&exists); err != nil {
+		r.logger.Error("Failed to check flat existence", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !exists {
+		r.logger.Warn("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return fmt.Errorf("%s: %w", op, repositories.ErrFlatNotFound)
+	}
+
+	r.logger.Warn("Flat status was changed concurrently", slog.String("op", op), slog.Int("flatID", flatID))
+	return fmt.Errorf("%s: %w", op, repositories.ErrFlatStatusChanged)
+}
+
+// GetFlatByID returns nil if there is no such flat. Archived flats are returned only if includeArchived is set.
+func (r *Repository) GetFlatByID(ctx context.Context, flatID int, includeArchived bool) (*models.Flat, error) {
+	const op = "repository.flat.GetFlatByID"
+
+	query := "SELECT " + FlatColumns + " FROM flats WHERE id = $1"
+	if !includeArchived {
+		query += " AND archived_at IS NULL"
+	}
+
 	var flat models.Flat
-	err := r.db.QueryRowContext(ctx, query, flatID).Scan(
-		&flat.ID,
-		&flat.HouseID,
-		&flat.FlatNumber,
-		&flat.Price,
-		&flat.Rooms,
-		&flat.Status,
-		&flat.ModeratorID,
-	)
+	err := ScanFlat(r.db.QueryRowContext(ctx, query, flatID), &flat)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -137,3 +501,320 @@ func (r *Repository) GetFlatByID(ctx context.Context, flatID int) (*models.Flat,
 
 	return &flat, nil
 }
+
+// SetFlatArchived archives or restores the flat. Flats on moderation are not archived:
+// repositories.ErrFlatStatusChanged is returned for them.
+func (r *Repository) SetFlatArchived(ctx context.Context, flatID int, archived bool) (*models.Flat, error) {
+	const op = "repository.flat.SetFlatArchived"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE flats
+		SET archived_at = CASE WHEN $2 THEN COALESCE(archived_at, CURRENT_TIMESTAMP) END
+		WHERE id = $1 AND status <> 'on moderation'
+		RETURNING ` + FlatColumns
+
+	var flat models.Flat
+	if err := ScanFlat(tx.QueryRowContext(ctx, query, flatID, archived), &flat); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.statusUpdateMiss(ctx, tx, flatID)
+		}
+		r.logger.Error("Failed to archive flat", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	eventType := models.EventFlatRestored
+	if archived {
+		eventType = models.EventFlatArchived
+	}
+	if err := outboxRepo.InsertEvent(ctx, tx, eventType, flat.ID, newFlatEvent(&flat)); err != nil {
+		r.logger.Error("Failed to write outbox event", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &flat, nil
+}
+
+// GetStatusHistory - OnlyModerator. Records are ordered from the oldest to the newest.
+func (r *Repository) GetStatusHistory(ctx context.Context, flatID int) ([]models.StatusChange, error) {
+	const op = "repository.flat.GetStatusHistory"
+
+	query := `
+		SELECT id, flat_id, previous_status, new_status, COALESCE(actor_id::text, ''), comment, changed_at
+		FROM flat_status_history
+		WHERE flat_id = $1
+		ORDER BY changed_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, flatID)
+	if err != nil {
+		r.logger.Error("Failed to get status history", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var history []models.StatusChange
+	for rows.Next() {
+		var change models.StatusChange
+		if err := rows.Scan(&change.ID, &change.FlatID, &change.PreviousStatus, &change.NewStatus, &change.ActorID, &change.Comment, &change.ChangedAt); err != nil {
+			r.logger.Error("Failed to scan status change", slog.String("op", op), "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		history = append(history, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Rows iteration error", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
+
+// GetPriceHistory - AuthOnly. Every price of the flat from the oldest to the current one.
+func (r *Repository) GetPriceHistory(ctx context.Context, flatID int) ([]models.PriceChange, error) {
+	const op = "repository.flat.GetPriceHistory"
+
+	query := `
+		SELECT price, changed_by::text, changed_at
+		FROM flat_price_history
+		WHERE flat_id = $1
+		ORDER BY changed_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, flatID)
+	if err != nil {
+		r.logger.Error("Failed to get price history", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var history []models.PriceChange
+	for rows.Next() {
+		var change models.PriceChange
+		if err := rows.Scan(&change.Price, &change.ChangedBy, &change.ChangedAt); err != nil {
+			r.logger.Error("Failed to scan price change", slog.String("op", op), "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		history = append(history, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Rows iteration error", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
+
+func recordPrice(ctx context.Context, tx *sql.Tx, flatID, price int, changedBy *string) error {
+	query := "INSERT INTO flat_price_history (flat_id, price, changed_by) VALUES ($1, $2, $3)"
+	if _, err := tx.ExecContext(ctx, query, flatID, price, changedBy); err != nil {
+		return fmt.Errorf("write price history: %w", err)
+	}
+	return nil
+}
+
+// syncPriceChange copies the last price change from flat_price_history to the flat,
+// so that the flat and its price history never disagree
+func syncPriceChange(ctx context.Context, tx *sql.Tx, flat *models.Flat) error {
+	query := `
+		UPDATE flats f
+		SET previous_price = h.previous_price, price_changed_at = h.changed_at
+		FROM (
+			SELECT changed_at, LAG(price) OVER (ORDER BY changed_at, id) AS previous_price
+			FROM flat_price_history
+			WHERE flat_id = $1
+			ORDER BY changed_at DESC, id DESC
+			LIMIT 1
+		) h
+		WHERE f.id = $1
+		RETURNING f.previous_price, f.price_changed_at
+	`
+	if err := tx.QueryRowContext(ctx, query, flat.ID).Scan(&flat.PreviousPrice, &flat.PriceChangedAt); err != nil {
+		return fmt.Errorf("sync price change: %w", err)
+	}
+	return nil
+}
+
+// RenewModeration - OnlyModerator. Extends the lease of a flat held by the moderator.
+func (r *Repository) RenewModeration(ctx context.Context, flatID int, moderatorID string, ttl time.Duration) (*models.Flat, error) {
+	const op = "repository.flat.RenewModeration"
+
+	query := `
+		UPDATE flats
+		SET moderation_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $1)
+		WHERE id = $2 AND status = 'on moderation' AND moderator_id = $3
+		RETURNING ` + FlatColumns
+
+	var flat models.Flat
+	err := ScanFlat(r.db.QueryRowContext(ctx, query, ttl.Seconds(), flatID, moderatorID), &flat)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.Warn("Flat is not held by the moderator", slog.String("op", op), slog.Int("flatID", flatID))
+			return nil, fmt.Errorf("%s: %w", op, repositories.ErrFlatStatusChanged)
+		}
+		r.logger.Error("Failed to renew moderation", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &flat, nil
+}
+
+// ReleaseExpiredModerations returns flats with an expired moderation lease back to "created".
+// Every release is recorded to history (without an actor) and to the outbox in one transaction.
+func (r *Repository) ReleaseExpiredModerations(ctx context.Context) ([]models.Flat, error) {
+	const op = "repository.flat.ReleaseExpiredModerations"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE flats
+		SET status = 'created', moderator_id = NULL, moderation_expires_at = NULL
+		WHERE status = 'on moderation' AND moderation_expires_at < CURRENT_TIMESTAMP
+		RETURNING ` + FlatColumns
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to release expired moderations", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var flats []models.Flat
+	for rows.Next() {
+		var flat models.Flat
+		if err := ScanFlat(rows, &flat); err != nil {
+			rows.Close()
+			r.logger.Error("Failed to scan flat", slog.String("op", op), "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		flats = append(flats, flat)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Rows iteration error", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comment := "moderation lease expired"
+	for i := range flats {
+		if err := recordStatusChange(ctx, tx, &flats[i], models.StatusOnModeration, nil, &comment); err != nil {
+			r.logger.Error("Failed to record status change", slog.String("op", op), "error", err, slog.Int("flatID", flats[i].ID))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flats, nil
+}
+
+// GetModerationQueue - OnlyModerator. Flats waiting for moderation, oldest first.
+func (r *Repository) GetModerationQueue(ctx context.Context, houseID *int, limit, offset int) ([]models.Flat, error) {
+	const op = "repository.flat.GetModerationQueue"
+
+	query := `
+		SELECT ` + FlatColumns + `
+		FROM flats
+		WHERE status = 'created' AND archived_at IS NULL AND ($1::int IS NULL OR house_id = $1)
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, houseID, limit, offset)
+	if err != nil {
+		r.logger.Error("Failed to get moderation queue", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var flats []models.Flat
+	for rows.Next() {
+		var flat models.Flat
+		if err := ScanFlat(rows, &flat); err != nil {
+			r.logger.Error("Failed to scan flat", slog.String("op", op), "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		flats = append(flats, flat)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Rows iteration error", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flats, nil
+}
+
+// ClaimNextFlat - OnlyModerator. Takes the oldest flat waiting for moderation on moderation for the moderator.
+// Rows locked by concurrent claims are skipped, so parallel moderators get different flats.
+// Returns nil if the queue is empty.
+func (r *Repository) ClaimNextFlat(ctx context.Context, moderatorID string, houseID *int, ttl time.Duration) (*models.Flat, error) {
+	const op = "repository.flat.ClaimNextFlat"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE flats
+		SET status = 'on moderation',
+		    moderator_id = $1,
+		    moderation_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE id = (
+			SELECT id
+			FROM flats
+			WHERE status = 'created' AND archived_at IS NULL AND ($3::int IS NULL OR house_id = $3)
+			ORDER BY created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + FlatColumns
+
+	var flat models.Flat
+	err = ScanFlat(tx.QueryRowContext(ctx, query, moderatorID, ttl.Seconds(), houseID), &flat)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.Debug("Moderation queue is empty", slog.String("op", op))
+			return nil, nil
+		}
+		r.logger.Error("Failed to claim next flat", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := recordStatusChange(ctx, tx, &flat, models.StatusCreated, &moderatorID, nil); err != nil {
+		r.logger.Error("Failed to record status change", slog.String("op", op), "error", err, slog.Int("flatID", flat.ID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &flat, nil
+}
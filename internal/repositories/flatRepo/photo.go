@@ -0,0 +1,197 @@
+package flatRepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+)
+
+// photosQuery aggregates the photos of the flat selected in the outer query, in display order.
+// The keys match the fields of models.FlatPhoto.
+const photosQuery = `
+	SELECT COALESCE(json_agg(json_build_object(
+		'ID', p.id, 'FlatID', p.flat_id, 'Kind', p.kind, 'Key', p.storage_key, 'ThumbnailKey', p.thumbnail_key,
+		'URL', p.url, 'ThumbnailURL', p.thumbnail_url, 'ContentType', p.content_type, 'Size', p.size_bytes,
+		'Position', p.position, 'CreatedAt', p.created_at
+	) ORDER BY p.position), '[]')
+	FROM flat_photos p
+	WHERE p.flat_id = flats.id`
+
+// photoList scans the JSON array of photosQuery
+type photoList struct {
+	photos *[]models.FlatPhoto
+}
+
+func (l photoList) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*l.photos = nil
+		return nil
+	default:
+		return fmt.Errorf("unexpected photos column type %T", src)
+	}
+
+	var photos []models.FlatPhoto
+	if err := json.Unmarshal(data, &photos); err != nil {
+		return fmt.Errorf("decode photos: %w", err)
+	}
+	if len(photos) == 0 {
+		photos = nil
+	}
+	*l.photos = photos
+	return nil
+}
+
+// AddFlatPhoto attaches the photo to the flat after the existing ones and applies the update
+// that comes with it, e.g. sends an approved flat back to moderation.
+// Returns ErrFlatStatusChanged if the flat is no longer in update.From
+// and ErrTooManyPhotos if the flat already has maxPhotos photos.
+func (r *Repository) AddFlatPhoto(ctx context.Context, photo *models.FlatPhoto, update models.FlatUpdate, maxPhotos int) (*models.Flat, error) {
+	const op = "repository.flat.AddFlatPhoto"
+
+	changes, err := json.Marshal(update.Changes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// The conditional update locks the flat row, so concurrent uploads get distinct positions
+	// and see each other's photos when counting them
+	if _, err := r.applyFlatUpdate(ctx, tx, update, changes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM flat_photos WHERE flat_id = $1", photo.FlatID).Scan(&count); err != nil {
+		r.logger.Error("Failed to count photos", slog.String("op", op), "error", err, slog.Int("flatID", photo.FlatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if count >= maxPhotos {
+		r.logger.Warn("Flat has too many photos", slog.String("op", op), slog.Int("flatID", photo.FlatID))
+		return nil, fmt.Errorf("%s: %w", op, repositories.ErrTooManyPhotos)
+	}
+
+	query := `
+		INSERT INTO flat_photos (flat_id, kind, storage_key, thumbnail_key, url, thumbnail_url, content_type, size_bytes, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8,
+		        (SELECT COALESCE(MAX(position), 0) + 1 FROM flat_photos WHERE flat_id = $1))
+		RETURNING id, position, created_at
+	`
+
+	err = tx.QueryRowContext(ctx, query, photo.FlatID, photo.Kind, photo.Key, photo.ThumbnailKey, photo.URL,
+		photo.ThumbnailURL, photo.ContentType, photo.Size).
+		Scan(&photo.ID, &photo.Position, &photo.CreatedAt)
+	if err != nil {
+		r.logger.Error("Failed to insert photo", slog.String("op", op), "error", err, slog.Int("flatID", photo.FlatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	flat, err := getFlat(ctx, tx, photo.FlatID)
+	if err != nil {
+		r.logger.Error("Failed to reload flat", slog.String("op", op), "error", err, slog.Int("flatID", photo.FlatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flat, nil
+}
+
+// DeleteFlatPhoto removes the photo from the flat and returns it, so that the caller can delete the files.
+// Returns ErrPhotoNotFound if the flat has no such photo.
+func (r *Repository) DeleteFlatPhoto(ctx context.Context, flatID, photoID int) (*models.FlatPhoto, error) {
+	const op = "repository.flat.DeleteFlatPhoto"
+
+	query := `
+		DELETE FROM flat_photos
+		WHERE id = $1 AND flat_id = $2
+		RETURNING id, flat_id, kind, storage_key, thumbnail_key, url, thumbnail_url, content_type, size_bytes, position, created_at
+	`
+
+	var photo models.FlatPhoto
+	err := r.db.QueryRowContext(ctx, query, photoID, flatID).Scan(&photo.ID, &photo.FlatID, &photo.Kind, &photo.Key,
+		&photo.ThumbnailKey, &photo.URL, &photo.ThumbnailURL, &photo.ContentType, &photo.Size, &photo.Position, &photo.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.Warn("Photo not found", slog.String("op", op), slog.Int("flatID", flatID), slog.Int("photoID", photoID))
+			return nil, fmt.Errorf("%s: %w", op, repositories.ErrPhotoNotFound)
+		}
+		r.logger.Error("Failed to delete photo", slog.String("op", op), "error", err, slog.Int("photoID", photoID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &photo, nil
+}
+
+// ReorderFlatPhotos sets the display order of the flat's photos. photoIDs must list every photo of the flat.
+func (r *Repository) ReorderFlatPhotos(ctx context.Context, flatID int, photoIDs []int) (*models.Flat, error) {
+	const op = "repository.flat.ReorderFlatPhotos"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE flat_photos p
+		SET position = o.position
+		FROM unnest($2::int[]) WITH ORDINALITY AS o(id, position)
+		WHERE p.id = o.id AND p.flat_id = $1
+	`
+
+	res, err := tx.ExecContext(ctx, query, flatID, pq.Array(photoIDs))
+	if err != nil {
+		r.logger.Error("Failed to reorder photos", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != int64(len(photoIDs)) {
+		r.logger.Warn("Photos do not belong to the flat", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, repositories.ErrPhotoNotFound)
+	}
+
+	flat, err := getFlat(ctx, tx, flatID)
+	if err != nil {
+		r.logger.Error("Failed to reload flat", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The deferred unique constraint on positions is checked here
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flat, nil
+}
+
+func getFlat(ctx context.Context, tx *sql.Tx, flatID int) (*models.Flat, error) {
+	var flat models.Flat
+	if err := ScanFlat(tx.QueryRowContext(ctx, "SELECT "+FlatColumns+" FROM flats WHERE id = $1", flatID), &flat); err != nil {
+		return nil, err
+	}
+	return &flat, nil
+}
@@ -3,16 +3,28 @@ package houseRepo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"log/slog"
+	"time"
 
 	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"avito/internal/repositories/flatRepo"
+	"avito/internal/repositories/outboxRepo"
 )
 
 type HouseRepo interface {
 	CreateHouse(ctx context.Context, house *models.House) error
 	SubscribeToHouse(ctx context.Context, houseID int, email string) error
-	GetFlatsByHouseID(ctx context.Context, houseID int, role string) ([]models.Flat, error)
+	GetSubscribers(ctx context.Context, houseID int) ([]string, error)
+	GetFlatsByHouseID(ctx context.Context, houseID int, userID string, role models.Role, includeArchived bool, page models.FlatPage) ([]models.Flat, error)
+	SetHouseArchived(ctx context.Context, houseID int, archived bool) (*models.House, error)
+	GetHouseByID(ctx context.Context, houseID int, includeArchived bool) (*models.House, error)
+	FindHouseByAddress(ctx context.Context, normalizedAddress string) (*models.House, error)
+	ListHouses(ctx context.Context, filter models.HouseFilter) ([]models.House, error)
+	UpdateHouse(ctx context.Context, house *models.House) error
 }
 
 type Repository struct {
@@ -24,40 +36,154 @@ func NewRepository(db *sql.DB, logger *slog.Logger) HouseRepo {
 	return &Repository{db: db, logger: logger}
 }
 
+// houseEvent is the outbox payload of house events
+type houseEvent struct {
+	ID         int        `json:"id"`
+	Address    string     `json:"address"`
+	YearBuilt  int        `json:"year"`
+	Builder    *string    `json:"developer,omitempty"`
+	Latitude   *float64   `json:"lat,omitempty"`
+	Longitude  *float64   `json:"lon,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+func newHouseEvent(house *models.House) houseEvent {
+	return houseEvent{
+		ID:         house.ID,
+		Address:    house.Address,
+		YearBuilt:  house.YearBuilt,
+		Builder:    house.Builder,
+		Latitude:   house.Latitude,
+		Longitude:  house.Longitude,
+		ArchivedAt: house.ArchivedAt,
+	}
+}
+
+// HouseColumns is the column list matching ScanHouse
+const HouseColumns = "id, address, COALESCE(address_normalized, ''), year_built, builder, latitude, longitude, " +
+	"created_at, updated_at, last_flat_added, archived_at"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// ScanHouse reads a row selected with HouseColumns
+func ScanHouse(row scanner, house *models.House) error {
+	return row.Scan(
+		&house.ID,
+		&house.Address,
+		&house.NormalizedAddress,
+		&house.YearBuilt,
+		&house.Builder,
+		&house.Latitude,
+		&house.Longitude,
+		&house.CreatedAt,
+		&house.UpdatedAt,
+		&house.LastFlatAdded,
+		&house.ArchivedAt,
+	)
+}
+
 func (r *Repository) CreateHouse(ctx context.Context, house *models.House) error {
 	const op = "repositories.house.CreateHouse"
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "op", op, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO houses (address, year_built, builder)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at, last_flat_added
+		INSERT INTO houses (address, address_normalized, year_built, builder, latitude, longitude)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at, last_flat_added
 	`
 
-	err := r.db.QueryRowContext(ctx, query, house.Address, house.YearBuilt, house.Builder).Scan(&house.ID, &house.CreatedAt, &house.LastFlatAdded)
+	err = tx.QueryRowContext(ctx, query, house.Address, house.NormalizedAddress, house.YearBuilt, house.Builder,
+		house.Latitude, house.Longitude).
+		Scan(&house.ID, &house.CreatedAt, &house.UpdatedAt, &house.LastFlatAdded)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == repositories.UniqueViolation {
+			r.logger.Warn("House already exists", "op", op, "address", house.NormalizedAddress)
+			return fmt.Errorf("%s: %w", op, repositories.ErrHouseExists)
+		}
 		r.logger.Error("Failed to create house", "op", op, "error", err)
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := outboxRepo.InsertEvent(ctx, tx, models.EventHouseCreated, house.ID, newHouseEvent(house)); err != nil {
+		r.logger.Error("Failed to write outbox event", "op", op, "error", err, "houseID", house.ID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "op", op, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	r.logger.Info("House created", "op", op, "houseID", house.ID, "address", house.Address, "year_built", house.YearBuilt, "created_at", house.CreatedAt)
 	return nil
 }
 
-func (r *Repository) GetFlatsByHouseID(ctx context.Context, houseID int, role string) ([]models.Flat, error) {
+// GetFlatsByHouseID returns no flats of an archived house and no archived flats unless includeArchived is set.
+// Flats come in keyset pages: the cursor is compared after filtering by house, so the (house_id, status)
+// index stays the access path and the page is sorted in memory.
+func (r *Repository) GetFlatsByHouseID(ctx context.Context, houseID int, userID string, role models.Role, includeArchived bool, page models.FlatPage) ([]models.Flat, error) {
 	const op = "repositories.house.GetFlatsByHouseID"
 
 	var query string
 	var args []interface{}
 
-	query = `
-        SELECT id, house_id, flat_number, price, rooms, status
-        FROM flats
-        WHERE house_id = $1
-    `
+	query = "SELECT " + flatRepo.FlatColumns + " FROM flats WHERE house_id = $1"
 	args = append(args, houseID)
 
-	if role != "moderator" {
-		query += " AND status = 'approved'"
+	if !includeArchived {
+		query += " AND archived_at IS NULL AND NOT EXISTS (SELECT 1 FROM houses WHERE id = $1 AND archived_at IS NOT NULL)"
+	}
+
+	// Non-moderators see approved flats and their own flats in any status
+	if !role.CanModerate() {
+		query += " AND (status = 'approved' OR created_by = $2)"
+		args = append(args, userID)
+	}
+
+	// Sort column comes from a fixed set, never from the request
+	var sortColumn string
+	switch page.SortBy {
+	case models.FlatSortPrice:
+		sortColumn = "price"
+	case models.FlatSortRooms:
+		sortColumn = "rooms"
+	default:
+		sortColumn = "id"
+	}
+
+	direction, cmp := "ASC", ">"
+	if page.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if page.After != nil {
+		if sortColumn == "id" {
+			args = append(args, page.After.ID)
+			query += fmt.Sprintf(" AND id %s $%d", cmp, len(args))
+		} else {
+			args = append(args, page.After.Value, page.After.ID)
+			query += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", sortColumn, cmp, len(args)-1, len(args))
+		}
+	}
+
+	if sortColumn == "id" {
+		query += " ORDER BY id " + direction
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, id %s", sortColumn, direction, direction)
+	}
+
+	if page.Limit > 0 {
+		args = append(args, page.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -71,7 +197,7 @@ func (r *Repository) GetFlatsByHouseID(ctx context.Context, houseID int, role st
 
 	for rows.Next() {
 		var flat models.Flat
-		if err := rows.Scan(&flat.ID, &flat.HouseID, &flat.FlatNumber, &flat.Price, &flat.Rooms, &flat.Status); err != nil {
+		if err := flatRepo.ScanFlat(rows, &flat); err != nil {
 			r.logger.Error("Failed to scan flat", "op", op, "error", err)
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
@@ -87,8 +213,240 @@ func (r *Repository) GetFlatsByHouseID(ctx context.Context, houseID int, role st
 	return flats, nil
 }
 
-// TODO: /house/{id}/subscribe
+// SubscribeToHouse - AuthOnly. Repeated subscription with the same email is a no-op.
 func (r *Repository) SubscribeToHouse(ctx context.Context, houseID int, email string) error {
 	const op = "repository.house.SubscribeToHouse"
+
+	query := `
+		INSERT INTO subscriptions (house_id, email)
+		VALUES ($1, $2)
+		ON CONFLICT (house_id, email) DO NOTHING
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, houseID, email); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == repositories.ForeignKeyViolation {
+			r.logger.Warn("House not found", "op", op, "houseID", houseID)
+			return fmt.Errorf("%s: %w", op, repositories.ErrHouseNotFound)
+		}
+		r.logger.Error("Failed to subscribe to house", "op", op, "error", err, "houseID", houseID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.logger.Info("Subscription created", "op", op, "houseID", houseID, "email", email)
+	return nil
+}
+
+func (r *Repository) GetSubscribers(ctx context.Context, houseID int) ([]string, error) {
+	const op = "repository.house.GetSubscribers"
+
+	query := "SELECT email FROM subscriptions WHERE house_id = $1"
+
+	rows, err := r.db.QueryContext(ctx, query, houseID)
+	if err != nil {
+		r.logger.Error("Failed to get subscribers", "op", op, "error", err, "houseID", houseID)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var emails []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			r.logger.Error("Failed to scan subscriber", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		emails = append(emails, email)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error during rows iteration", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return emails, nil
+}
+
+// SetHouseArchived archives or restores the house
+func (r *Repository) SetHouseArchived(ctx context.Context, houseID int, archived bool) (*models.House, error) {
+	const op = "repositories.house.SetHouseArchived"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE houses
+		SET archived_at = CASE WHEN $2 THEN COALESCE(archived_at, CURRENT_TIMESTAMP) END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + HouseColumns
+
+	var house models.House
+	if err := ScanHouse(tx.QueryRowContext(ctx, query, houseID, archived), &house); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.Warn("House not found", "op", op, "houseID", houseID)
+			return nil, fmt.Errorf("%s: %w", op, repositories.ErrHouseNotFound)
+		}
+		r.logger.Error("Failed to archive house", "op", op, "error", err, "houseID", houseID)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	eventType := models.EventHouseRestored
+	if archived {
+		eventType = models.EventHouseArchived
+	}
+	if err := outboxRepo.InsertEvent(ctx, tx, eventType, house.ID, newHouseEvent(&house)); err != nil {
+		r.logger.Error("Failed to write outbox event", "op", op, "error", err, "houseID", house.ID)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &house, nil
+}
+
+// GetHouseByID returns nil if there is no such house. Archived houses are returned only if includeArchived is set.
+func (r *Repository) GetHouseByID(ctx context.Context, houseID int, includeArchived bool) (*models.House, error) {
+	const op = "repositories.house.GetHouseByID"
+
+	query := "SELECT " + HouseColumns + " FROM houses WHERE id = $1"
+	if !includeArchived {
+		query += " AND archived_at IS NULL"
+	}
+
+	var house models.House
+	if err := ScanHouse(r.db.QueryRowContext(ctx, query, houseID), &house); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get house", "op", op, "error", err, "houseID", houseID)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &house, nil
+}
+
+// FindHouseByAddress returns the house, archived or not, with the normalized address or nil if there is none
+func (r *Repository) FindHouseByAddress(ctx context.Context, normalizedAddress string) (*models.House, error) {
+	const op = "repositories.house.FindHouseByAddress"
+
+	query := "SELECT " + HouseColumns + " FROM houses WHERE address_normalized = $1"
+
+	var house models.House
+	if err := ScanHouse(r.db.QueryRowContext(ctx, query, normalizedAddress), &house); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to find house", "op", op, "error", err, "address", normalizedAddress)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &house, nil
+}
+
+// ListHouses returns houses matching the filter ordered by id
+func (r *Repository) ListHouses(ctx context.Context, filter models.HouseFilter) ([]models.House, error) {
+	const op = "repositories.house.ListHouses"
+
+	query := "SELECT " + HouseColumns + " FROM houses WHERE TRUE"
+	var args []interface{}
+
+	if !filter.IncludeArchived {
+		query += " AND archived_at IS NULL"
+	}
+	if filter.YearFrom != nil {
+		args = append(args, *filter.YearFrom)
+		query += fmt.Sprintf(" AND year_built >= $%d", len(args))
+	}
+	if filter.YearTo != nil {
+		args = append(args, *filter.YearTo)
+		query += fmt.Sprintf(" AND year_built <= $%d", len(args))
+	}
+	if filter.Developer != nil {
+		args = append(args, *filter.Developer)
+		query += fmt.Sprintf(" AND lower(builder) = lower($%d)", len(args))
+	}
+	if filter.Address != nil {
+		args = append(args, "%"+repositories.EscapeLike(*filter.Address)+"%")
+		query += fmt.Sprintf(" AND address ILIKE $%d", len(args))
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to list houses", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var houses []models.House
+	for rows.Next() {
+		var house models.House
+		if err := ScanHouse(rows, &house); err != nil {
+			r.logger.Error("Failed to scan house", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		houses = append(houses, house)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error during rows iteration", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return houses, nil
+}
+
+// UpdateHouse saves address, year and developer of the house and refreshes its timestamps
+func (r *Repository) UpdateHouse(ctx context.Context, house *models.House) error {
+	const op = "repositories.house.UpdateHouse"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", "op", op, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE houses
+		SET address = $1, address_normalized = NULLIF($2, ''), year_built = $3, builder = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND archived_at IS NULL
+		RETURNING ` + HouseColumns
+
+	row := tx.QueryRowContext(ctx, query, house.Address, house.NormalizedAddress, house.YearBuilt, house.Builder, house.ID)
+	if err := ScanHouse(row, house); err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			r.logger.Warn("House not found", "op", op, "houseID", house.ID)
+			return fmt.Errorf("%s: %w", op, repositories.ErrHouseNotFound)
+		case errors.As(err, &pqErr) && pqErr.Code == repositories.UniqueViolation:
+			r.logger.Warn("House already exists", "op", op, "address", house.NormalizedAddress)
+			return fmt.Errorf("%s: %w", op, repositories.ErrHouseExists)
+		}
+		r.logger.Error("Failed to update house", "op", op, "error", err, "houseID", house.ID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := outboxRepo.InsertEvent(ctx, tx, models.EventHouseUpdated, house.ID, newHouseEvent(house)); err != nil {
+		r.logger.Error("Failed to write outbox event", "op", op, "error", err, "houseID", house.ID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit transaction", "op", op, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
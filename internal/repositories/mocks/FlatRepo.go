@@ -8,6 +8,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	models "avito/internal/domain/models"
+
+	time "time"
 )
 
 // FlatRepo is an autogenerated mock type for the FlatRepo type
@@ -15,6 +17,66 @@ type FlatRepo struct {
 	mock.Mock
 }
 
+// AddFlatPhoto provides a mock function with given fields: ctx, photo, update, maxPhotos
+func (_m *FlatRepo) AddFlatPhoto(ctx context.Context, photo *models.FlatPhoto, update models.FlatUpdate, maxPhotos int) (*models.Flat, error) {
+	ret := _m.Called(ctx, photo, update, maxPhotos)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddFlatPhoto")
+	}
+
+	var r0 *models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.FlatPhoto, models.FlatUpdate, int) (*models.Flat, error)); ok {
+		return rf(ctx, photo, update, maxPhotos)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.FlatPhoto, models.FlatUpdate, int) *models.Flat); ok {
+		r0 = rf(ctx, photo, update, maxPhotos)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.FlatPhoto, models.FlatUpdate, int) error); ok {
+		r1 = rf(ctx, photo, update, maxPhotos)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimNextFlat provides a mock function with given fields: ctx, moderatorID, houseID, ttl
+func (_m *FlatRepo) ClaimNextFlat(ctx context.Context, moderatorID string, houseID *int, ttl time.Duration) (*models.Flat, error) {
+	ret := _m.Called(ctx, moderatorID, houseID, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimNextFlat")
+	}
+
+	var r0 *models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *int, time.Duration) (*models.Flat, error)); ok {
+		return rf(ctx, moderatorID, houseID, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *int, time.Duration) *models.Flat); ok {
+		r0 = rf(ctx, moderatorID, houseID, ttl)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *int, time.Duration) error); ok {
+		r1 = rf(ctx, moderatorID, houseID, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateFlat provides a mock function with given fields: ctx, flat
 func (_m *FlatRepo) CreateFlat(ctx context.Context, flat *models.Flat) (int, error) {
 	ret := _m.Called(ctx, flat)
@@ -43,9 +105,39 @@ func (_m *FlatRepo) CreateFlat(ctx context.Context, flat *models.Flat) (int, err
 	return r0, r1
 }
 
-// GetFlatByID provides a mock function with given fields: ctx, flatID
-func (_m *FlatRepo) GetFlatByID(ctx context.Context, flatID int) (*models.Flat, error) {
-	ret := _m.Called(ctx, flatID)
+// DeleteFlatPhoto provides a mock function with given fields: ctx, flatID, photoID
+func (_m *FlatRepo) DeleteFlatPhoto(ctx context.Context, flatID int, photoID int) (*models.FlatPhoto, error) {
+	ret := _m.Called(ctx, flatID, photoID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFlatPhoto")
+	}
+
+	var r0 *models.FlatPhoto
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*models.FlatPhoto, error)); ok {
+		return rf(ctx, flatID, photoID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) *models.FlatPhoto); ok {
+		r0 = rf(ctx, flatID, photoID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.FlatPhoto)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, flatID, photoID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFlatByID provides a mock function with given fields: ctx, flatID, includeArchived
+func (_m *FlatRepo) GetFlatByID(ctx context.Context, flatID int, includeArchived bool) (*models.Flat, error) {
+	ret := _m.Called(ctx, flatID, includeArchived)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetFlatByID")
@@ -53,14 +145,44 @@ func (_m *FlatRepo) GetFlatByID(ctx context.Context, flatID int) (*models.Flat,
 
 	var r0 *models.Flat
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.Flat, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*models.Flat, error)); ok {
+		return rf(ctx, flatID, includeArchived)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *models.Flat); ok {
+		r0 = rf(ctx, flatID, includeArchived)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, flatID, includeArchived)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFlatEdits provides a mock function with given fields: ctx, flatID
+func (_m *FlatRepo) GetFlatEdits(ctx context.Context, flatID int) ([]models.FlatEdit, error) {
+	ret := _m.Called(ctx, flatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFlatEdits")
+	}
+
+	var r0 []models.FlatEdit
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.FlatEdit, error)); ok {
 		return rf(ctx, flatID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int) *models.Flat); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.FlatEdit); ok {
 		r0 = rf(ctx, flatID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.Flat)
+			r0 = ret.Get(0).([]models.FlatEdit)
 		}
 	}
 
@@ -73,9 +195,9 @@ func (_m *FlatRepo) GetFlatByID(ctx context.Context, flatID int) (*models.Flat,
 	return r0, r1
 }
 
-// GetFlatsByHouseID provides a mock function with given fields: ctx, houseID
-func (_m *FlatRepo) GetFlatsByHouseID(ctx context.Context, houseID int) ([]*models.Flat, error) {
-	ret := _m.Called(ctx, houseID)
+// GetFlatsByHouseID provides a mock function with given fields: ctx, houseID, includeArchived
+func (_m *FlatRepo) GetFlatsByHouseID(ctx context.Context, houseID int, includeArchived bool) ([]*models.Flat, error) {
+	ret := _m.Called(ctx, houseID, includeArchived)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetFlatsByHouseID")
@@ -83,19 +205,109 @@ func (_m *FlatRepo) GetFlatsByHouseID(ctx context.Context, houseID int) ([]*mode
 
 	var r0 []*models.Flat
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*models.Flat, error)); ok {
-		return rf(ctx, houseID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) ([]*models.Flat, error)); ok {
+		return rf(ctx, houseID, includeArchived)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int) []*models.Flat); ok {
-		r0 = rf(ctx, houseID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) []*models.Flat); ok {
+		r0 = rf(ctx, houseID, includeArchived)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Flat)
 		}
 	}
 
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, houseID, includeArchived)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFlatsByOwner provides a mock function with given fields: ctx, userID, includeArchived
+func (_m *FlatRepo) GetFlatsByOwner(ctx context.Context, userID string, includeArchived bool) ([]models.Flat, error) {
+	ret := _m.Called(ctx, userID, includeArchived)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFlatsByOwner")
+	}
+
+	var r0 []models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) ([]models.Flat, error)); ok {
+		return rf(ctx, userID, includeArchived)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) []models.Flat); ok {
+		r0 = rf(ctx, userID, includeArchived)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, userID, includeArchived)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetModerationQueue provides a mock function with given fields: ctx, houseID, limit, offset
+func (_m *FlatRepo) GetModerationQueue(ctx context.Context, houseID *int, limit int, offset int) ([]models.Flat, error) {
+	ret := _m.Called(ctx, houseID, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetModerationQueue")
+	}
+
+	var r0 []models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *int, int, int) ([]models.Flat, error)); ok {
+		return rf(ctx, houseID, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *int, int, int) []models.Flat); ok {
+		r0 = rf(ctx, houseID, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *int, int, int) error); ok {
+		r1 = rf(ctx, houseID, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPriceHistory provides a mock function with given fields: ctx, flatID
+func (_m *FlatRepo) GetPriceHistory(ctx context.Context, flatID int) ([]models.PriceChange, error) {
+	ret := _m.Called(ctx, flatID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPriceHistory")
+	}
+
+	var r0 []models.PriceChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.PriceChange, error)); ok {
+		return rf(ctx, flatID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.PriceChange); ok {
+		r0 = rf(ctx, flatID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PriceChange)
+		}
+	}
+
 	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
-		r1 = rf(ctx, houseID)
+		r1 = rf(ctx, flatID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -103,29 +315,89 @@ func (_m *FlatRepo) GetFlatsByHouseID(ctx context.Context, houseID int) ([]*mode
 	return r0, r1
 }
 
-// UpdateFlatStatus provides a mock function with given fields: ctx, flatID, status, moderatorID
-func (_m *FlatRepo) UpdateFlatStatus(ctx context.Context, flatID int, status string, moderatorID *string) (*models.Flat, error) {
-	ret := _m.Called(ctx, flatID, status, moderatorID)
+// GetStatusHistory provides a mock function with given fields: ctx, flatID
+func (_m *FlatRepo) GetStatusHistory(ctx context.Context, flatID int) ([]models.StatusChange, error) {
+	ret := _m.Called(ctx, flatID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateFlatStatus")
+		panic("no return value specified for GetStatusHistory")
+	}
+
+	var r0 []models.StatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.StatusChange, error)); ok {
+		return rf(ctx, flatID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.StatusChange); ok {
+		r0 = rf(ctx, flatID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, flatID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseExpiredModerations provides a mock function with given fields: ctx
+func (_m *FlatRepo) ReleaseExpiredModerations(ctx context.Context) ([]models.Flat, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseExpiredModerations")
+	}
+
+	var r0 []models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Flat, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Flat); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RenewModeration provides a mock function with given fields: ctx, flatID, moderatorID, ttl
+func (_m *FlatRepo) RenewModeration(ctx context.Context, flatID int, moderatorID string, ttl time.Duration) (*models.Flat, error) {
+	ret := _m.Called(ctx, flatID, moderatorID, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenewModeration")
 	}
 
 	var r0 *models.Flat
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, string, *string) (*models.Flat, error)); ok {
-		return rf(ctx, flatID, status, moderatorID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, time.Duration) (*models.Flat, error)); ok {
+		return rf(ctx, flatID, moderatorID, ttl)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int, string, *string) *models.Flat); ok {
-		r0 = rf(ctx, flatID, status, moderatorID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, time.Duration) *models.Flat); ok {
+		r0 = rf(ctx, flatID, moderatorID, ttl)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Flat)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int, string, *string) error); ok {
-		r1 = rf(ctx, flatID, status, moderatorID)
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, time.Duration) error); ok {
+		r1 = rf(ctx, flatID, moderatorID, ttl)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -133,6 +405,133 @@ func (_m *FlatRepo) UpdateFlatStatus(ctx context.Context, flatID int, status str
 	return r0, r1
 }
 
+// ReorderFlatPhotos provides a mock function with given fields: ctx, flatID, photoIDs
+func (_m *FlatRepo) ReorderFlatPhotos(ctx context.Context, flatID int, photoIDs []int) (*models.Flat, error) {
+	ret := _m.Called(ctx, flatID, photoIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReorderFlatPhotos")
+	}
+
+	var r0 *models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []int) (*models.Flat, error)); ok {
+		return rf(ctx, flatID, photoIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, []int) *models.Flat); ok {
+		r0 = rf(ctx, flatID, photoIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, []int) error); ok {
+		r1 = rf(ctx, flatID, photoIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetFlatArchived provides a mock function with given fields: ctx, flatID, archived
+func (_m *FlatRepo) SetFlatArchived(ctx context.Context, flatID int, archived bool) (*models.Flat, error) {
+	ret := _m.Called(ctx, flatID, archived)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFlatArchived")
+	}
+
+	var r0 *models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*models.Flat, error)); ok {
+		return rf(ctx, flatID, archived)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *models.Flat); ok {
+		r0 = rf(ctx, flatID, archived)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, flatID, archived)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateFlat provides a mock function with given fields: ctx, update
+func (_m *FlatRepo) UpdateFlat(ctx context.Context, update models.FlatUpdate) (*models.Flat, error) {
+	ret := _m.Called(ctx, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateFlat")
+	}
+
+	var r0 *models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.FlatUpdate) (*models.Flat, error)); ok {
+		return rf(ctx, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.FlatUpdate) *models.Flat); ok {
+		r0 = rf(ctx, update)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.FlatUpdate) error); ok {
+		r1 = rf(ctx, update)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateFlatStatus provides a mock function with given fields: ctx, update
+func (_m *FlatRepo) UpdateFlatStatus(ctx context.Context, update models.StatusUpdate) (*models.Flat, bool, error) {
+	ret := _m.Called(ctx, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateFlatStatus")
+	}
+
+	var r0 *models.Flat
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.StatusUpdate) (*models.Flat, bool, error)); ok {
+		return rf(ctx, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.StatusUpdate) *models.Flat); ok {
+		r0 = rf(ctx, update)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.StatusUpdate) bool); ok {
+		r1 = rf(ctx, update)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, models.StatusUpdate) error); ok {
+		r2 = rf(ctx, update)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // NewFlatRepo creates a new instance of FlatRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewFlatRepo(t interface {
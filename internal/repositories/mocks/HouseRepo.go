@@ -33,9 +33,39 @@ func (_m *HouseRepo) CreateHouse(ctx context.Context, house *models.House) error
 	return r0
 }
 
-// GetFlatsByHouseID provides a mock function with given fields: ctx, houseID, role
-func (_m *HouseRepo) GetFlatsByHouseID(ctx context.Context, houseID int, role string) ([]models.Flat, error) {
-	ret := _m.Called(ctx, houseID, role)
+// FindHouseByAddress provides a mock function with given fields: ctx, normalizedAddress
+func (_m *HouseRepo) FindHouseByAddress(ctx context.Context, normalizedAddress string) (*models.House, error) {
+	ret := _m.Called(ctx, normalizedAddress)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindHouseByAddress")
+	}
+
+	var r0 *models.House
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.House, error)); ok {
+		return rf(ctx, normalizedAddress)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.House); ok {
+		r0 = rf(ctx, normalizedAddress)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.House)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, normalizedAddress)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFlatsByHouseID provides a mock function with given fields: ctx, houseID, userID, role, includeArchived, page
+func (_m *HouseRepo) GetFlatsByHouseID(ctx context.Context, houseID int, userID string, role models.Role, includeArchived bool, page models.FlatPage) ([]models.Flat, error) {
+	ret := _m.Called(ctx, houseID, userID, role, includeArchived, page)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetFlatsByHouseID")
@@ -43,19 +73,139 @@ func (_m *HouseRepo) GetFlatsByHouseID(ctx context.Context, houseID int, role st
 
 	var r0 []models.Flat
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, string) ([]models.Flat, error)); ok {
-		return rf(ctx, houseID, role)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, models.Role, bool, models.FlatPage) ([]models.Flat, error)); ok {
+		return rf(ctx, houseID, userID, role, includeArchived, page)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int, string) []models.Flat); ok {
-		r0 = rf(ctx, houseID, role)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, models.Role, bool, models.FlatPage) []models.Flat); ok {
+		r0 = rf(ctx, houseID, userID, role, includeArchived, page)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.Flat)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int, string) error); ok {
-		r1 = rf(ctx, houseID, role)
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, models.Role, bool, models.FlatPage) error); ok {
+		r1 = rf(ctx, houseID, userID, role, includeArchived, page)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHouseByID provides a mock function with given fields: ctx, houseID, includeArchived
+func (_m *HouseRepo) GetHouseByID(ctx context.Context, houseID int, includeArchived bool) (*models.House, error) {
+	ret := _m.Called(ctx, houseID, includeArchived)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHouseByID")
+	}
+
+	var r0 *models.House
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*models.House, error)); ok {
+		return rf(ctx, houseID, includeArchived)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *models.House); ok {
+		r0 = rf(ctx, houseID, includeArchived)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.House)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, houseID, includeArchived)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSubscribers provides a mock function with given fields: ctx, houseID
+func (_m *HouseRepo) GetSubscribers(ctx context.Context, houseID int) ([]string, error) {
+	ret := _m.Called(ctx, houseID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSubscribers")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]string, error)); ok {
+		return rf(ctx, houseID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []string); ok {
+		r0 = rf(ctx, houseID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, houseID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListHouses provides a mock function with given fields: ctx, filter
+func (_m *HouseRepo) ListHouses(ctx context.Context, filter models.HouseFilter) ([]models.House, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListHouses")
+	}
+
+	var r0 []models.House
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.HouseFilter) ([]models.House, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.HouseFilter) []models.House); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.House)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.HouseFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetHouseArchived provides a mock function with given fields: ctx, houseID, archived
+func (_m *HouseRepo) SetHouseArchived(ctx context.Context, houseID int, archived bool) (*models.House, error) {
+	ret := _m.Called(ctx, houseID, archived)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetHouseArchived")
+	}
+
+	var r0 *models.House
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) (*models.House, error)); ok {
+		return rf(ctx, houseID, archived)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, bool) *models.House); ok {
+		r0 = rf(ctx, houseID, archived)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.House)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, bool) error); ok {
+		r1 = rf(ctx, houseID, archived)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -81,6 +231,24 @@ func (_m *HouseRepo) SubscribeToHouse(ctx context.Context, houseID int, email st
 	return r0
 }
 
+// UpdateHouse provides a mock function with given fields: ctx, house
+func (_m *HouseRepo) UpdateHouse(ctx context.Context, house *models.House) error {
+	ret := _m.Called(ctx, house)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateHouse")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.House) error); ok {
+		r0 = rf(ctx, house)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewHouseRepo creates a new instance of HouseRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewHouseRepo(t interface {
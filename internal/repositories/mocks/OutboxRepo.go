@@ -0,0 +1,77 @@
+// Code generated by mockery v2.44.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "avito/internal/domain/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OutboxRepo is an autogenerated mock type for the OutboxRepo type
+type OutboxRepo struct {
+	mock.Mock
+}
+
+// FetchUnpublished provides a mock function with given fields: ctx, limit
+func (_m *OutboxRepo) FetchUnpublished(ctx context.Context, limit int) ([]models.Event, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FetchUnpublished")
+	}
+
+	var r0 []models.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.Event, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.Event); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkPublished provides a mock function with given fields: ctx, eventID
+func (_m *OutboxRepo) MarkPublished(ctx context.Context, eventID int64) error {
+	ret := _m.Called(ctx, eventID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkPublished")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, eventID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewOutboxRepo creates a new instance of OutboxRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOutboxRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OutboxRepo {
+	mock := &OutboxRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,119 @@
+// Code generated by mockery v2.44.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "avito/internal/domain/models"
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// SearchRepo is an autogenerated mock type for the SearchRepo type
+type SearchRepo struct {
+	mock.Mock
+}
+
+// NearbyHouses provides a mock function with given fields: ctx, center, radiusM, limit
+func (_m *SearchRepo) NearbyHouses(ctx context.Context, center models.GeoPoint, radiusM float64, limit int) ([]models.NearbyHouse, error) {
+	ret := _m.Called(ctx, center, radiusM, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NearbyHouses")
+	}
+
+	var r0 []models.NearbyHouse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.GeoPoint, float64, int) ([]models.NearbyHouse, error)); ok {
+		return rf(ctx, center, radiusM, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.GeoPoint, float64, int) []models.NearbyHouse); ok {
+		r0 = rf(ctx, center, radiusM, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.NearbyHouse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.GeoPoint, float64, int) error); ok {
+		r1 = rf(ctx, center, radiusM, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchFlats provides a mock function with given fields: ctx, search
+func (_m *SearchRepo) SearchFlats(ctx context.Context, search models.FlatSearch) ([]models.Flat, error) {
+	ret := _m.Called(ctx, search)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchFlats")
+	}
+
+	var r0 []models.Flat
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.FlatSearch) ([]models.Flat, error)); ok {
+		return rf(ctx, search)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.FlatSearch) []models.Flat); ok {
+		r0 = rf(ctx, search)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Flat)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.FlatSearch) error); ok {
+		r1 = rf(ctx, search)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchHouses provides a mock function with given fields: ctx, text, limit, offset
+func (_m *SearchRepo) SearchHouses(ctx context.Context, text string, limit int, offset int) ([]models.House, error) {
+	ret := _m.Called(ctx, text, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchHouses")
+	}
+
+	var r0 []models.House
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) ([]models.House, error)); ok {
+		return rf(ctx, text, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []models.House); ok {
+		r0 = rf(ctx, text, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.House)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) error); ok {
+		r1 = rf(ctx, text, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewSearchRepo creates a new instance of SearchRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSearchRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *SearchRepo {
+	mock := &SearchRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
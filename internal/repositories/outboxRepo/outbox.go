@@ -0,0 +1,93 @@
+package outboxRepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"avito/internal/domain/models"
+)
+
+type OutboxRepo interface {
+	FetchUnpublished(ctx context.Context, limit int) ([]models.Event, error)
+	MarkPublished(ctx context.Context, eventID int64) error
+}
+
+type Repository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewRepository(db *sql.DB, logger *slog.Logger) OutboxRepo {
+	return &Repository{db: db, logger: logger}
+}
+
+// InsertEvent writes an event within the caller's transaction,
+// so the event is stored if and only if the change itself is committed.
+func InsertEvent(ctx context.Context, tx *sql.Tx, eventType string, aggregateID int, payload any) error {
+	const op = "repository.outbox.InsertEvent"
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := "INSERT INTO outbox (event_type, aggregate_id, payload) VALUES ($1, $2, $3)"
+
+	if _, err := tx.ExecContext(ctx, query, eventType, aggregateID, data); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Repository) FetchUnpublished(ctx context.Context, limit int) ([]models.Event, error) {
+	const op = "repository.outbox.FetchUnpublished"
+
+	query := `
+		SELECT id, event_type, aggregate_id, payload, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		r.logger.Error("Failed to fetch outbox events", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		if err := rows.Scan(&event.ID, &event.Type, &event.AggregateID, &event.Payload, &event.CreatedAt); err != nil {
+			r.logger.Error("Failed to scan outbox event", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error during rows iteration", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+func (r *Repository) MarkPublished(ctx context.Context, eventID int64) error {
+	const op = "repository.outbox.MarkPublished"
+
+	query := "UPDATE outbox SET published_at = CURRENT_TIMESTAMP WHERE id = $1"
+
+	if _, err := r.db.ExecContext(ctx, query, eventID); err != nil {
+		r.logger.Error("Failed to mark outbox event as published", "op", op, "error", err, "eventID", eventID)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
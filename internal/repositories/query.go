@@ -0,0 +1,10 @@
+package repositories
+
+import "strings"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes the LIKE wildcards in s, so that it is matched literally
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,235 @@
+package searchRepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/lib/pq"
+
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"avito/internal/repositories/flatRepo"
+	"avito/internal/repositories/houseRepo"
+)
+
+type SearchRepo interface {
+	SearchFlats(ctx context.Context, search models.FlatSearch) ([]models.Flat, error)
+	SearchHouses(ctx context.Context, text string, limit, offset int) ([]models.House, error)
+	NearbyHouses(ctx context.Context, center models.GeoPoint, radiusM float64, limit int) ([]models.NearbyHouse, error)
+}
+
+type Repository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewRepository(db *sql.DB, logger *slog.Logger) SearchRepo {
+	return &Repository{db: db, logger: logger}
+}
+
+// SearchFlats - AuthOnly. Visibility is the same as in the flats of a house: moderators see every flat,
+// others approved flats and their own ones. Archived flats and flats of archived houses are never returned.
+func (r *Repository) SearchFlats(ctx context.Context, search models.FlatSearch) ([]models.Flat, error) {
+	const op = "repositories.search.SearchFlats"
+
+	query := "SELECT " + flatRepo.FlatColumns + " FROM flats WHERE archived_at IS NULL"
+	var args []interface{}
+
+	if !search.Role.CanModerate() {
+		args = append(args, search.UserID)
+		query += fmt.Sprintf(" AND (status = 'approved' OR created_by = $%d)", len(args))
+	}
+	if search.PriceFrom != nil {
+		args = append(args, *search.PriceFrom)
+		query += fmt.Sprintf(" AND price >= $%d", len(args))
+	}
+	if search.PriceTo != nil {
+		args = append(args, *search.PriceTo)
+		query += fmt.Sprintf(" AND price <= $%d", len(args))
+	}
+	if len(search.Rooms) > 0 {
+		args = append(args, pq.Array(search.Rooms))
+		query += fmt.Sprintf(" AND rooms = ANY($%d)", len(args))
+	}
+	if search.AreaFrom != nil {
+		args = append(args, *search.AreaFrom)
+		query += fmt.Sprintf(" AND total_area >= $%d", len(args))
+	}
+	if search.AreaTo != nil {
+		args = append(args, *search.AreaTo)
+		query += fmt.Sprintf(" AND total_area <= $%d", len(args))
+	}
+	if search.FloorFrom != nil {
+		args = append(args, *search.FloorFrom)
+		query += fmt.Sprintf(" AND floor >= $%d", len(args))
+	}
+	if search.FloorTo != nil {
+		args = append(args, *search.FloorTo)
+		query += fmt.Sprintf(" AND floor <= $%d", len(args))
+	}
+	if search.Balcony != nil {
+		args = append(args, *search.Balcony)
+		query += fmt.Sprintf(" AND balcony = $%d", len(args))
+	}
+	if search.Renovation != nil {
+		args = append(args, *search.Renovation)
+		query += fmt.Sprintf(" AND renovation = $%d", len(args))
+	}
+
+	// House filters narrow the set of houses the flats may belong to
+	houses := "SELECT id FROM houses WHERE archived_at IS NULL"
+	if search.YearFrom != nil {
+		args = append(args, *search.YearFrom)
+		houses += fmt.Sprintf(" AND year_built >= $%d", len(args))
+	}
+	if search.YearTo != nil {
+		args = append(args, *search.YearTo)
+		houses += fmt.Sprintf(" AND year_built <= $%d", len(args))
+	}
+	if search.Developer != nil {
+		args = append(args, *search.Developer)
+		houses += fmt.Sprintf(" AND lower(builder) = lower($%d)", len(args))
+	}
+	if search.Address != nil {
+		args = append(args, "%"+repositories.EscapeLike(*search.Address)+"%")
+		houses += fmt.Sprintf(" AND address ILIKE $%d", len(args))
+	}
+	query += " AND house_id IN (" + houses + ")"
+
+	args = append(args, search.Limit, search.Offset)
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to search flats", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var flats []models.Flat
+	for rows.Next() {
+		var flat models.Flat
+		if err := flatRepo.ScanFlat(rows, &flat); err != nil {
+			r.logger.Error("Failed to scan flat", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		flats = append(flats, flat)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error during rows iteration", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flats, nil
+}
+
+// SearchHouses - AuthOnly. Full-text search over addresses of not archived houses, most relevant first.
+// The query goes through the same abbreviation expansion as the stored address_tsv.
+func (r *Repository) SearchHouses(ctx context.Context, text string, limit, offset int) ([]models.House, error) {
+	const op = "repositories.search.SearchHouses"
+
+	query := `
+		SELECT ` + houseRepo.HouseColumns + `
+		FROM houses, websearch_to_tsquery('russian', expand_address_abbreviations($1)) AS q
+		WHERE address_tsv @@ q AND archived_at IS NULL
+		ORDER BY ts_rank(address_tsv, q) DESC, id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, text, limit, offset)
+	if err != nil {
+		r.logger.Error("Failed to search houses", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var houses []models.House
+	for rows.Next() {
+		var house models.House
+		if err := houseRepo.ScanHouse(rows, &house); err != nil {
+			r.logger.Error("Failed to scan house", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		houses = append(houses, house)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error during rows iteration", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return houses, nil
+}
+
+const (
+	earthRadiusM    = 6371000.0
+	metersPerDegree = earthRadiusM * math.Pi / 180
+)
+
+// withDistance reads the distance column selected after HouseColumns
+type withDistance struct {
+	rows     *sql.Rows
+	distance *float64
+}
+
+func (w withDistance) Scan(dest ...any) error {
+	return w.rows.Scan(append(dest, w.distance)...)
+}
+
+// NearbyHouses - AuthOnly. Not archived houses within radiusM meters of the center, nearest first.
+// Houses are preselected by a bounding box on (latitude, longitude), then filtered by the haversine distance.
+func (r *Repository) NearbyHouses(ctx context.Context, center models.GeoPoint, radiusM float64, limit int) ([]models.NearbyHouse, error) {
+	const op = "repositories.search.NearbyHouses"
+
+	dLat := radiusM / metersPerDegree
+	minLon, maxLon := -180.0, 180.0
+	// Near the poles and across the antimeridian the longitude box is not worth it
+	if cosLat := math.Cos(center.Lat * math.Pi / 180); cosLat > 0.01 {
+		dLon := radiusM / (metersPerDegree * cosLat)
+		if center.Lon-dLon >= -180 && center.Lon+dLon <= 180 {
+			minLon, maxLon = center.Lon-dLon, center.Lon+dLon
+		}
+	}
+
+	query := `
+		SELECT ` + houseRepo.HouseColumns + `, d.distance
+		FROM houses, LATERAL (
+			SELECT 2 * $3::float8 * asin(sqrt(LEAST(1,
+				power(sin(radians(latitude - $1) / 2), 2) +
+				cos(radians($1)) * cos(radians(latitude)) * power(sin(radians(longitude - $2) / 2), 2)
+			))) AS distance
+		) AS d
+		WHERE latitude IS NOT NULL AND archived_at IS NULL
+		  AND latitude BETWEEN $4 AND $5 AND longitude BETWEEN $6 AND $7
+		  AND d.distance <= $8
+		ORDER BY d.distance, id
+		LIMIT $9`
+
+	rows, err := r.db.QueryContext(ctx, query, center.Lat, center.Lon, earthRadiusM,
+		center.Lat-dLat, center.Lat+dLat, minLon, maxLon, radiusM, limit)
+	if err != nil {
+		r.logger.Error("Failed to find nearby houses", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var houses []models.NearbyHouse
+	for rows.Next() {
+		var house models.NearbyHouse
+		if err := houseRepo.ScanHouse(withDistance{rows: rows, distance: &house.DistanceM}, &house.House); err != nil {
+			r.logger.Error("Failed to scan house", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		houses = append(houses, house)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error during rows iteration", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return houses, nil
+}
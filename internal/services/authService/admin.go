@@ -0,0 +1,90 @@
+package authService
+
+import (
+	"avito/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrSelfManagement means an admin tried to demote or disable themselves
+	ErrSelfManagement = errors.New("admins can not change their own role or disable themselves")
+)
+
+// ListUsers returns the users matching the filter ordered by email
+func (s *Service) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	const op = "authService.ListUsers"
+
+	if filter.Role != nil && !filter.Role.Valid() {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidRole)
+	}
+
+	users, err := s.repo.ListUsers(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to list users", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// SetRole changes the role of the user. Their sessions end, so the new role applies to every request from now on.
+func (s *Service) SetRole(ctx context.Context, adminID, userID string, role models.Role) (*models.User, error) {
+	const op = "authService.SetRole"
+
+	if !role.Valid() {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidRole)
+	}
+	if adminID == userID {
+		return nil, fmt.Errorf("%s: %w", op, ErrSelfManagement)
+	}
+
+	user, err := s.repo.SetUserRole(ctx, userID, role)
+	if err != nil {
+		s.logger.Error("Failed to set role", slog.String("op", op), slog.String("user_id", userID), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	s.denylist.SetUser(*user)
+
+	s.logger.Info("User role changed", slog.String("op", op), slog.String("admin_id", adminID),
+		slog.String("user_id", userID), slog.String("role", string(role)))
+	return user, nil
+}
+
+// SetDisabled disables or enables the user. A disabled user can not log in and all their tokens are rejected.
+func (s *Service) SetDisabled(ctx context.Context, adminID, userID string, disabled bool) (*models.User, error) {
+	const op = "authService.SetDisabled"
+
+	if adminID == userID {
+		return nil, fmt.Errorf("%s: %w", op, ErrSelfManagement)
+	}
+
+	user, err := s.repo.SetUserDisabled(ctx, userID, disabled)
+	if err != nil {
+		s.logger.Error("Failed to update user", slog.String("op", op), slog.String("user_id", userID), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	s.denylist.SetUser(*user)
+
+	s.logger.Info("User disabled state changed", slog.String("op", op), slog.String("admin_id", adminID),
+		slog.String("user_id", userID), slog.Bool("disabled", disabled))
+	return user, nil
+}
+
+// ForceLogout ends every session of the user: issued access tokens are rejected and refresh tokens revoked
+func (s *Service) ForceLogout(ctx context.Context, userID string) error {
+	const op = "authService.ForceLogout"
+
+	user, err := s.repo.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to revoke sessions", slog.String("op", op), slog.String("user_id", userID), "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	s.denylist.SetUser(*user)
+
+	s.logger.Info("User logged out by admin", slog.String("op", op), slog.String("user_id", userID))
+	return nil
+}
@@ -2,6 +2,8 @@ package authService
 
 import (
 	"avito/internal/domain/models"
+	"avito/internal/lib/loginguard"
+	"avito/internal/repositories"
 	"avito/internal/repositories/authRepo"
 
 	"context"
@@ -9,33 +11,77 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"log/slog"
+	"strings"
 	"time"
 )
 
 type AuthService interface {
-	Register(ctx context.Context, email, password, role string) (string, error)
-	Login(ctx context.Context, id, password string) (*models.User, error)
-	GenerateToken(userID string, role string) (string, error)
+	Register(ctx context.Context, email, password string, role models.Role, inviteCode string) (string, error)
+	CreateInvite(ctx context.Context, createdBy string, role models.Role) (*models.Invite, error)
+	Login(ctx context.Context, id, password, ip string) (*models.User, error)
+	LoginByEmail(ctx context.Context, email, password, ip string) (*models.User, error)
+	GenerateToken(userID string, role models.Role) (string, error)
 	ValidateToken(tokenStr string) (*models.Claims, error)
+	IssueTokens(ctx context.Context, user *models.User) (*models.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	Logout(ctx context.Context, claims *models.Claims, refreshToken string, allSessions bool) error
+	IsRevoked(claims *models.Claims) bool
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)
+	SetRole(ctx context.Context, adminID, userID string, role models.Role) (*models.User, error)
+	SetDisabled(ctx context.Context, adminID, userID string, disabled bool) (*models.User, error)
+	ForceLogout(ctx context.Context, userID string) error
 }
 
 type Service struct {
-	repo      authRepo.AuthRepo
-	jwtSecret string
-	logger    *slog.Logger
+	repo       authRepo.AuthRepo
+	denylist   *Denylist
+	guard      *loginguard.Guard
+	jwtSecret  string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	inviteTTL  time.Duration
+	logger     *slog.Logger
 }
 
-func NewService(repo authRepo.AuthRepo, jwtSecret string, logger *slog.Logger) AuthService {
+// NewService creates the auth service. Access tokens live for accessTTL and are checked against
+// the denylist; refresh tokens live for refreshTTL and invite codes for inviteTTL. Failed logins are
+// throttled by the guard; a nil guard does not limit them.
+func NewService(repo authRepo.AuthRepo, denylist *Denylist, guard *loginguard.Guard, jwtSecret string, accessTTL, refreshTTL, inviteTTL time.Duration,
+	logger *slog.Logger) AuthService {
 	return &Service{
-		repo:      repo,
-		jwtSecret: jwtSecret,
-		logger:    logger,
+		repo:       repo,
+		denylist:   denylist,
+		guard:      guard,
+		jwtSecret:  jwtSecret,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		inviteTTL:  inviteTTL,
+		logger:     logger,
 	}
 }
 
-func (s *Service) Register(ctx context.Context, email, password, role string) (string, error) {
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Register creates a user. Moderators must present an invite code, which is redeemed together with
+// creating the user; for other roles the code is ignored.
+func (s *Service) Register(ctx context.Context, email, password string, role models.Role, inviteCode string) (string, error) {
 	const op = "authService.Register"
 
+	email = strings.TrimSpace(email)
+	if err := validateEmail(email); err != nil {
+		s.logger.Warn("Invalid email", slog.String("op", op), "error", err)
+		return "", err
+	}
+	if err := validatePassword(password); err != nil {
+		s.logger.Warn("Weak password", slog.String("op", op), "error", err)
+		return "", err
+	}
+
+	if requiresInvite(role) && inviteCode == "" {
+		s.logger.Warn("Registration without invite", slog.String("op", op), slog.String("role", string(role)))
+		return "", ErrInviteRequired
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		s.logger.Error("Password hashing error", slog.String("op", op), "error", err)
@@ -48,7 +94,16 @@ func (s *Service) Register(ctx context.Context, email, password, role string) (s
 		Role:     role,
 	}
 
-	userID, err := s.repo.CreateUser(ctx, user)
+	var userID string
+	if requiresInvite(role) {
+		userID, err = s.repo.CreateUserWithInvite(ctx, user, hashToken(inviteCode))
+		if errors.Is(err, repositories.ErrInvalidInvite) {
+			s.logger.Warn("Invalid invite", slog.String("op", op), slog.String("role", string(role)))
+			return "", ErrInvalidInvite
+		}
+	} else {
+		userID, err = s.repo.CreateUser(ctx, user)
+	}
 	if err != nil {
 		s.logger.Error("Error creating user", slog.String("op", op), "error", err)
 		return "", err
@@ -57,18 +112,37 @@ func (s *Service) Register(ctx context.Context, email, password, role string) (s
 	return userID, nil
 }
 
-func (s *Service) Login(ctx context.Context, id, password string) (*models.User, error) {
+// Login checks the password of the user with the ID. Kept for clients that log in by ID.
+// ip is the client address failed attempts are also counted against.
+func (s *Service) Login(ctx context.Context, id, password, ip string) (*models.User, error) {
 	const op = "authService.Login"
 
-	user, err := s.repo.GetUserByEmail(ctx, id)
-	if err != nil {
-		s.logger.Error("Error getting user by id", slog.String("op", op), "error", err)
-		return nil, errors.New("invalid credentials")
-	}
+	return s.guardedLogin(ctx, op, id, password, ip, func() (*models.User, error) {
+		return s.repo.GetUserByID(ctx, id)
+	})
+}
 
+// LoginByEmail checks the password of the user with the email, compared case-insensitively
+func (s *Service) LoginByEmail(ctx context.Context, email, password, ip string) (*models.User, error) {
+	const op = "authService.LoginByEmail"
+
+	email = strings.TrimSpace(email)
+	return s.guardedLogin(ctx, op, email, password, ip, func() (*models.User, error) {
+		return s.repo.GetUserByEmail(ctx, email)
+	})
+}
+
+func (s *Service) checkPassword(op string, user *models.User, password string) (*models.User, error) {
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		s.logger.Error("Incorrect credentials", slog.String("op", op), slog.String("id", id))
-		return nil, errors.New("invalid credentials")
+		s.logger.Error("Incorrect credentials", slog.String("op", op), slog.String("id", user.ID))
+		return nil, ErrInvalidCredentials
+	}
+
+	// A disabled user gets the same answer as a wrong password, otherwise the answer would tell
+	// whether the guessed password was right
+	if user.DisabledAt != nil {
+		s.logger.Warn("Login of disabled user", slog.String("op", op), slog.String("id", user.ID))
+		return nil, ErrInvalidCredentials
 	}
 
 	s.logger.Debug("Successful login", slog.String("op", op), slog.String("user_id", user.ID))
@@ -76,15 +150,29 @@ func (s *Service) Login(ctx context.Context, id, password string) (*models.User,
 	return user, nil
 }
 
-func (s *Service) GenerateToken(userID string, role string) (string, error) {
+func init() {
+	// iat is compared with session revocations, which have microseconds in the database:
+	// with whole seconds a token issued in the second of a forced logout could not be told apart
+	jwt.TimePrecision = time.Microsecond
+}
+
+func (s *Service) GenerateToken(userID string, role models.Role) (string, error) {
 	const op = "authService.GenerateToken"
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+	jti, err := randomToken()
+	if err != nil {
+		s.logger.Error("Error generating token ID", slog.String("op", op), "error", err)
+		return "", err
+	}
+
+	now := time.Now()
 	claims := &models.Claims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
 		},
 	}
 
@@ -0,0 +1,55 @@
+package authService
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"unicode"
+)
+
+const (
+	MaxEmailLen    = 254
+	MinPasswordLen = 8
+	MaxPasswordLen = 72 // bcrypt ignores the bytes after the 72nd
+)
+
+var (
+	ErrInvalidEmail = errors.New("invalid email")
+	ErrWeakPassword = errors.New("weak password")
+)
+
+// validateEmail accepts a bare address like user@example.com, without a display name or angle brackets
+func validateEmail(email string) error {
+	if len(email) > MaxEmailLen {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidEmail, MaxEmailLen)
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("%w: %q is not an email address", ErrInvalidEmail, email)
+	}
+	return nil
+}
+
+// validatePassword requires MinPasswordLen to MaxPasswordLen bytes with at least one letter and one digit
+func validatePassword(password string) error {
+	if len(password) < MinPasswordLen {
+		return fmt.Errorf("%w: shorter than %d characters", ErrWeakPassword, MinPasswordLen)
+	}
+	if len(password) > MaxPasswordLen {
+		return fmt.Errorf("%w: longer than %d bytes", ErrWeakPassword, MaxPasswordLen)
+	}
+
+	var letter, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !letter || !digit {
+		return fmt.Errorf("%w: must contain a letter and a digit", ErrWeakPassword)
+	}
+	return nil
+}
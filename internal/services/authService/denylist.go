@@ -0,0 +1,160 @@
+package authService
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/repositories/authRepo"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Denylist keeps the IDs of revoked access tokens and the users whose tokens are rejected in memory,
+// so that requests are checked without a query. Revocations made by this instance apply at once;
+// those made by other instances are picked up when the list is reloaded from the repository every interval.
+type Denylist struct {
+	repo     authRepo.AuthRepo
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.RWMutex
+	revoked map[string]time.Time // jti -> expiry of the token
+	users   map[string]userRevocation
+}
+
+// userRevocation rejects all tokens of a disabled user and the tokens issued before revokedAt
+type userRevocation struct {
+	disabled  bool
+	revokedAt time.Time
+	setAt     time.Time // when this instance learned about it
+}
+
+func NewDenylist(repo authRepo.AuthRepo, interval time.Duration, logger *slog.Logger) *Denylist {
+	return &Denylist{
+		repo:     repo,
+		interval: interval,
+		logger:   logger,
+		revoked:  make(map[string]time.Time),
+		users:    make(map[string]userRevocation),
+	}
+}
+
+// IsRevoked reports whether the token with this jti was revoked. Tokens without a jti can not be revoked.
+func (d *Denylist) IsRevoked(jti string) bool {
+	if jti == "" {
+		return false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	expiresAt, ok := d.revoked[jti]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// IsUserRevoked reports whether a token issued to the user at issuedAt is rejected.
+// Tokens carry iat with microseconds, so a token issued in the same second as the revocation is told apart.
+func (d *Denylist) IsUserRevoked(userID string, issuedAt time.Time) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	revocation, ok := d.users[userID]
+	return ok && (revocation.disabled || issuedAt.Before(revocation.revokedAt))
+}
+
+// SetUser applies the disabled state and the session revocation of the user in this instance
+func (d *Denylist) SetUser(user models.User) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.setUser(user, time.Now())
+}
+
+func (d *Denylist) setUser(user models.User, now time.Time) {
+	revocation := userRevocation{disabled: user.DisabledAt != nil, setAt: now}
+	if user.SessionsRevokedAt != nil {
+		revocation.revokedAt = *user.SessionsRevokedAt
+	}
+	if !revocation.disabled && revocation.revokedAt.IsZero() {
+		delete(d.users, user.ID)
+		return
+	}
+	d.users[user.ID] = revocation
+}
+
+// Add revokes the token in this instance until it expires
+func (d *Denylist) Add(jti string, expiresAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.revoked[jti] = expiresAt
+}
+
+// Reload merges the revoked tokens from the repository into the list and drops expired ones.
+func (d *Denylist) Reload(ctx context.Context) error {
+	const op = "authService.Denylist.Reload"
+
+	started := time.Now()
+	loaded, err := d.repo.GetRevokedAccessTokens(ctx)
+	if err != nil {
+		return err
+	}
+	users, err := d.repo.GetRevokedUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Users changed by this instance while the queries were running keep the newer state
+	changed := make(map[string]userRevocation)
+	for userID, revocation := range d.users {
+		if revocation.setAt.After(started) {
+			changed[userID] = revocation
+		}
+	}
+	d.users = make(map[string]userRevocation, len(users))
+	for _, user := range users {
+		d.setUser(user, started)
+	}
+	for userID, revocation := range changed {
+		d.users[userID] = revocation
+	}
+
+	// Tokens added while the query was running are kept
+	now := time.Now()
+	for jti, expiresAt := range d.revoked {
+		if _, ok := loaded[jti]; !ok && now.Before(expiresAt) {
+			loaded[jti] = expiresAt
+		}
+	}
+	d.revoked = loaded
+
+	d.logger.Debug("Denylist reloaded", slog.String("op", op), slog.Int("count", len(loaded)),
+		slog.Int("users", len(d.users)))
+	return nil
+}
+
+// Run reloads the list until ctx is cancelled.
+func (d *Denylist) Run(ctx context.Context) {
+	const op = "authService.Denylist.Run"
+
+	d.logger.Info("Token denylist sync started", slog.String("op", op), slog.Duration("interval", d.interval))
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Token denylist sync stopped", slog.String("op", op))
+			return
+		case <-ticker.C:
+		}
+
+		if err := d.Reload(ctx); err != nil {
+			d.logger.Error("Failed to reload denylist", slog.String("op", op), "error", err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package authService
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrInviteRequired = errors.New("invite code is required for this role")
+	ErrInvalidInvite  = errors.New("invalid invite code")
+	// ErrInviteNotAllowed means the user may not invite users with this role
+	ErrInviteNotAllowed = errors.New("not allowed to invite users with this role")
+)
+
+// requiresInvite reports whether users with the role can register only by invite
+func requiresInvite(role models.Role) bool {
+	return role == models.RoleModerator || role == models.RoleAdmin
+}
+
+// CreateInvite mints a single-use code for registering a user with the role. Moderators invite moderators,
+// admins invite moderators and admins. The creator must be a registered user who is not disabled, and their
+// current role is taken from the repository: a dummy moderator token can not mint invites.
+// The code is returned only here; the repository keeps its hash.
+func (s *Service) CreateInvite(ctx context.Context, createdBy string, role models.Role) (*models.Invite, error) {
+	const op = "authService.CreateInvite"
+
+	creator, err := s.repo.GetUserByID(ctx, createdBy)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		s.logger.Warn("Invite by unregistered user", slog.String("op", op), slog.String("created_by", createdBy))
+		return nil, ErrInviteNotAllowed
+	}
+	if err != nil {
+		s.logger.Error("Failed to get invite creator", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	creatorRole := creator.Role
+	if creator.DisabledAt != nil {
+		creatorRole = ""
+	}
+
+	if !requiresInvite(role) || !creatorRole.CanModerate() || role == models.RoleAdmin && creatorRole != models.RoleAdmin {
+		s.logger.Warn("Invite not allowed", slog.String("op", op), slog.String("created_by", createdBy),
+			slog.String("role", string(role)))
+		return nil, ErrInviteNotAllowed
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		s.logger.Error("Failed to generate invite code", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	invite := &models.Invite{
+		Code:      code,
+		CodeHash:  hashToken(code),
+		Role:      role,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(s.inviteTTL),
+	}
+	if err := s.repo.CreateInvite(ctx, invite); err != nil {
+		s.logger.Error("Failed to store invite", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("Invite created", slog.String("op", op), slog.String("created_by", createdBy),
+		slog.String("role", string(role)), slog.Int("invite_id", invite.ID))
+	return invite, nil
+}
@@ -0,0 +1,77 @@
+package authService
+
+import (
+	"avito/internal/domain/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// TooManyAttemptsError is returned while the account or the client IP is locked out after failed logins
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter)
+}
+
+func (e *TooManyAttemptsError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// guardedLogin checks the password of the user found by lookup unless the IP or the account is locked out.
+// Failures are counted against the user ID, or against the identifier if there is no such user,
+// so guessing unknown accounts is throttled the same way. Every attempt is counted as failed before
+// the password is checked and forgiven when it succeeds; an attempt at a locked account still counts
+// against the IP. A login of a disabled user is a failure like a wrong password.
+// The guard store failing does not block logins.
+func (s *Service) guardedLogin(ctx context.Context, op, identifier, password, ip string,
+	lookup func() (*models.User, error)) (*models.User, error) {
+	if err := s.attemptLogin(ctx, op, "", ip); err != nil {
+		return nil, err
+	}
+
+	user, err := lookup()
+	account := "login:" + strings.ToLower(identifier)
+	if err == nil {
+		account = user.ID
+	}
+	if err := s.attemptLogin(ctx, op, account, ""); err != nil {
+		return nil, err
+	}
+
+	if err != nil {
+		s.logger.Error("Error getting user", slog.String("op", op), "error", err)
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err = s.checkPassword(op, user, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		return nil, err
+	}
+	if err := s.guard.Succeed(ctx, account, ip); err != nil {
+		s.logger.Error("Failed to reset login attempts", slog.String("op", op), "error", err)
+	}
+	return user, err
+}
+
+// attemptLogin counts the attempt against the account and the IP or rejects it if they are locked out
+func (s *Service) attemptLogin(ctx context.Context, op, account, ip string) error {
+	retryAfter, err := s.guard.Attempt(ctx, account, ip)
+	if err != nil {
+		s.logger.Error("Failed to count login attempt", slog.String("op", op), "error", err)
+		return nil
+	}
+	if retryAfter > 0 {
+		s.logger.Warn("Login locked out", slog.String("op", op), slog.String("account", account),
+			slog.String("ip", ip), slog.Duration("retry_after", retryAfter))
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+	return nil
+}
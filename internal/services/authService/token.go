@@ -0,0 +1,189 @@
+package authService
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrTokenRevoked        = errors.New("token is revoked")
+)
+
+// IssueTokens starts a session of the registered user: an access token and a new family of refresh tokens.
+func (s *Service) IssueTokens(ctx context.Context, user *models.User) (*models.TokenPair, error) {
+	const op = "authService.IssueTokens"
+
+	refresh, record, err := s.newRefreshToken(user.ID, "")
+	if err != nil {
+		s.logger.Error("Failed to generate refresh token", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if err := s.repo.CreateRefreshToken(ctx, record); err != nil {
+		s.logger.Error("Failed to store refresh token", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	access, err := s.GenerateToken(user.ID, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresIn: s.accessTTL}, nil
+}
+
+// Refresh exchanges a refresh token for a new pair. The used token is revoked; presenting it again means
+// it was stolen, so the whole family is revoked and the session ends. The role is read from the user record,
+// so a changed role takes effect on the next refresh.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	const op = "authService.Refresh"
+
+	stored, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		s.logger.Error("Failed to get refresh token", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if stored == nil {
+		s.logger.Warn("Unknown refresh token", slog.String("op", op))
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.RevokedAt != nil {
+		s.logger.Warn("Revoked refresh token reused, ending the session", slog.String("op", op),
+			slog.String("user_id", stored.UserID), slog.String("family_id", stored.FamilyID))
+		return nil, s.revokeFamily(ctx, op, stored.FamilyID)
+	}
+	if !time.Now().Before(stored.ExpiresAt) {
+		s.logger.Warn("Refresh token expired", slog.String("op", op), slog.String("user_id", stored.UserID))
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		s.logger.Error("Failed to get user", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		s.logger.Warn("Refresh by disabled user", slog.String("op", op), slog.String("user_id", user.ID))
+		return nil, ErrInvalidRefreshToken
+	}
+
+	refresh, record, err := s.newRefreshToken(user.ID, stored.FamilyID)
+	if err != nil {
+		s.logger.Error("Failed to generate refresh token", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if err := s.repo.RotateRefreshToken(ctx, stored.ID, record); err != nil {
+		if errors.Is(err, repositories.ErrRefreshTokenUsed) {
+			s.logger.Warn("Refresh token used concurrently, ending the session", slog.String("op", op),
+				slog.String("user_id", stored.UserID))
+			return nil, s.revokeFamily(ctx, op, stored.FamilyID)
+		}
+		s.logger.Error("Failed to rotate refresh token", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	access, err := s.GenerateToken(user.ID, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Debug("Tokens refreshed", slog.String("op", op), slog.String("user_id", user.ID))
+	return &models.TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresIn: s.accessTTL}, nil
+}
+
+// Logout revokes the access token of the request and the session of the refresh token, if given.
+// With allSessions every refresh token of the user is revoked.
+func (s *Service) Logout(ctx context.Context, claims *models.Claims, refreshToken string, allSessions bool) error {
+	const op = "authService.Logout"
+
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.repo.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			s.logger.Error("Failed to revoke access token", slog.String("op", op), "error", err)
+			return err
+		}
+		s.denylist.Add(claims.ID, claims.ExpiresAt.Time)
+	}
+
+	if allSessions {
+		if err := s.repo.RevokeUserRefreshTokens(ctx, claims.UserID); err != nil {
+			s.logger.Error("Failed to revoke refresh tokens", slog.String("op", op), "error", err)
+			return err
+		}
+	} else if refreshToken != "" {
+		stored, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+		if err != nil {
+			s.logger.Error("Failed to get refresh token", slog.String("op", op), "error", err)
+			return err
+		}
+		// Somebody else's token is not revealed: it is ignored like an unknown one
+		if stored != nil && stored.UserID == claims.UserID {
+			if err := s.repo.RevokeRefreshFamily(ctx, stored.FamilyID); err != nil {
+				s.logger.Error("Failed to revoke refresh tokens", slog.String("op", op), "error", err)
+				return err
+			}
+		}
+	}
+
+	s.logger.Debug("User logged out", slog.String("op", op), slog.String("user_id", claims.UserID),
+		slog.Bool("all_sessions", allSessions))
+	return nil
+}
+
+// IsRevoked reports whether the access token was revoked before it expired, or belongs to a disabled user
+// or to a user whose sessions were revoked after it was issued
+func (s *Service) IsRevoked(claims *models.Claims) bool {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	return s.denylist.IsRevoked(claims.ID) || s.denylist.IsUserRevoked(claims.UserID, issuedAt)
+}
+
+func (s *Service) revokeFamily(ctx context.Context, op, familyID string) error {
+	if err := s.repo.RevokeRefreshFamily(ctx, familyID); err != nil {
+		s.logger.Error("Failed to revoke refresh tokens", slog.String("op", op), "error", err)
+		return err
+	}
+	return ErrInvalidRefreshToken
+}
+
+// newRefreshToken returns a random token and its record to store; familyID is empty for a new session
+func (s *Service) newRefreshToken(userID, familyID string) (string, *models.RefreshToken, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is the stored form of a refresh token. The token is random, so a plain hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
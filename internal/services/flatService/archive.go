@@ -0,0 +1,55 @@
+package flatService
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Archive hides the flat from every read path. The owner and moderators may archive a flat,
+// except while it is on moderation.
+func (s *Service) Archive(ctx context.Context, flatID int, userID string, role models.Role) (*models.Flat, error) {
+	return s.setArchived(ctx, flatID, userID, role, true)
+}
+
+// Restore brings an archived flat back with the status it had.
+func (s *Service) Restore(ctx context.Context, flatID int, userID string, role models.Role) (*models.Flat, error) {
+	return s.setArchived(ctx, flatID, userID, role, false)
+}
+
+func (s *Service) setArchived(ctx context.Context, flatID int, userID string, role models.Role, archived bool) (*models.Flat, error) {
+	const op = "flatService.setArchived"
+
+	flat, err := s.repo.GetFlatByID(ctx, flatID, true)
+	if err != nil {
+		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if flat == nil {
+		s.logger.Error("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatNotFound
+	}
+
+	if !role.CanModerate() && !isOwner(flat, userID) {
+		s.logger.Error("User is not the owner of the flat", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrNotFlatOwner
+	}
+
+	updatedFlat, err := s.repo.SetFlatArchived(ctx, flatID, archived)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrFlatStatusChanged):
+			s.logger.Warn("Flat is being moderated", slog.String("op", op), slog.Int("flatID", flatID))
+			return nil, ErrFlatBeingModerated
+		case errors.Is(err, repositories.ErrFlatNotFound):
+			return nil, ErrFlatNotFound
+		}
+		s.logger.Error("Failed to archive flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	s.logger.Debug("Flat archive state changed", slog.String("op", op), slog.Int("flatID", flatID), slog.Bool("archived", archived))
+	return updatedFlat, nil
+}
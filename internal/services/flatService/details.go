@@ -0,0 +1,45 @@
+package flatService
+
+import (
+	"avito/internal/domain/models"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Ranges of the flat details
+const (
+	MaxArea           = 10000.0 // m²
+	MinFloor          = -3      // basement floors
+	MaxFloors         = 200
+	MaxDescriptionLen = 5000 // characters
+)
+
+// validateDetails checks the ranges of the details and their consistency:
+// the living area is within the total area and the floor is within the house.
+func validateDetails(d models.FlatDetails) error {
+	if d.TotalArea != nil && (*d.TotalArea <= 0 || *d.TotalArea > MaxArea) {
+		return fmt.Errorf("%w: total area must be in (0, %g]", ErrInvalidFlatData, MaxArea)
+	}
+	if d.LivingArea != nil && (*d.LivingArea <= 0 || *d.LivingArea > MaxArea) {
+		return fmt.Errorf("%w: living area must be in (0, %g]", ErrInvalidFlatData, MaxArea)
+	}
+	if d.TotalArea != nil && d.LivingArea != nil && *d.LivingArea > *d.TotalArea {
+		return fmt.Errorf("%w: living area exceeds total area", ErrInvalidFlatData)
+	}
+	if d.Floor != nil && (*d.Floor < MinFloor || *d.Floor > MaxFloors) {
+		return fmt.Errorf("%w: floor must be in [%d, %d]", ErrInvalidFlatData, MinFloor, MaxFloors)
+	}
+	if d.TotalFloors != nil && (*d.TotalFloors < 1 || *d.TotalFloors > MaxFloors) {
+		return fmt.Errorf("%w: total floors must be in [1, %d]", ErrInvalidFlatData, MaxFloors)
+	}
+	if d.Floor != nil && d.TotalFloors != nil && *d.Floor > *d.TotalFloors {
+		return fmt.Errorf("%w: floor exceeds total floors", ErrInvalidFlatData)
+	}
+	if d.Renovation != nil && !models.IsRenovation(*d.Renovation) {
+		return fmt.Errorf("%w: unknown renovation type %q", ErrInvalidFlatData, *d.Renovation)
+	}
+	if d.Description != nil && utf8.RuneCountInString(*d.Description) > MaxDescriptionLen {
+		return fmt.Errorf("%w: description is longer than %d characters", ErrInvalidFlatData, MaxDescriptionLen)
+	}
+	return nil
+}
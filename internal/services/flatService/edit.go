@@ -0,0 +1,198 @@
+package flatService
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"context"
+	"errors"
+	"log/slog"
+)
+
+var (
+	ErrInvalidFlatData = errors.New("invalid flat data")
+	ErrFlatNumberTaken = errors.New("flat number is already taken in the house")
+)
+
+// FlatChanges are the fields the owner wants to change; nil fields are left as they are.
+type FlatChanges struct {
+	FlatNumber *int
+	Price      *int
+	Rooms      *int
+	models.FlatDetails
+}
+
+// materialFields are the fields whose change sends an approved flat back to moderation.
+// The balcony flag is a correction the moderator has nothing to check in.
+var materialFields = map[string]bool{
+	"flat_number":  true,
+	"price":        true,
+	"rooms":        true,
+	"photos":       true,
+	"total_area":   true,
+	"living_area":  true,
+	"floor":        true,
+	"total_floors": true,
+	"renovation":   true,
+	"description":  true,
+}
+
+func (c FlatChanges) validate() error {
+	if c.FlatNumber != nil && *c.FlatNumber < 1 {
+		return ErrInvalidFlatData
+	}
+	if c.Price != nil && *c.Price < 0 {
+		return ErrInvalidFlatData
+	}
+	if c.Rooms != nil && *c.Rooms < 1 {
+		return ErrInvalidFlatData
+	}
+	return validateDetails(c.FlatDetails)
+}
+
+// apply builds the update of flat and the diff of the fields that actually change.
+func (c FlatChanges) apply(flat *models.Flat) models.FlatUpdate {
+	update := models.FlatUpdate{
+		FlatID:      flat.ID,
+		From:        flat.Status,
+		To:          flat.Status,
+		FlatNumber:  flat.FlatNumber,
+		Price:       flat.Price,
+		Rooms:       flat.Rooms,
+		FlatDetails: flat.FlatDetails,
+		Changes:     make(map[string]models.FieldChange),
+	}
+
+	update.FlatNumber = applyOptional(update.Changes, "flat_number", flat.FlatNumber, c.FlatNumber)
+	if c.Price != nil && *c.Price != flat.Price {
+		update.Changes["price"] = models.FieldChange{Old: flat.Price, New: *c.Price}
+		update.Price = *c.Price
+	}
+	if c.Rooms != nil && *c.Rooms != flat.Rooms {
+		update.Changes["rooms"] = models.FieldChange{Old: flat.Rooms, New: *c.Rooms}
+		update.Rooms = *c.Rooms
+	}
+
+	d := &update.FlatDetails
+	d.TotalArea = applyOptional(update.Changes, "total_area", flat.TotalArea, c.TotalArea)
+	d.LivingArea = applyOptional(update.Changes, "living_area", flat.LivingArea, c.LivingArea)
+	d.Floor = applyOptional(update.Changes, "floor", flat.Floor, c.Floor)
+	d.TotalFloors = applyOptional(update.Changes, "total_floors", flat.TotalFloors, c.TotalFloors)
+	d.Balcony = applyOptional(update.Changes, "balcony", flat.Balcony, c.Balcony)
+	d.Renovation = applyOptional(update.Changes, "renovation", flat.Renovation, c.Renovation)
+	d.Description = applyOptional(update.Changes, "description", flat.Description, c.Description)
+
+	requireModeration(flat, &update)
+
+	return update
+}
+
+// applyOptional returns the new value of an optional field and records the change if there is one
+func applyOptional[T comparable](changes map[string]models.FieldChange, field string, old, new *T) *T {
+	if new == nil || old != nil && *old == *new {
+		return old
+	}
+	var oldValue any
+	if old != nil {
+		oldValue = *old
+	}
+	changes[field] = models.FieldChange{Old: oldValue, New: *new}
+	return new
+}
+
+// requireModeration sends an approved flat back to moderation if the update changes a material field
+func requireModeration(flat *models.Flat, update *models.FlatUpdate) {
+	if flat.Status != models.StatusApproved {
+		return
+	}
+	for field := range update.Changes {
+		if materialFields[field] {
+			update.To = models.StatusCreated
+			return
+		}
+	}
+}
+
+// UpdateFlat applies the owner's changes to the flat. A material change of an approved flat
+// sends it back to the moderation queue. Flats on moderation can not be edited.
+func (s *Service) UpdateFlat(ctx context.Context, flatID int, userID string, changes FlatChanges) (*models.Flat, error) {
+	const op = "flatService.UpdateFlat"
+
+	if err := changes.validate(); err != nil {
+		s.logger.Error("Invalid flat data", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, err
+	}
+
+	flat, err := s.repo.GetFlatByID(ctx, flatID, false)
+	if err != nil {
+		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if flat == nil {
+		s.logger.Error("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatNotFound
+	}
+
+	if !isOwner(flat, userID) {
+		s.logger.Error("User is not the owner of the flat", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrNotFlatOwner
+	}
+
+	if flat.Status == models.StatusOnModeration {
+		s.logger.Error("Flat is being moderated", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatBeingModerated
+	}
+
+	update := changes.apply(flat)
+	if len(update.Changes) == 0 {
+		s.logger.Debug("Nothing to change", slog.String("op", op), slog.Int("flatID", flatID))
+		return flat, nil
+	}
+	// The changed details must agree with the kept ones, e.g. a new living area with the old total area
+	if err := validateDetails(update.FlatDetails); err != nil {
+		s.logger.Debug("Invalid flat details", slog.String("op", op), slog.Int("flatID", flatID), "error", err)
+		return nil, err
+	}
+	update.EditorID = userID
+
+	updatedFlat, err := s.repo.UpdateFlat(ctx, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrFlatStatusChanged):
+			s.logger.Warn("Flat was changed concurrently", slog.String("op", op), slog.Int("flatID", flatID))
+			return nil, ErrLostRace
+		case errors.Is(err, repositories.ErrFlatNotFound):
+			return nil, ErrFlatNotFound
+		case errors.Is(err, repositories.ErrFlatNumberExists):
+			return nil, ErrFlatNumberTaken
+		}
+		s.logger.Error("Failed to update flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	s.logger.Debug("Flat updated", slog.String("op", op), slog.Int("flatID", flatID),
+		slog.String("status", updatedFlat.Status))
+	return updatedFlat, nil
+}
+
+// GetFlatEdits returns the owner's edits of the flat, oldest first.
+func (s *Service) GetFlatEdits(ctx context.Context, flatID int) ([]models.FlatEdit, error) {
+	const op = "flatService.GetFlatEdits"
+
+	flat, err := s.repo.GetFlatByID(ctx, flatID, false)
+	if err != nil {
+		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if flat == nil {
+		s.logger.Error("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatNotFound
+	}
+
+	edits, err := s.repo.GetFlatEdits(ctx, flatID)
+	if err != nil {
+		s.logger.Error("Failed to get flat edits", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	return edits, nil
+}
@@ -2,41 +2,86 @@ package flatService
 
 import (
 	"avito/internal/domain/models"
+	"avito/internal/lib/blobstore"
+	"avito/internal/lib/sender"
+	"avito/internal/repositories"
 	"avito/internal/repositories/flatRepo"
+	"avito/internal/repositories/houseRepo"
 	"context"
 	"errors"
+	"io"
 	"log/slog"
+	"time"
 )
 
 type FlatService interface {
-	Create(ctx context.Context, houseID int, flatNumber *int, price, rooms int) (*models.Flat, error)
-	UpdateStatus(ctx context.Context, flatID int, newStatus string, moderatorID string) (*models.Flat, error)
+	Create(ctx context.Context, houseID int, flatNumber *int, price, rooms int, details models.FlatDetails, createdBy string) (*models.Flat, error)
+	GetFlat(ctx context.Context, flatID int, userID string, role models.Role, includeArchived bool) (*models.Flat, error)
+	GetUserFlats(ctx context.Context, userID string, includeArchived bool) ([]models.Flat, error)
+	UpdateStatus(ctx context.Context, flatID int, newStatus string, moderatorID string, comment, declineReason *string) (*models.Flat, error)
+	Resubmit(ctx context.Context, flatID int, userID string) (*models.Flat, error)
+	UpdateFlat(ctx context.Context, flatID int, userID string, changes FlatChanges) (*models.Flat, error)
+	GetFlatEdits(ctx context.Context, flatID int) ([]models.FlatEdit, error)
+	Archive(ctx context.Context, flatID int, userID string, role models.Role) (*models.Flat, error)
+	Restore(ctx context.Context, flatID int, userID string, role models.Role) (*models.Flat, error)
+	GetStatusHistory(ctx context.Context, flatID int) ([]models.StatusChange, error)
+	GetPriceHistory(ctx context.Context, flatID int, userID string, role models.Role) ([]models.PriceChange, error)
+	RenewModeration(ctx context.Context, flatID int, moderatorID string) (*models.Flat, error)
+	GetModerationQueue(ctx context.Context, houseID *int, limit, offset int) ([]models.Flat, error)
+	ClaimNextFlat(ctx context.Context, moderatorID string, houseID *int) (*models.Flat, error)
+	AddPhoto(ctx context.Context, flatID int, userID, kind string, data io.Reader) (*models.Flat, error)
+	DeletePhoto(ctx context.Context, flatID, photoID int, userID string) (*models.Flat, error)
+	ReorderPhotos(ctx context.Context, flatID int, userID string, photoIDs []int) (*models.Flat, error)
+	CheckPhotoAccess(ctx context.Context, flatID int, key, userID string, role models.Role) error
 }
 
 type Service struct {
-	repo        flatRepo.FlatRepo
-	isModerator func(ctx context.Context) bool
-	logger      *slog.Logger
+	repo      flatRepo.FlatRepo
+	houseRepo houseRepo.HouseRepo
+	sender    sender.Sender
+	store     blobstore.BlobStore
+	leaseTTL  time.Duration
+	logger    *slog.Logger
 }
 
-var ErrFlatBeingModerated = errors.New("flat is already being moderated by another user")
+var (
+	ErrFlatBeingModerated = errors.New("flat is already being moderated by another user")
+	ErrFlatNotFound       = errors.New("flat not found")
+	ErrLostRace           = errors.New("flat was changed by another user, reload and retry")
+	ErrLeaseNotHeld       = errors.New("flat is not on moderation by this user")
+	ErrNotFlatOwner       = errors.New("flat belongs to another user")
+)
 
-func NewService(repo flatRepo.FlatRepo, logger *slog.Logger) FlatService {
+// NewService creates the flat service. store keeps the flat photos. leaseTTL is how long a moderator
+// holds a flat "on moderation" before it is returned to the queue, unless the lease is renewed.
+func NewService(repo flatRepo.FlatRepo, houseRepo houseRepo.HouseRepo, sender sender.Sender, store blobstore.BlobStore,
+	leaseTTL time.Duration, logger *slog.Logger) FlatService {
 	return &Service{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		houseRepo: houseRepo,
+		sender:    sender,
+		store:     store,
+		leaseTTL:  leaseTTL,
+		logger:    logger,
 	}
 }
 
-func (s *Service) Create(ctx context.Context, houseID int, flatNumber *int, price, rooms int) (*models.Flat, error) {
+func (s *Service) Create(ctx context.Context, houseID int, flatNumber *int, price, rooms int, details models.FlatDetails, createdBy string) (*models.Flat, error) {
 	const op = "flatService.Create"
 
+	if err := validateDetails(details); err != nil {
+		s.logger.Debug("Invalid flat details", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
 	newFlat := &models.Flat{
-		HouseID:    houseID,
-		FlatNumber: flatNumber,
-		Price:      price,
-		Rooms:      rooms,
-		Status:     "created",
+		HouseID:     houseID,
+		FlatNumber:  flatNumber,
+		Price:       price,
+		Rooms:       rooms,
+		Status:      models.StatusCreated,
+		CreatedBy:   &createdBy,
+		FlatDetails: details,
 	}
 
 	var err error
@@ -55,33 +100,278 @@ func (s *Service) Create(ctx context.Context, houseID int, flatNumber *int, pric
 	return newFlat, nil
 }
 
-func (s *Service) UpdateStatus(ctx context.Context, flatID int, newStatus string, moderatorID string) (*models.Flat, error) {
+// GetFlat returns the flat if the user may see it: moderators see any flat, the owner sees their own flat
+// in any status, everybody else only approved flats. Archived flats are seen only by moderators asking for them.
+func (s *Service) GetFlat(ctx context.Context, flatID int, userID string, role models.Role, includeArchived bool) (*models.Flat, error) {
+	const op = "flatService.GetFlat"
+
+	flat, err := s.repo.GetFlatByID(ctx, flatID, includeArchived && role.CanModerate())
+	if err != nil {
+		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if flat == nil || !canSee(flat, userID, role) {
+		s.logger.Debug("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatNotFound
+	}
+
+	return flat, nil
+}
+
+// GetUserFlats returns the flats created by the user in any status, newest first.
+// The user's archived flats are included if asked, so that they can be restored.
+func (s *Service) GetUserFlats(ctx context.Context, userID string, includeArchived bool) ([]models.Flat, error) {
+	const op = "flatService.GetUserFlats"
+
+	flats, err := s.repo.GetFlatsByOwner(ctx, userID, includeArchived)
+	if err != nil {
+		s.logger.Error("Failed to get user flats", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	return flats, nil
+}
+
+// UpdateStatus moves the flat to newStatus on behalf of the moderator.
+// declineReason is required when declining and must not be set otherwise.
+func (s *Service) UpdateStatus(ctx context.Context, flatID int, newStatus string, moderatorID string, comment, declineReason *string) (*models.Flat, error) {
 	const op = "flatService.UpdateStatus"
 
-	flat, err := s.repo.GetFlatByID(ctx, flatID)
+	if !IsValidStatus(newStatus) {
+		s.logger.Error("Invalid status value", slog.String("op", op), slog.String("status", newStatus))
+		return nil, ErrInvalidStatus
+	}
+
+	if err := checkDeclineReason(newStatus, declineReason); err != nil {
+		s.logger.Error("Invalid decline reason", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	flat, err := s.repo.GetFlatByID(ctx, flatID, false)
 	if err != nil {
 		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
 		return nil, err
 	}
+	if flat == nil {
+		s.logger.Error("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatNotFound
+	}
 
-	if flat.Status == "on moderation" && (flat.ModeratorID == nil || *flat.ModeratorID != moderatorID) {
+	if flat.Status == models.StatusOnModeration && (flat.ModeratorID == nil || *flat.ModeratorID != moderatorID) {
 		s.logger.Error("Flat is already being moderated by another user", slog.String("op", op))
 		return nil, ErrFlatBeingModerated
 	}
 
-	flat.Status = newStatus
-	if newStatus == "on moderation" {
-		flat.ModeratorID = &moderatorID
-	} else {
-		flat.ModeratorID = nil
+	if err := checkTransition(flat.Status, newStatus); err != nil {
+		s.logger.Error("Status transition rejected", slog.String("op", op), slog.Int("flatID", flatID), "error", err)
+		return nil, err
 	}
 
-	updatedFlat, err := s.repo.UpdateFlatStatus(ctx, flatID, newStatus, flat.ModeratorID)
+	update := models.StatusUpdate{
+		FlatID:        flatID,
+		From:          flat.Status,
+		To:            newStatus,
+		ActorID:       moderatorID,
+		Comment:       comment,
+		DeclineReason: declineReason,
+	}
+	if newStatus == models.StatusOnModeration {
+		update.ModeratorID = &moderatorID
+		update.LeaseTTL = s.leaseTTL
+	}
+
+	// The repository re-checks the status and ownership atomically:
+	// the flat could have been changed by another moderator since it was read above.
+	updatedFlat, firstApproval, err := s.repo.UpdateFlatStatus(ctx, update)
 	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrFlatStatusChanged):
+			s.logger.Warn("Lost the race for the flat", slog.String("op", op), slog.Int("flatID", flatID))
+			return nil, ErrLostRace
+		case errors.Is(err, repositories.ErrFlatNotFound):
+			return nil, ErrFlatNotFound
+		}
 		s.logger.Error("Failed to update flat status", slog.String("op", op), "error", err)
 		return nil, err
 	}
 
+	// Approved flats come back through moderation after edits, subscribers hear only about new ones
+	if firstApproval {
+		s.notifySubscribers(ctx, updatedFlat)
+	}
+
 	s.logger.Debug("Flat status updated successfully", slog.String("op", op), slog.Int("flatID", flatID))
 	return updatedFlat, nil
 }
+
+// Resubmit sends a declined flat back to the moderation queue. Only the owner of the flat may do it.
+func (s *Service) Resubmit(ctx context.Context, flatID int, userID string) (*models.Flat, error) {
+	const op = "flatService.Resubmit"
+
+	flat, err := s.repo.GetFlatByID(ctx, flatID, false)
+	if err != nil {
+		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if flat == nil {
+		s.logger.Error("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatNotFound
+	}
+
+	if !isOwner(flat, userID) {
+		s.logger.Error("User is not the owner of the flat", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrNotFlatOwner
+	}
+
+	if flat.Status != models.StatusDeclined {
+		err := &TransitionError{From: flat.Status, To: models.StatusCreated}
+		s.logger.Error("Status transition rejected", slog.String("op", op), slog.Int("flatID", flatID), "error", err)
+		return nil, err
+	}
+
+	updatedFlat, _, err := s.repo.UpdateFlatStatus(ctx, models.StatusUpdate{
+		FlatID:  flatID,
+		From:    models.StatusDeclined,
+		To:      models.StatusCreated,
+		ActorID: userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrFlatStatusChanged):
+			s.logger.Warn("Flat was changed concurrently", slog.String("op", op), slog.Int("flatID", flatID))
+			return nil, ErrLostRace
+		case errors.Is(err, repositories.ErrFlatNotFound):
+			return nil, ErrFlatNotFound
+		}
+		s.logger.Error("Failed to resubmit flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	s.logger.Debug("Flat resubmitted for moderation", slog.String("op", op), slog.Int("flatID", flatID))
+	return updatedFlat, nil
+}
+
+func (s *Service) GetStatusHistory(ctx context.Context, flatID int) ([]models.StatusChange, error) {
+	const op = "flatService.GetStatusHistory"
+
+	flat, err := s.repo.GetFlatByID(ctx, flatID, false)
+	if err != nil {
+		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if flat == nil {
+		s.logger.Error("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatNotFound
+	}
+
+	history, err := s.repo.GetStatusHistory(ctx, flatID)
+	if err != nil {
+		s.logger.Error("Failed to get status history", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// GetPriceHistory returns every price of the flat, oldest first, to whoever may see the flat.
+func (s *Service) GetPriceHistory(ctx context.Context, flatID int, userID string, role models.Role) ([]models.PriceChange, error) {
+	const op = "flatService.GetPriceHistory"
+
+	if _, err := s.GetFlat(ctx, flatID, userID, role, false); err != nil {
+		return nil, err
+	}
+
+	history, err := s.repo.GetPriceHistory(ctx, flatID)
+	if err != nil {
+		s.logger.Error("Failed to get price history", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// RenewModeration extends the moderation lease of a flat held by the moderator.
+func (s *Service) RenewModeration(ctx context.Context, flatID int, moderatorID string) (*models.Flat, error) {
+	const op = "flatService.RenewModeration"
+
+	flat, err := s.repo.RenewModeration(ctx, flatID, moderatorID, s.leaseTTL)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrFlatStatusChanged) {
+			s.logger.Error("Failed to renew moderation", slog.String("op", op), "error", err)
+			return nil, err
+		}
+
+		existing, getErr := s.repo.GetFlatByID(ctx, flatID, false)
+		if getErr != nil {
+			s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", getErr)
+			return nil, getErr
+		}
+		if existing == nil {
+			return nil, ErrFlatNotFound
+		}
+		return nil, ErrLeaseNotHeld
+	}
+
+	s.logger.Debug("Moderation lease renewed", slog.String("op", op), slog.Int("flatID", flatID))
+	return flat, nil
+}
+
+// GetModerationQueue returns flats waiting for moderation, oldest first.
+func (s *Service) GetModerationQueue(ctx context.Context, houseID *int, limit, offset int) ([]models.Flat, error) {
+	const op = "flatService.GetModerationQueue"
+
+	flats, err := s.repo.GetModerationQueue(ctx, houseID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to get moderation queue", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	return flats, nil
+}
+
+// ClaimNextFlat puts the oldest waiting flat on moderation for the moderator.
+// Returns nil if there is nothing to moderate.
+func (s *Service) ClaimNextFlat(ctx context.Context, moderatorID string, houseID *int) (*models.Flat, error) {
+	const op = "flatService.ClaimNextFlat"
+
+	flat, err := s.repo.ClaimNextFlat(ctx, moderatorID, houseID, s.leaseTTL)
+	if err != nil {
+		s.logger.Error("Failed to claim next flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	if flat == nil {
+		s.logger.Debug("Moderation queue is empty", slog.String("op", op))
+		return nil, nil
+	}
+
+	s.logger.Debug("Flat claimed for moderation", slog.String("op", op), slog.Int("flatID", flat.ID))
+	return flat, nil
+}
+
+func isOwner(flat *models.Flat, userID string) bool {
+	return flat.CreatedBy != nil && *flat.CreatedBy == userID
+}
+
+func canSee(flat *models.Flat, userID string, role models.Role) bool {
+	return role.CanModerate() || flat.Status == models.StatusApproved || isOwner(flat, userID)
+}
+
+// notifySubscribers hands the approved flat to the sender for every subscriber of its house.
+// Failures are only logged: notifications must not affect the moderation result.
+func (s *Service) notifySubscribers(ctx context.Context, flat *models.Flat) {
+	const op = "flatService.notifySubscribers"
+
+	emails, err := s.houseRepo.GetSubscribers(ctx, flat.HouseID)
+	if err != nil {
+		s.logger.Error("Failed to get house subscribers", slog.String("op", op), "error", err, slog.Int("houseID", flat.HouseID))
+		return
+	}
+
+	for _, email := range emails {
+		if err := s.sender.Send(ctx, email, *flat); err != nil {
+			s.logger.Error("Failed to queue notification", slog.String("op", op), "error", err,
+				slog.Int("flatID", flat.ID), slog.String("email", email))
+		}
+	}
+}
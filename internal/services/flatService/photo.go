@@ -0,0 +1,305 @@
+package flatService
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/lib/thumbnail"
+	"avito/internal/repositories"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// Limits of flat photos
+const (
+	MaxPhotoSize   = 10 << 20   // bytes
+	MaxPhotoPixels = 20_000_000 // a decoded RGBA image of this size takes 80 MB
+	MaxFlatPhotos  = 30
+	ThumbnailSide  = 320 // px, the longer side
+)
+
+var (
+	ErrInvalidPhoto      = errors.New("invalid photo")
+	ErrPhotoTooLarge     = errors.New("photo is too large")
+	ErrTooManyPhotos     = errors.New("flat has too many photos")
+	ErrPhotoNotFound     = errors.New("photo not found")
+	ErrInvalidPhotoOrder = errors.New("photo order must list every photo of the flat once")
+)
+
+// Accepted image types and the extensions of their files
+var photoTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+}
+
+// AddPhoto attaches an image to the owner's flat after the existing photos. The image type is detected
+// from its content; a thumbnail is stored next to it. Photos are moderated with the flat,
+// so an approved flat goes back to the moderation queue.
+func (s *Service) AddPhoto(ctx context.Context, flatID int, userID, kind string, data io.Reader) (*models.Flat, error) {
+	const op = "flatService.AddPhoto"
+
+	if kind == "" {
+		kind = models.PhotoKindPhoto
+	}
+	if kind != models.PhotoKindPhoto && kind != models.PhotoKindFloorPlan {
+		s.logger.Error("Unknown photo kind", slog.String("op", op), slog.String("kind", kind))
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidPhoto, kind)
+	}
+
+	flat, err := s.editableFlat(ctx, op, flatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Checked again by the repository under the flat lock, here it only spares reading the upload
+	if len(flat.Photos) >= MaxFlatPhotos {
+		s.logger.Error("Too many photos", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrTooManyPhotos
+	}
+
+	body, err := io.ReadAll(io.LimitReader(data, MaxPhotoSize+1))
+	if err != nil {
+		s.logger.Error("Failed to read photo", slog.String("op", op), "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(body) > MaxPhotoSize {
+		s.logger.Error("Photo is too large", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrPhotoTooLarge
+	}
+
+	photo, thumb, err := decodePhoto(body)
+	if err != nil {
+		s.logger.Error("Invalid photo", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, err
+	}
+	photo.FlatID = flatID
+	photo.Kind = kind
+
+	if err := s.storePhoto(ctx, photo, body, thumb); err != nil {
+		s.logger.Error("Failed to store photo", slog.String("op", op), "error", err, slog.Int("flatID", flatID))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	update := models.FlatUpdate{
+		FlatID:      flat.ID,
+		From:        flat.Status,
+		To:          flat.Status,
+		EditorID:    userID,
+		FlatNumber:  flat.FlatNumber,
+		Price:       flat.Price,
+		Rooms:       flat.Rooms,
+		FlatDetails: flat.FlatDetails,
+		Changes:     map[string]models.FieldChange{"photos": {Old: len(flat.Photos), New: len(flat.Photos) + 1}},
+	}
+	requireModeration(flat, &update)
+
+	updatedFlat, err := s.repo.AddFlatPhoto(ctx, photo, update, MaxFlatPhotos)
+	if err != nil {
+		s.deletePhotoFiles(ctx, photo)
+		switch {
+		case errors.Is(err, repositories.ErrFlatStatusChanged):
+			s.logger.Warn("Flat was changed concurrently", slog.String("op", op), slog.Int("flatID", flatID))
+			return nil, ErrLostRace
+		case errors.Is(err, repositories.ErrFlatNotFound):
+			return nil, ErrFlatNotFound
+		case errors.Is(err, repositories.ErrTooManyPhotos):
+			return nil, ErrTooManyPhotos
+		}
+		s.logger.Error("Failed to add photo", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	s.logger.Debug("Photo added", slog.String("op", op), slog.Int("flatID", flatID), slog.Int("photoID", photo.ID),
+		slog.String("status", updatedFlat.Status))
+	return updatedFlat, nil
+}
+
+// DeletePhoto removes the photo from the owner's flat. Removing a photo does not need moderation.
+func (s *Service) DeletePhoto(ctx context.Context, flatID, photoID int, userID string) (*models.Flat, error) {
+	const op = "flatService.DeletePhoto"
+
+	if _, err := s.editableFlat(ctx, op, flatID, userID); err != nil {
+		return nil, err
+	}
+
+	photo, err := s.repo.DeleteFlatPhoto(ctx, flatID, photoID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPhotoNotFound) {
+			return nil, ErrPhotoNotFound
+		}
+		s.logger.Error("Failed to delete photo", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	s.deletePhotoFiles(ctx, photo)
+
+	flat, err := s.repo.GetFlatByID(ctx, flatID, false)
+	if err != nil {
+		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if flat == nil {
+		return nil, ErrFlatNotFound
+	}
+
+	return flat, nil
+}
+
+// ReorderPhotos sets the display order of the owner's flat photos; photoIDs must list every photo once.
+func (s *Service) ReorderPhotos(ctx context.Context, flatID int, userID string, photoIDs []int) (*models.Flat, error) {
+	const op = "flatService.ReorderPhotos"
+
+	flat, err := s.editableFlat(ctx, op, flatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !samePhotos(flat.Photos, photoIDs) {
+		s.logger.Error("Invalid photo order", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrInvalidPhotoOrder
+	}
+
+	updatedFlat, err := s.repo.ReorderFlatPhotos(ctx, flatID, photoIDs)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPhotoNotFound) {
+			s.logger.Warn("Photos were changed concurrently", slog.String("op", op), slog.Int("flatID", flatID))
+			return nil, ErrLostRace
+		}
+		s.logger.Error("Failed to reorder photos", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	return updatedFlat, nil
+}
+
+// CheckPhotoAccess tells whether the user may download the file of a flat photo or of its thumbnail.
+// Photos are visible like their flat: the photos of a flat that is not approved are seen only by its owner and moderators.
+func (s *Service) CheckPhotoAccess(ctx context.Context, flatID int, key, userID string, role models.Role) error {
+	const op = "flatService.CheckPhotoAccess"
+
+	flat, err := s.GetFlat(ctx, flatID, userID, role, true)
+	if err != nil {
+		return err
+	}
+	for _, photo := range flat.Photos {
+		if photo.Key == key || photo.ThumbnailKey == key {
+			return nil
+		}
+	}
+
+	s.logger.Debug("Photo not found", slog.String("op", op), slog.Int("flatID", flatID), slog.String("key", key))
+	return ErrPhotoNotFound
+}
+
+// editableFlat returns the flat if the user owns it and it is not being moderated
+func (s *Service) editableFlat(ctx context.Context, op string, flatID int, userID string) (*models.Flat, error) {
+	flat, err := s.repo.GetFlatByID(ctx, flatID, false)
+	if err != nil {
+		s.logger.Error("Failed to retrieve flat", slog.String("op", op), "error", err)
+		return nil, err
+	}
+	if flat == nil {
+		s.logger.Error("Flat not found", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatNotFound
+	}
+	if !isOwner(flat, userID) {
+		s.logger.Error("User is not the owner of the flat", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrNotFlatOwner
+	}
+	if flat.Status == models.StatusOnModeration {
+		s.logger.Error("Flat is being moderated", slog.String("op", op), slog.Int("flatID", flatID))
+		return nil, ErrFlatBeingModerated
+	}
+	return flat, nil
+}
+
+// decodePhoto checks that body is a JPEG or PNG image of a sane size and makes its thumbnail
+func decodePhoto(body []byte) (*models.FlatPhoto, []byte, error) {
+	contentType := http.DetectContentType(body)
+	if _, ok := photoTypes[contentType]; !ok {
+		return nil, nil, fmt.Errorf("%w: unsupported type %s", ErrInvalidPhoto, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidPhoto, err)
+	}
+	// Checked before decoding: a small file may declare a huge image
+	if cfg.Width*cfg.Height > MaxPhotoPixels {
+		return nil, nil, fmt.Errorf("%w: %dx%d pixels", ErrPhotoTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidPhoto, err)
+	}
+
+	var thumb bytes.Buffer
+	small := thumbnail.Make(img, ThumbnailSide)
+	if contentType == "image/png" {
+		err = png.Encode(&thumb, small)
+	} else {
+		err = jpeg.Encode(&thumb, small, &jpeg.Options{Quality: 80})
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	return &models.FlatPhoto{ContentType: contentType, Size: int64(len(body))}, thumb.Bytes(), nil
+}
+
+// storePhoto puts the photo and its thumbnail into the blob store under random keys
+func (s *Service) storePhoto(ctx context.Context, photo *models.FlatPhoto, body, thumb []byte) error {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return err
+	}
+	base := fmt.Sprintf("flats/%d/%s", photo.FlatID, hex.EncodeToString(name))
+	ext := photoTypes[photo.ContentType]
+	photo.Key = base + "." + ext
+	photo.ThumbnailKey = base + "_thumb." + ext
+
+	var err error
+	if photo.URL, err = s.store.Put(ctx, photo.Key, photo.ContentType, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	if photo.ThumbnailURL, err = s.store.Put(ctx, photo.ThumbnailKey, photo.ContentType, bytes.NewReader(thumb)); err != nil {
+		s.deletePhotoFiles(ctx, photo)
+		return err
+	}
+	return nil
+}
+
+// deletePhotoFiles removes the photo files from the blob store. Failures leave orphaned files and are only logged.
+func (s *Service) deletePhotoFiles(ctx context.Context, photo *models.FlatPhoto) {
+	const op = "flatService.deletePhotoFiles"
+
+	for _, key := range []string{photo.Key, photo.ThumbnailKey} {
+		if err := s.store.Delete(ctx, key); err != nil {
+			s.logger.Warn("Failed to delete photo file", slog.String("op", op), slog.String("key", key), "error", err)
+		}
+	}
+}
+
+func samePhotos(photos []models.FlatPhoto, photoIDs []int) bool {
+	if len(photos) != len(photoIDs) {
+		return false
+	}
+	ids := make(map[int]bool, len(photos))
+	for _, photo := range photos {
+		ids[photo.ID] = true
+	}
+	for _, id := range photoIDs {
+		if !ids[id] {
+			return false
+		}
+		delete(ids, id)
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package flatService
+
+import (
+	"avito/internal/repositories/flatRepo"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LeaseReaper periodically returns flats with an expired moderation lease to "created",
+// so a moderator who walked away does not lock them forever.
+type LeaseReaper struct {
+	repo     flatRepo.FlatRepo
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewLeaseReaper(repo flatRepo.FlatRepo, interval time.Duration, logger *slog.Logger) *LeaseReaper {
+	return &LeaseReaper{
+		repo:     repo,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run releases expired leases until ctx is cancelled.
+func (r *LeaseReaper) Run(ctx context.Context) {
+	const op = "flatService.LeaseReaper.Run"
+
+	r.logger.Info("Moderation lease reaper started", slog.String("op", op), slog.Duration("interval", r.interval))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Moderation lease reaper stopped", slog.String("op", op))
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := r.ReleaseExpired(ctx); err != nil {
+			r.logger.Error("Failed to release expired moderations", slog.String("op", op), "error", err)
+		}
+	}
+}
+
+// ReleaseExpired runs a single release pass and reports how many flats were returned to the queue.
+func (r *LeaseReaper) ReleaseExpired(ctx context.Context) (int, error) {
+	const op = "flatService.LeaseReaper.ReleaseExpired"
+
+	flats, err := r.repo.ReleaseExpiredModerations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, flat := range flats {
+		r.logger.Info("Moderation lease expired, flat returned to queue", slog.String("op", op), slog.Int("flatID", flat.ID))
+	}
+	return len(flats), nil
+}
@@ -0,0 +1,96 @@
+package flatService
+
+import (
+	"avito/internal/domain/models"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidStatus        = errors.New("invalid flat status")
+	ErrInvalidTransition    = errors.New("flat status transition is not allowed")
+	ErrInvalidDeclineReason = errors.New("invalid decline reason")
+)
+
+// TransitionError describes a rejected status change. It matches ErrInvalidTransition with errors.Is.
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("flat status transition %q -> %q is not allowed", e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// transitions is the moderation state machine: status -> statuses it may be moved to.
+//
+//	created -> on moderation -> approved | declined
+//	on moderation -> created            (moderator releases the flat)
+//	approved | declined -> on moderation (re-moderation)
+//
+// The owner of a declined flat moves it back to created with Service.Resubmit, that is not a moderator transition.
+var transitions = map[string][]string{
+	models.StatusCreated:      {models.StatusOnModeration},
+	models.StatusOnModeration: {models.StatusApproved, models.StatusDeclined, models.StatusCreated},
+	models.StatusApproved:     {models.StatusOnModeration},
+	models.StatusDeclined:     {models.StatusOnModeration},
+}
+
+var declineReasons = map[string]bool{
+	models.DeclineReasonIncorrectData: true,
+	models.DeclineReasonWrongPrice:    true,
+	models.DeclineReasonDuplicate:     true,
+	models.DeclineReasonProhibited:    true,
+	models.DeclineReasonOther:         true,
+}
+
+// IsValidDeclineReason reports whether reason is one of the known decline reason codes.
+func IsValidDeclineReason(reason string) bool {
+	return declineReasons[reason]
+}
+
+// IsValidStatus reports whether status is one of the known flat statuses.
+func IsValidStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// CanTransition reports whether a flat in status from may be moved to status to.
+func CanTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTransition(from, to string) error {
+	if !IsValidStatus(to) {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, to)
+	}
+	if !CanTransition(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+func checkDeclineReason(status string, reason *string) error {
+	if status != models.StatusDeclined {
+		if reason != nil {
+			return fmt.Errorf("%w: reason is only allowed when declining", ErrInvalidDeclineReason)
+		}
+		return nil
+	}
+	if reason == nil {
+		return fmt.Errorf("%w: reason is required when declining", ErrInvalidDeclineReason)
+	}
+	if !IsValidDeclineReason(*reason) {
+		return fmt.Errorf("%w: %q", ErrInvalidDeclineReason, *reason)
+	}
+	return nil
+}
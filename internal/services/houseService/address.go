@@ -0,0 +1,53 @@
+package houseService
+
+import (
+	"avito/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var ErrDuplicateAddress = errors.New("house with this address already exists")
+
+// DuplicateAddressError points at the house that already has the address
+type DuplicateAddressError struct {
+	HouseID int
+}
+
+func (e *DuplicateAddressError) Error() string {
+	return fmt.Sprintf("%s: house %d", ErrDuplicateAddress, e.HouseID)
+}
+
+func (e *DuplicateAddressError) Unwrap() error {
+	return ErrDuplicateAddress
+}
+
+// checkDuplicate returns DuplicateAddressError if a house other than houseID has the normalized address
+func (s *Service) checkDuplicate(ctx context.Context, normalized string, houseID int) error {
+	const op = "houseService.checkDuplicate"
+
+	existing, err := s.repo.FindHouseByAddress(ctx, normalized)
+	if err != nil {
+		s.logger.Error("Failed to look for duplicate house", slog.String("op", op), "error", err)
+		return err
+	}
+	if existing != nil && existing.ID != houseID {
+		s.logger.Warn("Duplicate house address", slog.String("op", op), slog.String("address", normalized), slog.Int("houseID", existing.ID))
+		return &DuplicateAddressError{HouseID: existing.ID}
+	}
+
+	return nil
+}
+
+// duplicateAfterRace turns ErrHouseExists, returned when a concurrent request saved the same address
+// between the check and the write, into DuplicateAddressError
+func (s *Service) duplicateAfterRace(ctx context.Context, err error, normalized string, houseID int) error {
+	if !errors.Is(err, repositories.ErrHouseExists) {
+		return err
+	}
+	if dupErr := s.checkDuplicate(ctx, normalized, houseID); dupErr != nil {
+		return dupErr
+	}
+	return err
+}
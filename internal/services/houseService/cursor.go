@@ -0,0 +1,42 @@
+package houseService
+
+import (
+	"avito/internal/domain/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor makes an opaque next_cursor pointing after the flat
+func encodeCursor(flat models.Flat, sortBy string, desc bool) string {
+	cursor := models.FlatCursor{SortBy: sortBy, Desc: desc, ID: flat.ID}
+	switch sortBy {
+	case models.FlatSortPrice:
+		cursor.Value = flat.Price
+	case models.FlatSortRooms:
+		cursor.Value = flat.Rooms
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a cursor and checks it was issued for the same ordering
+func decodeCursor(s, sortBy string, desc bool) (*models.FlatCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor models.FlatCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.SortBy != sortBy || cursor.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
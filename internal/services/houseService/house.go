@@ -2,16 +2,41 @@ package houseService
 
 import (
 	"avito/internal/domain/models"
+	"avito/internal/lib/address"
+	"avito/internal/repositories"
 	"avito/internal/repositories/houseRepo"
 	"context"
 	"errors"
 	"log/slog"
+	"net/mail"
 )
 
 type HouseService interface {
-	Create(ctx context.Context, address string, yearBuilt int, builder *string) (*models.House, error)
+	Create(ctx context.Context, address string, yearBuilt int, builder *string, location *models.GeoPoint) (*models.House, error)
 	Subscribe(ctx context.Context, houseID int, email string) error
-	GetFlatsByHouseID(ctx context.Context, houseID int, role string) ([]models.Flat, error)
+	GetFlatsByHouseID(ctx context.Context, houseID int, userID string, role models.Role, includeArchived bool, listing FlatListing) ([]models.Flat, string, error)
+	Archive(ctx context.Context, houseID int) (*models.House, error)
+	Restore(ctx context.Context, houseID int) (*models.House, error)
+	GetHouse(ctx context.Context, houseID int, role models.Role, includeArchived bool) (*models.House, error)
+	ListHouses(ctx context.Context, filter models.HouseFilter, role models.Role) ([]models.House, error)
+	UpdateHouse(ctx context.Context, houseID int, changes HouseChanges) (*models.House, error)
+}
+
+// HouseChanges are the fields a moderator wants to change; nil fields are left as they are.
+// An empty Builder removes the developer.
+type HouseChanges struct {
+	Address   *string
+	YearBuilt *int
+	Builder   *string
+}
+
+// FlatListing asks for a page of flats of a house. Cursor is the next_cursor of the previous page
+// and must come with the same SortBy and Desc.
+type FlatListing struct {
+	SortBy string
+	Desc   bool
+	Limit  int
+	Cursor string
 }
 
 type Service struct {
@@ -25,17 +50,36 @@ func NewService(repo houseRepo.HouseRepo, logger *slog.Logger) HouseService {
 	return &Service{repo: repo, logger: logger}
 }
 
-func (s *Service) Create(ctx context.Context, address string, yearBuilt int, builder *string) (*models.House, error) {
+// Create saves a new house. A house with the same normalized address is rejected with DuplicateAddressError.
+// The location is optional.
+func (s *Service) Create(ctx context.Context, rawAddress string, yearBuilt int, builder *string, location *models.GeoPoint) (*models.House, error) {
 	const op = "houseService.Create"
 
+	normalized := address.Normalize(rawAddress)
+	if normalized == "" {
+		s.logger.Error("Validation error: address is empty", slog.String("op", op))
+		return nil, ErrValidation
+	}
+	if location != nil && !location.Valid() {
+		s.logger.Error("Validation error: invalid location", slog.String("op", op), slog.Float64("lat", location.Lat), slog.Float64("lon", location.Lon))
+		return nil, ErrValidation
+	}
+	if err := s.checkDuplicate(ctx, normalized, 0); err != nil {
+		return nil, err
+	}
+
 	newHouse := &models.House{
-		Address:   address,
-		YearBuilt: yearBuilt,
-		Builder:   builder,
+		Address:           rawAddress,
+		NormalizedAddress: normalized,
+		YearBuilt:         yearBuilt,
+		Builder:           builder,
+	}
+	if location != nil {
+		newHouse.Latitude, newHouse.Longitude = &location.Lat, &location.Lon
 	}
 	if err := s.repo.CreateHouse(ctx, newHouse); err != nil {
 		s.logger.Error("Failed to create house", slog.String("op", op), "error", err)
-		return nil, err
+		return nil, s.duplicateAfterRace(ctx, err, normalized, 0)
 	}
 
 	s.logger.Debug("House created successfully", slog.String("op", op), slog.Int("houseID", newHouse.ID))
@@ -50,6 +94,10 @@ func (s *Service) Subscribe(ctx context.Context, houseID int, email string) erro
 		s.logger.Error("Validation error: email is empty", slog.String("op", op))
 		return ErrValidation
 	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		s.logger.Error("Validation error: invalid email", slog.String("op", op), slog.String("email", email))
+		return ErrValidation
+	}
 
 	if err := s.repo.SubscribeToHouse(ctx, houseID, email); err != nil {
 		s.logger.Error("Failed to subscribe to house", slog.String("op", op), "error", err, slog.Int("houseID", houseID), slog.String("email", email))
@@ -60,15 +108,161 @@ func (s *Service) Subscribe(ctx context.Context, houseID int, email string) erro
 	return nil
 }
 
-func (s *Service) GetFlatsByHouseID(ctx context.Context, houseID int, role string) ([]models.Flat, error) {
+// GetFlatsByHouseID returns flats of the house: all of them for moderators,
+// approved ones and the user's own flats for everybody else.
+// Archived flats and flats of an archived house are returned only to moderators asking for them.
+// The returned cursor is empty on the last page.
+func (s *Service) GetFlatsByHouseID(ctx context.Context, houseID int, userID string, role models.Role, includeArchived bool, listing FlatListing) ([]models.Flat, string, error) {
 	const op = "houseService.GetFlatsByHouseID"
 
-	flats, err := s.repo.GetFlatsByHouseID(ctx, houseID, role)
+	if listing.SortBy == "" {
+		listing.SortBy = models.FlatSortID
+	}
+	switch listing.SortBy {
+	case models.FlatSortID, models.FlatSortPrice, models.FlatSortRooms:
+	default:
+		s.logger.Error("Validation error: unknown sort key", slog.String("op", op), slog.String("sort", listing.SortBy))
+		return nil, "", ErrValidation
+	}
+	if listing.Limit <= 0 {
+		s.logger.Error("Validation error: invalid limit", slog.String("op", op), slog.Int("limit", listing.Limit))
+		return nil, "", ErrValidation
+	}
+
+	// One extra flat tells whether there is a next page
+	page := models.FlatPage{SortBy: listing.SortBy, Desc: listing.Desc, Limit: listing.Limit + 1}
+	if listing.Cursor != "" {
+		after, err := decodeCursor(listing.Cursor, listing.SortBy, listing.Desc)
+		if err != nil {
+			s.logger.Error("Validation error: invalid cursor", slog.String("op", op), slog.String("cursor", listing.Cursor))
+			return nil, "", err
+		}
+		page.After = after
+	}
+
+	flats, err := s.repo.GetFlatsByHouseID(ctx, houseID, userID, role, includeArchived && role.CanModerate(), page)
 	if err != nil {
 		s.logger.Error("Failed to get flats by house ID", slog.String("op", op), "error", err, slog.Int("houseID", houseID))
+		return nil, "", err
+	}
+
+	var next string
+	if len(flats) > listing.Limit {
+		flats = flats[:listing.Limit]
+		next = encodeCursor(flats[len(flats)-1], listing.SortBy, listing.Desc)
+	}
+
+	s.logger.Debug("Returning flats", slog.String("op", op), slog.Int("houseID", houseID), slog.Int("count", len(flats)))
+	return flats, next, nil
+}
+
+// Archive hides the house and its flats from every read path
+func (s *Service) Archive(ctx context.Context, houseID int) (*models.House, error) {
+	const op = "houseService.Archive"
+
+	house, err := s.repo.SetHouseArchived(ctx, houseID, true)
+	if err != nil {
+		s.logger.Error("Failed to archive house", slog.String("op", op), "error", err, slog.Int("houseID", houseID))
+		return nil, err
+	}
+
+	s.logger.Debug("House archived", slog.String("op", op), slog.Int("houseID", houseID))
+	return house, nil
+}
+
+func (s *Service) Restore(ctx context.Context, houseID int) (*models.House, error) {
+	const op = "houseService.Restore"
+
+	house, err := s.repo.SetHouseArchived(ctx, houseID, false)
+	if err != nil {
+		s.logger.Error("Failed to restore house", slog.String("op", op), "error", err, slog.Int("houseID", houseID))
 		return nil, err
 	}
 
-	s.logger.Debug("Returning all flats", slog.String("op", op), slog.Int("houseID", houseID))
-	return flats, nil
+	s.logger.Debug("House restored", slog.String("op", op), slog.Int("houseID", houseID))
+	return house, nil
+}
+
+// GetHouse returns the house. Archived houses are returned only to moderators asking for them.
+func (s *Service) GetHouse(ctx context.Context, houseID int, role models.Role, includeArchived bool) (*models.House, error) {
+	const op = "houseService.GetHouse"
+
+	house, err := s.repo.GetHouseByID(ctx, houseID, includeArchived && role.CanModerate())
+	if err != nil {
+		s.logger.Error("Failed to get house", slog.String("op", op), "error", err, slog.Int("houseID", houseID))
+		return nil, err
+	}
+	if house == nil {
+		s.logger.Debug("House not found", slog.String("op", op), slog.Int("houseID", houseID))
+		return nil, repositories.ErrHouseNotFound
+	}
+
+	return house, nil
+}
+
+// ListHouses returns a page of houses matching the filter. Archived houses are listed only for moderators.
+func (s *Service) ListHouses(ctx context.Context, filter models.HouseFilter, role models.Role) ([]models.House, error) {
+	const op = "houseService.ListHouses"
+
+	if filter.YearFrom != nil && filter.YearTo != nil && *filter.YearFrom > *filter.YearTo {
+		s.logger.Error("Validation error: year range is empty", slog.String("op", op))
+		return nil, ErrValidation
+	}
+	filter.IncludeArchived = filter.IncludeArchived && role.CanModerate()
+
+	houses, err := s.repo.ListHouses(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to list houses", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	return houses, nil
+}
+
+func (s *Service) UpdateHouse(ctx context.Context, houseID int, changes HouseChanges) (*models.House, error) {
+	const op = "houseService.UpdateHouse"
+
+	if changes.Address != nil && address.Normalize(*changes.Address) == "" {
+		s.logger.Error("Validation error: address is empty", slog.String("op", op))
+		return nil, ErrValidation
+	}
+	if changes.YearBuilt != nil && *changes.YearBuilt < 0 {
+		s.logger.Error("Validation error: invalid year", slog.String("op", op), slog.Int("year", *changes.YearBuilt))
+		return nil, ErrValidation
+	}
+
+	house, err := s.repo.GetHouseByID(ctx, houseID, false)
+	if err != nil {
+		s.logger.Error("Failed to get house", slog.String("op", op), "error", err, slog.Int("houseID", houseID))
+		return nil, err
+	}
+	if house == nil {
+		s.logger.Error("House not found", slog.String("op", op), slog.Int("houseID", houseID))
+		return nil, repositories.ErrHouseNotFound
+	}
+
+	if changes.Address != nil {
+		house.Address = *changes.Address
+		house.NormalizedAddress = address.Normalize(house.Address)
+		if err := s.checkDuplicate(ctx, house.NormalizedAddress, houseID); err != nil {
+			return nil, err
+		}
+	}
+	if changes.YearBuilt != nil {
+		house.YearBuilt = *changes.YearBuilt
+	}
+	if changes.Builder != nil {
+		house.Builder = changes.Builder
+		if *changes.Builder == "" {
+			house.Builder = nil
+		}
+	}
+
+	if err := s.repo.UpdateHouse(ctx, house); err != nil {
+		s.logger.Error("Failed to update house", slog.String("op", op), "error", err, slog.Int("houseID", houseID))
+		return nil, s.duplicateAfterRace(ctx, err, house.NormalizedAddress, houseID)
+	}
+
+	s.logger.Debug("House updated", slog.String("op", op), slog.Int("houseID", houseID))
+	return house, nil
 }
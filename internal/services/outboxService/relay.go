@@ -0,0 +1,82 @@
+package outboxService
+
+import (
+	"avito/internal/lib/publisher"
+	"avito/internal/repositories/outboxRepo"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Relay polls the outbox and hands unpublished events to the publisher in order.
+// An event is marked as published only after Publish succeeds, so a crash between
+// the two steps leads to a redelivery rather than a lost event.
+type Relay struct {
+	repo      outboxRepo.OutboxRepo
+	publisher publisher.Publisher
+	interval  time.Duration
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewRelay(repo outboxRepo.OutboxRepo, publisher publisher.Publisher, interval time.Duration, batchSize int, logger *slog.Logger) *Relay {
+	return &Relay{
+		repo:      repo,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Run processes the outbox until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	const op = "outboxService.Relay.Run"
+
+	r.logger.Info("Outbox relay started", slog.String("op", op), slog.Duration("interval", r.interval))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.ProcessBatch(ctx); err != nil {
+			r.logger.Error("Failed to process outbox batch", slog.String("op", op), "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped", slog.String("op", op))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch publishes one batch of events and returns how many of them were published.
+// It stops at the first failure to keep the events order.
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	const op = "outboxService.Relay.ProcessBatch"
+
+	events, err := r.repo.FetchUnpublished(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range events {
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			r.logger.Warn("Failed to publish event", slog.String("op", op), slog.Int64("event_id", event.ID), "error", err)
+			return published, err
+		}
+
+		if err := r.repo.MarkPublished(ctx, event.ID); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	if published > 0 {
+		r.logger.Debug("Outbox events published", slog.String("op", op), slog.Int("count", published))
+	}
+	return published, nil
+}
@@ -0,0 +1,114 @@
+package searchService
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/repositories/searchRepo"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+type SearchService interface {
+	SearchFlats(ctx context.Context, search models.FlatSearch) ([]models.Flat, error)
+	SearchHouses(ctx context.Context, text string, limit, offset int) ([]models.House, error)
+	NearbyHouses(ctx context.Context, center models.GeoPoint, radiusM float64, limit int) ([]models.NearbyHouse, error)
+}
+
+type Service struct {
+	repo   searchRepo.SearchRepo
+	logger *slog.Logger
+}
+
+var ErrValidation = errors.New("validation error")
+
+// MaxNearbyRadiusM bounds the nearby search to what a map viewport can show
+const MaxNearbyRadiusM = 50000
+
+func NewService(repo searchRepo.SearchRepo, logger *slog.Logger) SearchService {
+	return &Service{repo: repo, logger: logger}
+}
+
+// SearchFlats returns a page of flats matching the search across all houses
+func (s *Service) SearchFlats(ctx context.Context, search models.FlatSearch) ([]models.Flat, error) {
+	const op = "searchService.SearchFlats"
+
+	if search.PriceFrom != nil && search.PriceTo != nil && *search.PriceFrom > *search.PriceTo {
+		s.logger.Error("Validation error: price range is empty", slog.String("op", op))
+		return nil, ErrValidation
+	}
+	if search.YearFrom != nil && search.YearTo != nil && *search.YearFrom > *search.YearTo {
+		s.logger.Error("Validation error: year range is empty", slog.String("op", op))
+		return nil, ErrValidation
+	}
+	if search.AreaFrom != nil && search.AreaTo != nil && *search.AreaFrom > *search.AreaTo {
+		s.logger.Error("Validation error: area range is empty", slog.String("op", op))
+		return nil, ErrValidation
+	}
+	if search.FloorFrom != nil && search.FloorTo != nil && *search.FloorFrom > *search.FloorTo {
+		s.logger.Error("Validation error: floor range is empty", slog.String("op", op))
+		return nil, ErrValidation
+	}
+	if search.Renovation != nil && !models.IsRenovation(*search.Renovation) {
+		s.logger.Error("Validation error: unknown renovation type", slog.String("op", op))
+		return nil, ErrValidation
+	}
+	for _, rooms := range search.Rooms {
+		if rooms <= 0 {
+			s.logger.Error("Validation error: invalid rooms", slog.String("op", op), slog.Int("rooms", rooms))
+			return nil, ErrValidation
+		}
+	}
+
+	flats, err := s.repo.SearchFlats(ctx, search)
+	if err != nil {
+		s.logger.Error("Failed to search flats", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	s.logger.Debug("Flats found", slog.String("op", op), slog.Int("count", len(flats)))
+	return flats, nil
+}
+
+// SearchHouses finds houses by address text, most relevant first
+func (s *Service) SearchHouses(ctx context.Context, text string, limit, offset int) ([]models.House, error) {
+	const op = "searchService.SearchHouses"
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		s.logger.Error("Validation error: search text is empty", slog.String("op", op))
+		return nil, ErrValidation
+	}
+
+	houses, err := s.repo.SearchHouses(ctx, text, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to search houses", slog.String("op", op), "error", err, slog.String("text", text))
+		return nil, err
+	}
+
+	s.logger.Debug("Houses found", slog.String("op", op), slog.Int("count", len(houses)))
+	return houses, nil
+}
+
+// NearbyHouses returns houses within radiusM meters of the center, nearest first
+func (s *Service) NearbyHouses(ctx context.Context, center models.GeoPoint, radiusM float64, limit int) ([]models.NearbyHouse, error) {
+	const op = "searchService.NearbyHouses"
+
+	if !center.Valid() {
+		s.logger.Error("Validation error: invalid center", slog.String("op", op), slog.Float64("lat", center.Lat), slog.Float64("lon", center.Lon))
+		return nil, ErrValidation
+	}
+	if radiusM <= 0 || radiusM > MaxNearbyRadiusM {
+		s.logger.Error("Validation error: invalid radius", slog.String("op", op), slog.Float64("radius_m", radiusM))
+		return nil, ErrValidation
+	}
+
+	houses, err := s.repo.NearbyHouses(ctx, center, radiusM, limit)
+	if err != nil {
+		s.logger.Error("Failed to find nearby houses", slog.String("op", op), "error", err)
+		return nil, err
+	}
+
+	s.logger.Debug("Nearby houses found", slog.String("op", op), slog.Int("count", len(houses)))
+	return houses, nil
+}
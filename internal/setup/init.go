@@ -5,36 +5,58 @@ import (
 	"avito/internal/handlers/authHandler"
 	"avito/internal/handlers/flatHandler"
 	"avito/internal/handlers/houseHandler"
+	"avito/internal/handlers/searchHandler"
+	"avito/internal/lib/blobstore"
+	"avito/internal/lib/loginguard"
+	"avito/internal/lib/sender"
 	"avito/internal/repositories/authRepo"
 	"avito/internal/repositories/flatRepo"
 	"avito/internal/repositories/houseRepo"
+	"avito/internal/repositories/searchRepo"
 	"avito/internal/services/authService"
 	"avito/internal/services/flatService"
 	"avito/internal/services/houseService"
+	"avito/internal/services/searchService"
 	"database/sql"
 	"log/slog"
+	"time"
 )
 
 func InitLayers(
 	conn *sql.DB,
 	cfg *config.Config,
+	notifier sender.Sender,
+	store blobstore.BlobStore,
+	denylist *authService.Denylist,
 	log *slog.Logger,
 ) (
 	authHandler.AuthHandler,
 	houseHandler.HouseHandler,
 	flatHandler.FlatHandler,
+	searchHandler.SearchHandler,
 ) {
 	authR := authRepo.NewRepository(conn, log)
 	houseR := houseRepo.NewRepository(conn, log)
 	flatR := flatRepo.NewRepository(conn, log)
+	searchR := searchRepo.NewRepository(conn, log)
 
-	authS := authService.NewService(authR, cfg.Auth.JWTSecret, log)
+	lockout := cfg.Auth.Lockout
+	guard := loginguard.NewGuard(
+		loginguard.NewMemoryStore(time.Now),
+		loginguard.Policy{FreeAttempts: lockout.AccountAttempts, BaseDelay: lockout.BaseDelay, MaxDelay: lockout.MaxDelay, Window: lockout.Window},
+		loginguard.Policy{FreeAttempts: lockout.IPAttempts, BaseDelay: lockout.BaseDelay, MaxDelay: lockout.MaxDelay, Window: lockout.Window},
+		time.Now,
+	)
+
+	authS := authService.NewService(authR, denylist, guard, cfg.Auth.JWTSecret, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL, cfg.Auth.InviteTTL, log)
 	houseS := houseService.NewService(houseR, log)
-	flatS := flatService.NewService(flatR, log)
+	flatS := flatService.NewService(flatR, houseR, notifier, store, cfg.Moderation.LeaseTTL, log)
+	searchS := searchService.NewService(searchR, log)
 
 	authH := authHandler.NewHandler(authS, log)
 	houseH := houseHandler.NewHandler(houseS, log)
 	flatH := flatHandler.NewHandler(flatS, log)
+	searchH := searchHandler.NewHandler(searchS, log)
 
-	return authH, houseH, flatH
+	return authH, houseH, flatH, searchH
 }
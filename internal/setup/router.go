@@ -2,18 +2,24 @@ package setup
 
 import (
 	"avito/internal/custommiddleware"
+	"avito/internal/domain/models"
 	"avito/internal/handlers/authHandler"
 	"avito/internal/handlers/flatHandler"
 	"avito/internal/handlers/houseHandler"
+	"avito/internal/handlers/searchHandler"
+	"avito/internal/lib/blobstore"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"log/slog"
+	"net/http"
 )
 
 func SetupRouter(
 	authH authHandler.AuthHandler,
 	houseH houseHandler.HouseHandler,
 	flatH flatHandler.FlatHandler,
+	searchH searchHandler.SearchHandler,
+	photos *blobstore.LocalStore,
 	logger *slog.Logger,
 ) *chi.Mux {
 	r := chi.NewRouter()
@@ -26,21 +32,66 @@ func SetupRouter(
 	r.Get("/dummyLogin", authH.DummyLogin)
 	r.Post("/register", authH.Register)
 	r.Post("/login", authH.Login)
+	r.Post("/token/refresh", authH.RefreshToken)
 
-	// Protected routes moderationsOnly
+	// Protected routes adminOnly
 	r.Group(func(r chi.Router) {
 		r.Use(custommiddleware.AuthMiddleware(authH, logger))
-		r.Use(custommiddleware.RoleMiddleware([]string{"moderator"}, logger))
+		r.Use(custommiddleware.RoleMiddleware([]models.Role{models.RoleAdmin}, logger))
+
+		r.Get("/admin/users", authH.ListUsers)
+		r.Put("/admin/users/{id}/role", authH.SetUserRole)
+		r.Post("/admin/users/{id}/disable", authH.DisableUser)
+		r.Post("/admin/users/{id}/enable", authH.EnableUser)
+		r.Post("/admin/users/{id}/logout", authH.LogoutUser)
+	})
+	// Protected routes moderationsOnly, admins have every moderator permission
+	r.Group(func(r chi.Router) {
+		r.Use(custommiddleware.AuthMiddleware(authH, logger))
+		r.Use(custommiddleware.RoleMiddleware([]models.Role{models.RoleModerator, models.RoleAdmin}, logger))
 
 		r.Post("/house/create", houseH.Create)
+		r.Patch("/house/{id}", houseH.Update)
+		r.Post("/house/{id}/archive", houseH.Archive)
+		r.Post("/house/{id}/restore", houseH.Restore)
 		r.Post("/flat/update", flatH.Update)
+		r.Get("/flat/{id}/history", flatH.GetStatusHistory)
+		r.Get("/flat/{id}/edits", flatH.GetEdits)
+		r.Post("/flat/{id}/renew", flatH.RenewModeration)
+		r.Get("/moderation/queue", flatH.ModerationQueue)
+		r.Post("/moderation/claim-next", flatH.ClaimNext)
+		r.Post("/invites", authH.CreateInvite)
 	})
 	// Protected routes authOnly
 	r.Group(func(r chi.Router) {
 		r.Use(custommiddleware.AuthMiddleware(authH, logger))
 
 		r.Get("/house/{id}", houseH.GetFlatsByHouseID)
+		r.Get("/house/{id}/info", houseH.Info)
+		r.Get("/houses", houseH.List)
+		r.Get("/houses/search", searchH.SearchHouses)
+		r.Get("/houses/nearby", searchH.NearbyHouses)
+		r.Post("/house/{id}/subscribe", houseH.Subscribe)
 		r.Post("/flat/create", flatH.Create)
+		r.Get("/flat/{id}", flatH.Get)
+		r.Get("/flat/{id}/prices", flatH.GetPrices)
+		r.Patch("/flat/{id}", flatH.Edit)
+		r.Post("/flat/{id}/resubmit", flatH.Resubmit)
+		r.Post("/flat/{id}/archive", flatH.Archive)
+		r.Post("/flat/{id}/restore", flatH.Restore)
+		r.Post("/flat/{id}/photos", flatH.UploadPhoto)
+		r.Put("/flat/{id}/photos/order", flatH.ReorderPhotos)
+		r.Delete("/flat/{id}/photos/{photoID}", flatH.DeletePhoto)
+		r.Get("/me/flats", flatH.MyFlats)
+		r.Post("/logout", authH.Logout)
+		r.Get("/flats/search", searchH.SearchFlats)
+	})
+	// Flat photo files, served to those who may see the flat
+	r.Group(func(r chi.Router) {
+		r.Use(custommiddleware.AuthMiddleware(authH, logger))
+		r.Use(flatH.PhotoAccess)
+
+		r.Get(photos.URLPrefix()+"/flats/{id}/*", http.StripPrefix(photos.URLPrefix(), photos).ServeHTTP)
 	})
 
 	return r
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_flats_created_by;
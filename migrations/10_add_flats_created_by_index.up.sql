@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_flats_created_by ON flats(created_by, created_at);
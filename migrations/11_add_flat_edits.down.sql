@@ -0,0 +1 @@
+DROP TABLE IF EXISTS flat_edits;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS flat_edits (
+    id BIGSERIAL PRIMARY KEY,
+    flat_id INT NOT NULL REFERENCES flats(id),
+    editor_id UUID NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_flat_edits_flat_id ON flat_edits(flat_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_flats_moderation_queue;
+CREATE INDEX IF NOT EXISTS idx_flats_moderation_queue ON flats(created_at, id) WHERE status = 'created';
+
+ALTER TABLE flats DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE houses DROP COLUMN IF EXISTS archived_at;
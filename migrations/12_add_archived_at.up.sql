@@ -0,0 +1,6 @@
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE flats ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+
+-- Read paths filter out archived flats
+DROP INDEX IF EXISTS idx_flats_moderation_queue;
+CREATE INDEX IF NOT EXISTS idx_flats_moderation_queue ON flats(created_at, id) WHERE status = 'created' AND archived_at IS NULL;
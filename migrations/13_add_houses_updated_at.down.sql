@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_houses_address_trgm;
+DROP INDEX IF EXISTS idx_houses_builder;
+DROP INDEX IF EXISTS idx_houses_year_built;
+
+ALTER TABLE houses DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,9 @@
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+UPDATE houses SET updated_at = created_at;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- House list filters
+CREATE INDEX IF NOT EXISTS idx_houses_year_built ON houses(year_built);
+CREATE INDEX IF NOT EXISTS idx_houses_builder ON houses(lower(builder));
+CREATE INDEX IF NOT EXISTS idx_houses_address_trgm ON houses USING gin (address gin_trgm_ops);
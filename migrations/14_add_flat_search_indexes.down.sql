@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_flats_search_rooms_price;
+DROP INDEX IF EXISTS idx_flats_search_price;
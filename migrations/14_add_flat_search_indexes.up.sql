@@ -0,0 +1,3 @@
+-- Cross-house flat search; house filters use the indexes from 13_add_houses_updated_at
+CREATE INDEX IF NOT EXISTS idx_flats_search_price ON flats(price) WHERE archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_flats_search_rooms_price ON flats(rooms, price) WHERE archived_at IS NULL;
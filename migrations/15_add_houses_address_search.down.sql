@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_houses_address_tsv;
+DROP TRIGGER IF EXISTS before_house_address_change ON houses;
+DROP FUNCTION IF EXISTS update_house_address_tsv;
+ALTER TABLE houses DROP COLUMN IF EXISTS address_tsv;
+DROP FUNCTION IF EXISTS expand_address_abbreviations;
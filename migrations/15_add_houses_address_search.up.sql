@@ -0,0 +1,50 @@
+-- Common address abbreviations are expanded so that "ул. Ленина" and "Ленина улица" give the same lexemes
+CREATE OR REPLACE FUNCTION expand_address_abbreviations(address TEXT)
+    RETURNS TEXT AS $$
+DECLARE
+    abbr RECORD;
+    result TEXT := lower(address);
+BEGIN
+    FOR abbr IN SELECT * FROM (VALUES
+        ('ул', 'улица'),
+        ('пр-т', 'проспект'),
+        ('просп', 'проспект'),
+        ('пр', 'проспект'),
+        ('пр-д', 'проезд'),
+        ('пер', 'переулок'),
+        ('пл', 'площадь'),
+        ('наб', 'набережная'),
+        ('б-р', 'бульвар'),
+        ('бул', 'бульвар'),
+        ('ш', 'шоссе'),
+        ('г', 'город'),
+        ('д', 'дом'),
+        ('корп', 'корпус'),
+        ('к', 'корпус'),
+        ('стр', 'строение')
+    ) AS a(short, full_form)
+    LOOP
+        result := regexp_replace(result, '(^|[^а-яё0-9-])' || abbr.short || '(\.|(?![а-яё-]))', '\1' || abbr.full_form || ' ', 'g');
+    END LOOP;
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS address_tsv tsvector;
+
+CREATE OR REPLACE FUNCTION update_house_address_tsv()
+    RETURNS TRIGGER AS $$
+BEGIN
+    NEW.address_tsv := to_tsvector('russian', expand_address_abbreviations(NEW.address));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER before_house_address_change
+    BEFORE INSERT OR UPDATE OF address ON houses
+    FOR EACH ROW
+EXECUTE FUNCTION update_house_address_tsv();
+
+UPDATE houses SET address_tsv = to_tsvector('russian', expand_address_abbreviations(address));
+
+CREATE INDEX IF NOT EXISTS idx_houses_address_tsv ON houses USING gin (address_tsv);
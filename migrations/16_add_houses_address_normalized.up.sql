@@ -0,0 +1,5 @@
+-- Canonical form of the address written by the service, see internal/lib/address.
+-- Houses created before it stay NULL and are not checked for duplicates.
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS address_normalized TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_houses_address_normalized ON houses(address_normalized) WHERE address_normalized IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_houses_location;
+ALTER TABLE houses DROP COLUMN IF EXISTS longitude;
+ALTER TABLE houses DROP COLUMN IF EXISTS latitude;
@@ -0,0 +1,5 @@
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90);
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180);
+
+-- Bounding box prefilter of the nearby search, the exact distance is computed for the rows inside it
+CREATE INDEX IF NOT EXISTS idx_houses_location ON houses(latitude, longitude) WHERE latitude IS NOT NULL AND archived_at IS NULL;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_flats_search_total_area;
+
+ALTER TABLE flats
+    DROP CONSTRAINT IF EXISTS flats_floor_check,
+    DROP CONSTRAINT IF EXISTS flats_living_area_le_total_check,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS renovation,
+    DROP COLUMN IF EXISTS balcony,
+    DROP COLUMN IF EXISTS total_floors,
+    DROP COLUMN IF EXISTS floor,
+    DROP COLUMN IF EXISTS living_area,
+    DROP COLUMN IF EXISTS total_area;
@@ -0,0 +1,14 @@
+ALTER TABLE flats
+    ADD COLUMN IF NOT EXISTS total_area NUMERIC(7, 2) CHECK (total_area > 0),
+    ADD COLUMN IF NOT EXISTS living_area NUMERIC(7, 2) CHECK (living_area > 0),
+    ADD COLUMN IF NOT EXISTS floor INT,
+    ADD COLUMN IF NOT EXISTS total_floors INT CHECK (total_floors > 0),
+    ADD COLUMN IF NOT EXISTS balcony BOOLEAN,
+    ADD COLUMN IF NOT EXISTS renovation VARCHAR(50) CHECK (renovation IN ('none', 'cosmetic', 'euro', 'designer')),
+    ADD COLUMN IF NOT EXISTS description TEXT;
+
+ALTER TABLE flats ADD CONSTRAINT flats_living_area_le_total_check CHECK (living_area <= total_area);
+ALTER TABLE flats ADD CONSTRAINT flats_floor_check CHECK (floor <= total_floors);
+
+-- Flat search filters
+CREATE INDEX IF NOT EXISTS idx_flats_search_total_area ON flats(total_area) WHERE archived_at IS NULL;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS flat_photos (
+    id SERIAL PRIMARY KEY,
+    flat_id INT NOT NULL REFERENCES flats(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL DEFAULT 'photo' CHECK (kind IN ('photo', 'floor_plan')),
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    url TEXT NOT NULL,
+    thumbnail_url TEXT NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    position INT NOT NULL CHECK (position > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- Deferred, so that the photos can be reordered with a single UPDATE
+    CONSTRAINT flat_photos_position_key UNIQUE (flat_id, position) DEFERRABLE INITIALLY DEFERRED
+);
@@ -0,0 +1,5 @@
+ALTER TABLE flats
+    DROP COLUMN IF EXISTS price_changed_at,
+    DROP COLUMN IF EXISTS previous_price;
+
+DROP TABLE IF EXISTS flat_price_history;
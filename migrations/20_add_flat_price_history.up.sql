@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS flat_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    flat_id INT NOT NULL REFERENCES flats(id) ON DELETE CASCADE,
+    price INT NOT NULL,
+    changed_by UUID,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_flat_price_history_flat_id ON flat_price_history(flat_id, changed_at);
+
+-- The last price change is kept on the flat, so that listings need no join
+ALTER TABLE flats
+    ADD COLUMN IF NOT EXISTS previous_price INT,
+    ADD COLUMN IF NOT EXISTS price_changed_at TIMESTAMP WITH TIME ZONE;
+
+-- Backfill from the owners' edits: the price the flat was created with, then every edited price
+INSERT INTO flat_price_history (flat_id, price, changed_by, changed_at)
+SELECT f.id,
+       COALESCE((SELECT (e.changes -> 'price' ->> 'old')::int
+                 FROM flat_edits e
+                 WHERE e.flat_id = f.id AND e.changes ? 'price'
+                 ORDER BY e.created_at, e.id
+                 LIMIT 1), f.price),
+       f.created_by,
+       f.created_at
+FROM flats f;
+
+INSERT INTO flat_price_history (flat_id, price, changed_by, changed_at)
+SELECT e.flat_id, (e.changes -> 'price' ->> 'new')::int, e.editor_id, e.created_at
+FROM flat_edits e
+WHERE e.changes ? 'price'
+ORDER BY e.created_at, e.id;
+
+-- The last price change on the flat is derived from the history, as the repository does on every change
+UPDATE flats f
+SET previous_price = h.previous_price, price_changed_at = h.changed_at
+FROM (
+    SELECT DISTINCT ON (flat_id) flat_id, changed_at, previous_price
+    FROM (
+        SELECT flat_id, changed_at, id, LAG(price) OVER (PARTITION BY flat_id ORDER BY changed_at, id) AS previous_price
+        FROM flat_price_history
+    ) ordered
+    ORDER BY flat_id, changed_at DESC, id DESC
+) h
+WHERE f.id = h.flat_id AND h.previous_price IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Invite codes let a user register with a privileged role. A code is single-use and expires;
+-- only its SHA-256 hash is stored. created_by is a registered moderator or admin; it is not a foreign key
+-- so that the invite keeps its author after the user is deleted.
+CREATE TABLE IF NOT EXISTS invites (
+    id SERIAL PRIMARY KEY,
+    code_hash TEXT NOT NULL UNIQUE,
+    role VARCHAR(50) NOT NULL,
+    created_by UUID NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    used_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_invites_created_by ON invites(created_by);
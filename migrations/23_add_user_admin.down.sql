@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_users_revoked;
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_role_check,
+    DROP COLUMN IF EXISTS sessions_revoked_at,
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,10 @@
+-- Admins manage users. A disabled user can not log in and all their tokens are rejected;
+-- access tokens issued before sessions_revoked_at are rejected too.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMP WITH TIME ZONE,
+    ADD CONSTRAINT users_role_check CHECK (role IN ('client', 'moderator', 'admin'));
+
+CREATE INDEX IF NOT EXISTS idx_users_revoked ON users(id)
+    WHERE disabled_at IS NOT NULL OR sessions_revoked_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Users log in by email regardless of its case, so emails differing only in case are duplicates.
+-- The migration fails if such duplicates already exist; they have to be resolved by hand.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(lower(email));
@@ -0,0 +1 @@
+ALTER TABLE flats DROP COLUMN IF EXISTS approved_at;
@@ -0,0 +1,3 @@
+-- Time of the first approval; subscribers are notified only when it is set
+ALTER TABLE flats ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP WITH TIME ZONE;
+UPDATE flats SET approved_at = CURRENT_TIMESTAMP WHERE status = 'approved';
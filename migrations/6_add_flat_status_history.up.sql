@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS flat_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    flat_id INT NOT NULL REFERENCES flats(id),
+    previous_status VARCHAR(50) NOT NULL,
+    new_status VARCHAR(50) NOT NULL,
+    actor_id UUID NOT NULL,
+    comment TEXT,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_flat_status_history_flat_id ON flat_status_history(flat_id);
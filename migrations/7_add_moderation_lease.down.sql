@@ -0,0 +1,5 @@
+DELETE FROM flat_status_history WHERE actor_id IS NULL;
+ALTER TABLE flat_status_history ALTER COLUMN actor_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_flats_moderation_expires_at;
+ALTER TABLE flats DROP COLUMN IF EXISTS moderation_expires_at;
@@ -0,0 +1,6 @@
+ALTER TABLE flats ADD COLUMN IF NOT EXISTS moderation_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_flats_moderation_expires_at ON flats(moderation_expires_at) WHERE status = 'on moderation';
+
+-- Expired leases are released by the service itself, such history records have no actor
+ALTER TABLE flat_status_history ALTER COLUMN actor_id DROP NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_flats_moderation_queue;
+ALTER TABLE flats DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,4 @@
+ALTER TABLE flats ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- Moderation queue: flats waiting for moderation, oldest first
+CREATE INDEX IF NOT EXISTS idx_flats_moderation_queue ON flats(created_at, id) WHERE status = 'created';
@@ -0,0 +1,3 @@
+ALTER TABLE flats DROP COLUMN IF EXISTS decline_comment;
+ALTER TABLE flats DROP COLUMN IF EXISTS decline_reason;
+ALTER TABLE flats DROP COLUMN IF EXISTS created_by;
@@ -0,0 +1,5 @@
+-- Flats created before this migration have no known owner
+ALTER TABLE flats ADD COLUMN IF NOT EXISTS created_by UUID;
+
+ALTER TABLE flats ADD COLUMN IF NOT EXISTS decline_reason VARCHAR(50);
+ALTER TABLE flats ADD COLUMN IF NOT EXISTS decline_comment TEXT;
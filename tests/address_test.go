@@ -0,0 +1,64 @@
+package avito_test
+
+import (
+	"avito/internal/lib/address"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	cases := []struct {
+		raw      string
+		expected string
+	}{
+		// Abbreviations, case and punctuation
+		{"ул. Ленина, д. 5", "улица ленина, дом 5"},
+		{"УЛИЦА ЛЕНИНА, ДОМ 5", "улица ленина, дом 5"},
+		{"ул.Ленина,д.5", "улица ленина, дом 5"},
+		{"Ленина улица, 5", "улица ленина, дом 5"},
+		{"ул Ленина д5", "улица ленина, дом 5"},
+		// Корпус and строение, written apart and glued to the number
+		{"ул. Ленина, д. 5, корп. 2", "улица ленина, дом 5, корпус 2"},
+		{"ул. Ленина, д. 5к2", "улица ленина, дом 5, корпус 2"},
+		{"Ленина ул., 5 к 2", "улица ленина, дом 5, корпус 2"},
+		{"Москва, проспект Мира, 12с1", "москва, проспект мира, дом 12, строение 1"},
+		{"Россия, г. Москва, пр-т Мира, д.12 стр.1", "москва, проспект мира, дом 12, строение 1"},
+		{"ул. Ленина, д5к2с1", "улица ленина, дом 5, корпус 2, строение 1"},
+		// Postcode and country are dropped, the city goes first
+		{"Лесная улица, 7, Москва, 125196", "москва, улица лесная, дом 7"},
+		{"125196, Россия, г. Москва, ул. Лесная, д. 7", "москва, улица лесная, дом 7"},
+		{"Новый Арбат, 21, Москва, 119019", "москва, новый арбат, дом 21"},
+		{"г. Москва, Новый Арбат, д. 21", "москва, новый арбат, дом 21"},
+		// Other street types
+		{"Москва, Ленинградское ш., 5/2", "москва, шоссе ленинградское, дом 5/2"},
+		{"Гоголевский б-р, 10", "бульвар гоголевский, дом 10"},
+		{"Фрунзенская наб., 30", "набережная фрунзенская, дом 30"},
+		{"Сивцев Вражек пер., 4", "переулок сивцев вражек, дом 4"},
+		{"Санкт-Петербург, Невский пр., 28", "санкт-петербург, проспект невский, дом 28"},
+		// Ordinals belong to the street name, building letters to the house number
+		{"Тверская-Ямская 1-я ул., 12 А", "улица 1-я тверская-ямская, дом 12а"},
+		{"ул. 1-я Тверская-Ямская, д.12а", "улица 1-я тверская-ямская, дом 12а"},
+		{"Щёлковское шоссе, 3", "шоссе щелковское, дом 3"},
+		// Numbers in street names stay in the street
+		{"Москва, ул. 8 Марта, д. 5", "москва, улица 8 марта, дом 5"},
+		{"ул. 8 Марта, 5", "улица 8 марта, дом 5"},
+		{"ул. 1905 года, д. 10", "улица 1905 года, дом 10"},
+		{"Москва, ул. 50 лет Октября, д. 3к1", "москва, улица 50 лет октября, дом 3, корпус 1"},
+		// Nothing but punctuation
+		{" , . ", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.raw, func(t *testing.T) {
+			assert.Equal(t, tc.expected, address.Normalize(tc.raw))
+		})
+	}
+
+	// Different buildings stay different
+	assert.NotEqual(t, address.Normalize("ул. Ленина, д. 5"), address.Normalize("ул. Ленина, д. 5, корп. 2"))
+	assert.NotEqual(t, address.Normalize("ул. Ленина, д. 5"), address.Normalize("пр-т Ленина, д. 5"))
+	assert.NotEqual(t, address.Normalize("ул. Ленина, д. 5"), address.Normalize("ул. Ленина, д. 5а"))
+	assert.NotEqual(t, address.Normalize("Москва, ул. 8 Марта, д. 5"), address.Normalize("Москва, ул. Марта, д. 5"))
+	assert.NotEqual(t, address.Normalize("ул. 1905 года, д. 10"), address.Normalize("ул. года, д. 10"))
+}
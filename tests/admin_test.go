@@ -0,0 +1,157 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/handlers/response"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAdminManagesUsers(t *testing.T) {
+	authRepoMock := mocks.NewAuthRepo(t)
+
+	adminID := "admin-uuid"
+	moderatorID := "moderator-uuid"
+	clientID := "client-uuid"
+	now := time.Now()
+	// Sessions are revoked after the tokens below are issued
+	revokedAt := now.Add(time.Second)
+
+	moderatorRole := models.RoleModerator
+	authRepoMock.On("ListUsers", mock.Anything, models.UserFilter{Role: &moderatorRole, Limit: 10}).
+		Return([]models.User{{ID: moderatorID, Email: "m@example.com", Role: models.RoleModerator, CreatedAt: now}}, nil).Once()
+	authRepoMock.On("SetUserRole", mock.Anything, clientID, models.RoleModerator).
+		Return(&models.User{ID: clientID, Email: "c@example.com", Role: models.RoleModerator, SessionsRevokedAt: &revokedAt}, nil).Once()
+	authRepoMock.On("SetUserRole", mock.Anything, "unknown-uuid", models.RoleClient).
+		Return(nil, fmt.Errorf("repositories.auth.SetUserRole: %w", repositories.ErrUserNotFound)).Once()
+	authRepoMock.On("SetUserDisabled", mock.Anything, moderatorID, true).
+		Return(&models.User{ID: moderatorID, Email: "m@example.com", Role: models.RoleModerator, DisabledAt: &now, SessionsRevokedAt: &revokedAt}, nil).Once()
+	authRepoMock.On("SetUserDisabled", mock.Anything, moderatorID, false).
+		Return(&models.User{ID: moderatorID, Email: "m@example.com", Role: models.RoleModerator, SessionsRevokedAt: &revokedAt}, nil).Once()
+	authRepoMock.On("RevokeUserSessions", mock.Anything, clientID).
+		Return(&models.User{ID: clientID, Email: "c@example.com", Role: models.RoleClient, SessionsRevokedAt: &revokedAt}, nil).Once()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+	authRepoMock.On("GetUserByID", mock.Anything, moderatorID).
+		Return(&models.User{ID: moderatorID, Password: string(hashedPassword), Role: models.RoleModerator, DisabledAt: &now}, nil).Once()
+
+	router, authS := newTestRouter(t, testDeps{authRepo: authRepoMock})
+
+	tokens := make(map[string]string)
+	for userID, role := range map[string]models.Role{adminID: models.RoleAdmin, moderatorID: models.RoleModerator, clientID: models.RoleClient} {
+		token, err := authS.GenerateToken(userID, role)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens[userID] = token
+	}
+	call := func(method, url, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[user]))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Only admins manage users", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, call("GET", "/admin/users", moderatorID, "").Code)
+		assert.Equal(t, http.StatusForbidden, call("POST", "/admin/users/"+clientID+"/disable", clientID, "").Code)
+	})
+
+	t.Run("List users", func(t *testing.T) {
+		resp := call("GET", "/admin/users?role=moderator&limit=10", adminID, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var users struct {
+			Users []response.UserResponse `json:"users"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &users); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		if assert.Len(t, users.Users, 1) {
+			assert.Equal(t, moderatorID, users.Users[0].ID)
+		}
+		assert.NotContains(t, resp.Body.String(), "password")
+
+		assert.Equal(t, http.StatusBadRequest, call("GET", "/admin/users?role=owner", adminID, "").Code)
+	})
+
+	t.Run("Role changes", func(t *testing.T) {
+		resp := call("PUT", "/admin/users/"+clientID+"/role", adminID, `{"role": "moderator"}`)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"role":"moderator"`)
+
+		assert.Equal(t, http.StatusBadRequest, call("PUT", "/admin/users/"+clientID+"/role", adminID, `{"role": "owner"}`).Code)
+		assert.Equal(t, http.StatusNotFound, call("PUT", "/admin/users/unknown-uuid/role", adminID, `{"role": "client"}`).Code)
+		assert.Equal(t, http.StatusConflict, call("PUT", "/admin/users/"+adminID+"/role", adminID, `{"role": "client"}`).Code,
+			"Admins can not demote themselves")
+	})
+
+	t.Run("Role change ends the sessions of the user", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, call("GET", "/admin/users", clientID, "").Code)
+	})
+
+	t.Run("Disabled moderator is locked out", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, call("POST", "/admin/users/"+moderatorID+"/disable", adminID, "").Code)
+
+		assert.Equal(t, http.StatusUnauthorized, call("POST", "/invites", moderatorID, "").Code)
+
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"id": "moderator-uuid", "password": "qwerty"}`))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotFound, resp.Code, "The right password of a disabled user looks like a wrong one")
+
+		resp = call("POST", "/admin/users/"+moderatorID+"/enable", adminID, "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.NotContains(t, resp.Body.String(), "disabled_at")
+
+		assert.Equal(t, http.StatusConflict, call("POST", "/admin/users/"+adminID+"/disable", adminID, "").Code)
+	})
+
+	t.Run("Force logout", func(t *testing.T) {
+		clientToken, err := authS.GenerateToken(clientID, models.RoleClient)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		claims, err := authS.ValidateToken(clientToken)
+		if err != nil {
+			t.Fatal("Failed to validate token:", err)
+		}
+
+		assert.Equal(t, http.StatusNoContent, call("POST", "/admin/users/"+clientID+"/logout", adminID, "").Code)
+		assert.True(t, authS.IsRevoked(claims), "Tokens issued before the logout are rejected")
+
+		claims.IssuedAt.Time = revokedAt.Add(time.Millisecond)
+		assert.False(t, authS.IsRevoked(claims), "Tokens issued after the logout are accepted")
+	})
+
+	t.Run("Admins mint admin invites, moderators can not", func(t *testing.T) {
+		authRepoMock.On("GetUserByID", mock.Anything, adminID).
+			Return(&models.User{ID: adminID, Role: models.RoleAdmin}, nil).Once()
+		authRepoMock.On("GetUserByID", mock.Anything, "other-moderator-uuid").
+			Return(&models.User{ID: "other-moderator-uuid", Role: models.RoleModerator}, nil).Once()
+		authRepoMock.On("CreateInvite", mock.Anything, mock.MatchedBy(func(i *models.Invite) bool { return i.Role == models.RoleAdmin })).
+			Return(nil).Once()
+
+		assert.Equal(t, http.StatusOK, call("POST", "/invites", adminID, `{"user_type": "admin"}`).Code)
+
+		moderatorToken, err := authS.GenerateToken("other-moderator-uuid", models.RoleModerator)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens["other-moderator-uuid"] = moderatorToken
+		assert.Equal(t, http.StatusForbidden, call("POST", "/invites", "other-moderator-uuid", `{"user_type": "admin"}`).Code)
+	})
+}
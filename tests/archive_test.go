@@ -0,0 +1,124 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/handlers/response"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestArchive(t *testing.T) {
+	houseRepoMock := mocks.NewHouseRepo(t)
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	ownerID := "owner-uuid"
+	moderatorID := "moderator-uuid"
+	archivedAt := time.Now().UTC().Truncate(time.Second)
+
+	flat := func(id int, status string) *models.Flat {
+		return &models.Flat{ID: id, HouseID: 2, Price: 100, Rooms: 2, Status: status, CreatedBy: &ownerID}
+	}
+	archived := func(id int) *models.Flat {
+		f := flat(id, models.StatusApproved)
+		f.ArchivedAt = &archivedAt
+		return f
+	}
+
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, true).Return(flat(1, models.StatusApproved), nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 2, true).Return(flat(2, models.StatusOnModeration), nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 3, true).Return(archived(3), nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 3, false).Return(nil, nil)
+	flatRepoMock.On("SetFlatArchived", mock.Anything, 1, true).Return(archived(1), nil)
+	flatRepoMock.On("SetFlatArchived", mock.Anything, 2, true).
+		Return(nil, fmt.Errorf("repository.flat.SetFlatArchived: %w", repositories.ErrFlatStatusChanged))
+	flatRepoMock.On("SetFlatArchived", mock.Anything, 3, false).Return(flat(3, models.StatusApproved), nil)
+
+	houseRepoMock.On("SetHouseArchived", mock.Anything, 2, true).
+		Return(&models.House{ID: 2, Address: "Лесная улица, 7", YearBuilt: 2000, ArchivedAt: &archivedAt}, nil)
+	houseRepoMock.On("SetHouseArchived", mock.Anything, 404, true).
+		Return(nil, fmt.Errorf("repositories.house.SetHouseArchived: %w", repositories.ErrHouseNotFound))
+	houseRepoMock.On("GetFlatsByHouseID", mock.Anything, 2, moderatorID, models.RoleModerator, true, firstFlatPage).
+		Return([]models.Flat{*archived(3)}, nil)
+	houseRepoMock.On("GetFlatsByHouseID", mock.Anything, 2, ownerID, models.RoleClient, false, firstFlatPage).
+		Return([]models.Flat{}, nil)
+
+	router, authS := newTestRouter(t, testDeps{houseRepo: houseRepoMock, flatRepo: flatRepoMock})
+
+	tokens := make(map[string]string)
+	for userID, role := range map[string]models.Role{ownerID: "client", moderatorID: "moderator", "stranger-uuid": "client"} {
+		token, err := authS.GenerateToken(userID, role)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens[userID] = token
+	}
+
+	cases := []struct {
+		name     string
+		user     string
+		method   string
+		url      string
+		code     int
+		archived bool
+	}{
+		{"Owner archives flat", ownerID, "POST", "/flat/1/archive", http.StatusOK, true},
+		{"Stranger can not archive flat", "stranger-uuid", "POST", "/flat/1/archive", http.StatusForbidden, false},
+		{"Flat on moderation can not be archived", moderatorID, "POST", "/flat/2/archive", http.StatusConflict, false},
+		{"Moderator restores flat", moderatorID, "POST", "/flat/3/restore", http.StatusOK, false},
+		{"Moderator asks for archived flat", moderatorID, "GET", "/flat/3?include_archived=true", http.StatusOK, true},
+		{"Archived flat is hidden from clients", ownerID, "GET", "/flat/3?include_archived=true", http.StatusNotFound, false},
+		{"Moderator archives house", moderatorID, "POST", "/house/2/archive", http.StatusOK, true},
+		{"Client can not archive house", ownerID, "POST", "/house/2/archive", http.StatusForbidden, false},
+		{"Unknown house", moderatorID, "POST", "/house/404/archive", http.StatusNotFound, false},
+		{"Invalid include_archived", moderatorID, "GET", "/house/2?include_archived=maybe", http.StatusBadRequest, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[tc.user]))
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+
+			var archiveResponse struct {
+				ArchivedAt *time.Time `json:"archived_at"`
+			}
+			if err := json.Unmarshal(resp.Body.Bytes(), &archiveResponse); err != nil {
+				t.Fatal("Failed to unmarshal response:", err)
+			}
+			assert.Equal(t, tc.archived, archiveResponse.ArchivedAt != nil)
+		})
+	}
+
+	// Only moderators get archived flats of the house
+	for user, count := range map[string]int{moderatorID: 1, ownerID: 0} {
+		req := httptest.NewRequest("GET", "/house/2?include_archived=true", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[user]))
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var actualResponse map[string][]response.FlatResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &actualResponse); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		assert.Len(t, actualResponse["flats"], count)
+	}
+}
@@ -0,0 +1,68 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginAndRegisterCredentials(t *testing.T) {
+	authRepoMock := mocks.NewAuthRepo(t)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("passw0rd"), bcrypt.DefaultCost)
+	user := &models.User{ID: "user-uuid", Email: "User@Example.com", Password: string(hashedPassword), Role: models.RoleClient}
+	authRepoMock.On("GetUserByEmail", mock.Anything, "user@example.com").Return(user, nil)
+	authRepoMock.On("GetUserByEmail", mock.Anything, "nobody@example.com").
+		Return(nil, fmt.Errorf("repositories.auth.GetUserByEmail: %w", repositories.ErrUserNotFound))
+	authRepoMock.On("GetUserByID", mock.Anything, "user-uuid").Return(user, nil)
+	authRepoMock.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	authRepoMock.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.Email == "new@example.com" })).
+		Return("new-uuid", nil).Once()
+
+	router, _ := newTestRouter(t, testDeps{authRepo: authRepoMock})
+
+	cases := []struct {
+		name string
+		url  string
+		body string
+		code int
+	}{
+		{"Login by email", "/login", `{"email": "user@example.com", "password": "passw0rd"}`, http.StatusOK},
+		{"Login by email with surrounding spaces", "/login", `{"email": " user@example.com ", "password": "passw0rd"}`, http.StatusOK},
+		{"Login by ID still works", "/login", `{"id": "user-uuid", "password": "passw0rd"}`, http.StatusOK},
+		{"Wrong password", "/login", `{"email": "user@example.com", "password": "passw0rd!"}`, http.StatusNotFound},
+		{"Unknown email", "/login", `{"email": "nobody@example.com", "password": "passw0rd"}`, http.StatusNotFound},
+		{"Neither email nor ID", "/login", `{"password": "passw0rd"}`, http.StatusBadRequest},
+		{"Both email and ID", "/login", `{"id": "user-uuid", "email": "user@example.com", "password": "passw0rd"}`, http.StatusBadRequest},
+
+		{"Register", "/register", `{"email": "new@example.com", "password": "passw0rd", "user_type": "client"}`, http.StatusOK},
+		{"Not an email", "/register", `{"email": "new.example.com", "password": "passw0rd", "user_type": "client"}`, http.StatusBadRequest},
+		{"Email with a display name", "/register", `{"email": "New <new@example.com>", "password": "passw0rd", "user_type": "client"}`, http.StatusBadRequest},
+		{"Short password", "/register", `{"email": "new@example.com", "password": "pa55", "user_type": "client"}`, http.StatusBadRequest},
+		{"Password without digits", "/register", `{"email": "new@example.com", "password": "password", "user_type": "client"}`, http.StatusBadRequest},
+		{"Password without letters", "/register", `{"email": "new@example.com", "password": "12345678", "user_type": "client"}`, http.StatusBadRequest},
+		{"Password too long for bcrypt", "/register",
+			fmt.Sprintf(`{"email": "new@example.com", "password": "%s1", "user_type": "client"}`, strings.Repeat("a", 72)), http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tc.url, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/handlers/response"
+	"avito/internal/repositories/mocks"
+	"avito/internal/services/flatService"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateFlatWithDetails(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	flatRepoMock.On("CreateFlat", mock.Anything, mock.MatchedBy(func(f *models.Flat) bool {
+		return f.TotalArea != nil && *f.TotalArea == 52.5 && *f.LivingArea == 30 &&
+			*f.Floor == 4 && *f.TotalFloors == 9 && *f.Balcony && *f.Renovation == models.RenovationEuro &&
+			*f.Description == "Светлая квартира с видом на парк"
+	})).Return(42, nil).Once()
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	token, err := authS.GenerateToken("client-uuid", "client")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	createFlat := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/flat/create", strings.NewReader(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Flat with every detail", func(t *testing.T) {
+		resp := createFlat(`{
+            "house_id": 5,
+            "price": 10500000,
+            "rooms": 2,
+            "total_area": 52.5,
+            "living_area": 30,
+            "floor": 4,
+            "total_floors": 9,
+            "balcony": true,
+            "renovation": "euro",
+            "description": "Светлая квартира с видом на парк"
+        }`)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var flat response.FlatResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &flat); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		assert.Equal(t, 42, flat.ID)
+		assert.Equal(t, 52.5, *flat.TotalArea)
+		assert.Equal(t, 4, *flat.Floor)
+		assert.Equal(t, models.RenovationEuro, *flat.Renovation)
+		if assert.NotNil(t, flat.PricePerSqm) {
+			assert.Equal(t, 200000, *flat.PricePerSqm)
+		}
+	})
+
+	invalid := []struct {
+		name string
+		body string
+	}{
+		{"Zero total area", `{"house_id": 5, "price": 1, "rooms": 1, "total_area": 0}`},
+		{"Huge total area", `{"house_id": 5, "price": 1, "rooms": 1, "total_area": 20000}`},
+		{"Living area exceeds total area", `{"house_id": 5, "price": 1, "rooms": 1, "total_area": 40, "living_area": 45}`},
+		{"Floor exceeds total floors", `{"house_id": 5, "price": 1, "rooms": 1, "floor": 10, "total_floors": 9}`},
+		{"Floor too deep", `{"house_id": 5, "price": 1, "rooms": 1, "floor": -4}`},
+		{"No floors in the house", `{"house_id": 5, "price": 1, "rooms": 1, "total_floors": 0}`},
+		{"Unknown renovation", `{"house_id": 5, "price": 1, "rooms": 1, "renovation": "luxury"}`},
+		{"Description too long", fmt.Sprintf(`{"house_id": 5, "price": 1, "rooms": 1, "description": %q}`,
+			strings.Repeat("я", flatService.MaxDescriptionLen+1))},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, createFlat(tc.body).Code)
+		})
+	}
+}
+
+func TestFlatPricePerSquareMeter(t *testing.T) {
+	area := 33.3
+	flat := models.Flat{Price: 1000000}
+
+	_, ok := flat.PricePerSquareMeter()
+	assert.False(t, ok, "Expected no price per m² without the area")
+	assert.Nil(t, response.NewFlatResponse(flat).PricePerSqm)
+
+	flat.TotalArea = &area
+	price, ok := flat.PricePerSquareMeter()
+	assert.True(t, ok)
+	assert.Equal(t, 30030, price)
+}
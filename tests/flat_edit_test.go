@@ -0,0 +1,228 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/handlers/response"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEditFlat(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	ownerID := "owner-uuid"
+	moderatorID := "moderator-uuid"
+	number := 7
+
+	flat := func(id int, status string) *models.Flat {
+		return &models.Flat{ID: id, HouseID: 2, FlatNumber: &number, Price: 100, Rooms: 2, Status: status, CreatedBy: &ownerID}
+	}
+
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).Return(flat(1, models.StatusApproved), nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 2, false).Return(flat(2, models.StatusDeclined), nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 3, false).Return(flat(3, models.StatusOnModeration), nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 404, false).Return(nil, nil)
+
+	totalArea, livingArea := 50.0, 30.0
+	withDetails := flat(4, models.StatusApproved)
+	withDetails.TotalArea, withDetails.LivingArea = &totalArea, &livingArea
+	flatRepoMock.On("GetFlatByID", mock.Anything, 4, false).Return(withDetails, nil)
+
+	newPrice := 150
+	flatRepoMock.On("UpdateFlat", mock.Anything, models.FlatUpdate{
+		FlatID: 1, From: models.StatusApproved, To: models.StatusCreated, EditorID: ownerID,
+		FlatNumber: &number, Price: newPrice, Rooms: 2,
+		Changes: map[string]models.FieldChange{"price": {Old: 100, New: newPrice}},
+	}).Return(&models.Flat{ID: 1, HouseID: 2, FlatNumber: &number, Price: newPrice, Rooms: 2,
+		Status: models.StatusCreated, CreatedBy: &ownerID}, nil).Once()
+
+	newRooms := 3
+	flatRepoMock.On("UpdateFlat", mock.Anything, models.FlatUpdate{
+		FlatID: 2, From: models.StatusDeclined, To: models.StatusDeclined, EditorID: ownerID,
+		FlatNumber: &number, Price: 100, Rooms: newRooms,
+		Changes: map[string]models.FieldChange{"rooms": {Old: 2, New: newRooms}},
+	}).Return(&models.Flat{ID: 2, HouseID: 2, FlatNumber: &number, Price: 100, Rooms: newRooms,
+		Status: models.StatusDeclined, CreatedBy: &ownerID}, nil).Once()
+
+	flatRepoMock.On("UpdateFlat", mock.Anything, mock.MatchedBy(func(u models.FlatUpdate) bool {
+		return u.FlatID == 2 && u.FlatNumber != nil && *u.FlatNumber == 8
+	})).Return(nil, fmt.Errorf("repository.flat.UpdateFlat: %w", repositories.ErrFlatNumberExists)).Once()
+
+	newArea := 55.0
+	flatRepoMock.On("UpdateFlat", mock.Anything, mock.MatchedBy(func(u models.FlatUpdate) bool {
+		return u.FlatID == 4 && u.TotalArea != nil && *u.TotalArea == newArea && *u.LivingArea == livingArea &&
+			u.To == models.StatusCreated && len(u.Changes) == 1
+	})).Return(&models.Flat{ID: 4, HouseID: 2, Price: 100, Rooms: 2, Status: models.StatusCreated, CreatedBy: &ownerID}, nil).Once()
+	flatRepoMock.On("UpdateFlat", mock.Anything, mock.MatchedBy(func(u models.FlatUpdate) bool {
+		return u.FlatID == 4 && u.Balcony != nil && *u.Balcony && u.To == models.StatusApproved
+	})).Return(&models.Flat{ID: 4, HouseID: 2, Price: 100, Rooms: 2, Status: models.StatusApproved, CreatedBy: &ownerID}, nil).Once()
+
+	flatRepoMock.On("GetFlatEdits", mock.Anything, 1).Return([]models.FlatEdit{
+		{ID: 1, FlatID: 1, EditorID: ownerID, Changes: map[string]models.FieldChange{"price": {Old: 100, New: 150}}},
+	}, nil)
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	tokens := make(map[string]string)
+	for userID, role := range map[string]models.Role{ownerID: "client", moderatorID: "moderator", "stranger-uuid": "client"} {
+		token, err := authS.GenerateToken(userID, role)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens[userID] = token
+	}
+
+	cases := []struct {
+		name   string
+		user   string
+		flatID int
+		body   string
+		code   int
+		status string
+	}{
+		{"Price change sends approved flat to moderation", ownerID, 1, `{"price": 150}`, http.StatusOK, models.StatusCreated},
+		{"Declined flat stays declined", ownerID, 2, `{"rooms": 3}`, http.StatusOK, models.StatusDeclined},
+		{"Same values change nothing", ownerID, 1, `{"price": 100, "rooms": 2, "flat_number": 7}`, http.StatusOK, models.StatusApproved},
+		{"Flat number taken", ownerID, 2, `{"flat_number": 8}`, http.StatusConflict, ""},
+		{"Flat on moderation", ownerID, 3, `{"price": 1}`, http.StatusConflict, ""},
+		{"Not the owner", "stranger-uuid", 1, `{"price": 1}`, http.StatusForbidden, ""},
+		{"Negative price", ownerID, 1, `{"price": -1}`, http.StatusBadRequest, ""},
+		{"Zero rooms", ownerID, 1, `{"rooms": 0}`, http.StatusBadRequest, ""},
+		{"Unknown flat", ownerID, 404, `{"price": 1}`, http.StatusNotFound, ""},
+		{"Area change sends approved flat to moderation", ownerID, 4, `{"total_area": 55}`, http.StatusOK, models.StatusCreated},
+		{"Balcony is not a material change", ownerID, 4, `{"balcony": true}`, http.StatusOK, models.StatusApproved},
+		{"Living area over the kept total area", ownerID, 4, `{"living_area": 60}`, http.StatusBadRequest, ""},
+		{"Unknown renovation", ownerID, 4, `{"renovation": "palace"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", fmt.Sprintf("/flat/%d", tc.flatID), strings.NewReader(tc.body))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[tc.user]))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+
+			var flatResponse response.FlatResponse
+			if err := json.Unmarshal(resp.Body.Bytes(), &flatResponse); err != nil {
+				t.Fatal("Failed to unmarshal response:", err)
+			}
+			assert.Equal(t, tc.status, flatResponse.Status)
+		})
+	}
+
+	t.Run("Moderator sees the diff", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/flat/1/edits", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[moderatorID]))
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var editsResponse struct {
+			Edits []struct {
+				EditorID string                                `json:"editor_id"`
+				Changes  map[string]struct{ Old, New float64 } `json:"changes"`
+			} `json:"edits"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &editsResponse); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		if assert.Len(t, editsResponse.Edits, 1) {
+			assert.Equal(t, ownerID, editsResponse.Edits[0].EditorID)
+			assert.Equal(t, struct{ Old, New float64 }{100, 150}, editsResponse.Edits[0].Changes["price"])
+		}
+	})
+
+	t.Run("Clients can not see the diff", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/flat/1/edits", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[ownerID]))
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+}
+
+func TestFlatPriceHistory(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	ownerID := "owner-uuid"
+	oldPrice := 120
+	changedAt := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).Return(&models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 2,
+		Status: models.StatusApproved, CreatedBy: &ownerID, PreviousPrice: &oldPrice, PriceChangedAt: &changedAt}, nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 2, false).Return(&models.Flat{ID: 2, HouseID: 2, Price: 100, Rooms: 2,
+		Status: models.StatusCreated, CreatedBy: &ownerID}, nil)
+	flatRepoMock.On("GetPriceHistory", mock.Anything, 1).Return([]models.PriceChange{
+		{Price: oldPrice, ChangedBy: &ownerID, ChangedAt: changedAt.Add(-24 * time.Hour)},
+		{Price: 100, ChangedBy: &ownerID, ChangedAt: changedAt},
+	}, nil)
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	token, err := authS.GenerateToken("stranger-uuid", "client")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Flat shows the discount", func(t *testing.T) {
+		resp := get("/flat/1")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var flat response.FlatResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &flat); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		if assert.NotNil(t, flat.PreviousPrice) && assert.NotNil(t, flat.PriceChangedAt) {
+			assert.Equal(t, oldPrice, *flat.PreviousPrice)
+			assert.True(t, changedAt.Equal(*flat.PriceChangedAt))
+		}
+	})
+
+	t.Run("Price history oldest first", func(t *testing.T) {
+		resp := get("/flat/1/prices")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var prices struct {
+			Prices []response.PriceChangeResponse `json:"prices"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &prices); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		if assert.Len(t, prices.Prices, 2) {
+			assert.Equal(t, []int{oldPrice, 100}, []int{prices.Prices[0].Price, prices.Prices[1].Price})
+		}
+		assert.NotContains(t, resp.Body.String(), ownerID, "Who changed the price is not public")
+	})
+
+	t.Run("History of a flat the user can not see", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get("/flat/2/prices").Code)
+	})
+}
@@ -0,0 +1,503 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/handlers/common"
+	"avito/internal/handlers/response"
+	"avito/internal/lib/logger"
+	"avito/internal/lib/sender"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"avito/internal/services/flatService"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var allStatuses = []string{
+	models.StatusCreated,
+	models.StatusOnModeration,
+	models.StatusApproved,
+	models.StatusDeclined,
+}
+
+// Every pair of statuses and whether the moderation state machine allows it
+func TestFlatStatusTransitions(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{models.StatusCreated, models.StatusOnModeration}:  true,
+		{models.StatusOnModeration, models.StatusApproved}: true,
+		{models.StatusOnModeration, models.StatusDeclined}: true,
+		{models.StatusOnModeration, models.StatusCreated}:  true,
+		{models.StatusApproved, models.StatusOnModeration}: true,
+		{models.StatusDeclined, models.StatusOnModeration}: true,
+	}
+
+	log := logger.SetupLogger("prod")
+	moderatorID := "moderator-uuid"
+	declineReason := models.DeclineReasonIncorrectData
+
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			t.Run(fmt.Sprintf("%s -> %s", from, to), func(t *testing.T) {
+				expected := allowed[[2]string{from, to}]
+				assert.Equal(t, expected, flatService.CanTransition(from, to))
+
+				flatRepoMock := mocks.NewFlatRepo(t)
+				houseRepoMock := mocks.NewHouseRepo(t)
+
+				flat := &models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: from}
+				if from == models.StatusOnModeration {
+					flat.ModeratorID = &moderatorID
+				}
+				flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).Return(flat, nil)
+
+				var reason *string
+				if to == models.StatusDeclined {
+					reason = &declineReason
+				}
+
+				if expected {
+					update := models.StatusUpdate{FlatID: 1, From: from, To: to, ActorID: moderatorID, DeclineReason: reason}
+					if to == models.StatusOnModeration {
+						update.ModeratorID = &moderatorID
+						update.LeaseTTL = time.Minute
+					}
+					flatRepoMock.On("UpdateFlatStatus", mock.Anything, update).
+						Return(&models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: to}, to == models.StatusApproved, nil)
+					houseRepoMock.On("GetSubscribers", mock.Anything, 2).Return(nil, nil).Maybe()
+				}
+
+				flatS := flatService.NewService(flatRepoMock, houseRepoMock, sender.NewFileSender("", log), nil, time.Minute, log)
+				updated, err := flatS.UpdateStatus(context.Background(), 1, to, moderatorID, nil, reason)
+
+				if expected {
+					assert.NoError(t, err)
+					assert.Equal(t, to, updated.Status)
+					return
+				}
+
+				assert.ErrorIs(t, err, flatService.ErrInvalidTransition)
+				var transitionErr *flatService.TransitionError
+				if assert.True(t, errors.As(err, &transitionErr)) {
+					assert.Equal(t, from, transitionErr.From)
+					assert.Equal(t, to, transitionErr.To)
+				}
+			})
+		}
+	}
+}
+
+func TestFlatStatusUnknownStatus(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	flatRepoMock := mocks.NewFlatRepo(t)
+	houseRepoMock := mocks.NewHouseRepo(t)
+
+	flatS := flatService.NewService(flatRepoMock, houseRepoMock, sender.NewFileSender("", log), nil, time.Minute, log)
+	_, err := flatS.UpdateStatus(context.Background(), 1, "sold", "moderator-uuid", nil, nil)
+
+	assert.ErrorIs(t, err, flatService.ErrInvalidStatus)
+	for _, status := range allStatuses {
+		assert.False(t, flatService.CanTransition("sold", status))
+		assert.False(t, flatService.CanTransition(status, "sold"))
+	}
+}
+
+// recordingSender keeps notifications instead of delivering them
+type recordingSender struct {
+	recipients []string
+}
+
+func (s *recordingSender) Send(_ context.Context, recipient string, _ models.Flat) error {
+	s.recipients = append(s.recipients, recipient)
+	return nil
+}
+
+func TestFlatReapprovalDoesNotNotify(t *testing.T) {
+	log := logger.SetupLogger("prod")
+	moderatorID := "moderator-uuid"
+
+	flatRepoMock := mocks.NewFlatRepo(t)
+	houseRepoMock := mocks.NewHouseRepo(t)
+
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).
+		Return(&models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusOnModeration, ModeratorID: &moderatorID}, nil)
+	update := models.StatusUpdate{FlatID: 1, From: models.StatusOnModeration, To: models.StatusApproved, ActorID: moderatorID}
+	approved := &models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusApproved}
+	flatRepoMock.On("UpdateFlatStatus", mock.Anything, update).Return(approved, true, nil).Once()
+	flatRepoMock.On("UpdateFlatStatus", mock.Anything, update).Return(approved, false, nil).Once()
+	houseRepoMock.On("GetSubscribers", mock.Anything, 2).Return([]string{"subscriber@example.com"}, nil).Once()
+
+	notifier := &recordingSender{}
+	flatS := flatService.NewService(flatRepoMock, houseRepoMock, notifier, nil, time.Minute, log)
+
+	_, err := flatS.UpdateStatus(context.Background(), 1, models.StatusApproved, moderatorID, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"subscriber@example.com"}, notifier.recipients)
+
+	// The flat was edited after approval and went through moderation again
+	_, err = flatS.UpdateStatus(context.Background(), 1, models.StatusApproved, moderatorID, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, notifier.recipients, 1, "Re-approval must not notify subscribers again")
+}
+
+func TestFlatStatusHandlerErrors(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).
+		Return(&models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusApproved}, nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 404, false).
+		Return(nil, nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 3, false).
+		Return(&models.Flat{ID: 3, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusCreated}, nil)
+	flatRepoMock.On("UpdateFlatStatus", mock.Anything, mock.MatchedBy(func(u models.StatusUpdate) bool { return u.FlatID == 3 })).
+		Return(nil, false, fmt.Errorf("repository.flat.UpdateFlatStatus: %w", repositories.ErrFlatStatusChanged))
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	token, err := authS.GenerateToken("moderator-uuid", "moderator")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	cases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"Illegal transition", `{"id": 1, "status": "created"}`, http.StatusConflict},
+		{"Unknown status", `{"id": 1, "status": "sold"}`, http.StatusBadRequest},
+		{"Unknown flat", `{"id": 404, "status": "on moderation"}`, http.StatusNotFound},
+		{"Lost the race", `{"id": 3, "status": "on moderation"}`, http.StatusConflict},
+		{"Decline without reason", `{"id": 1, "status": "declined"}`, http.StatusBadRequest},
+		{"Unknown decline reason", `{"id": 1, "status": "declined", "reason": "ugly"}`, http.StatusBadRequest},
+		{"Reason without declining", `{"id": 1, "status": "approved", "reason": "other"}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/flat/update", strings.NewReader(tc.body))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+		})
+	}
+}
+
+func TestFlatStatusHistory(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	comment := "photos do not match the description"
+	changedAt := time.Date(2024, 8, 16, 18, 37, 59, 0, time.UTC)
+
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).
+		Return(&models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusDeclined}, nil)
+	flatRepoMock.On("GetStatusHistory", mock.Anything, 1).
+		Return([]models.StatusChange{
+			{ID: 1, FlatID: 1, PreviousStatus: models.StatusCreated, NewStatus: models.StatusOnModeration, ActorID: "moderator-uuid", ChangedAt: changedAt},
+			{ID: 2, FlatID: 1, PreviousStatus: models.StatusOnModeration, NewStatus: models.StatusDeclined, ActorID: "moderator-uuid", Comment: &comment, ChangedAt: changedAt},
+		}, nil)
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	t.Run("Client is forbidden", func(t *testing.T) {
+		token, _ := authS.GenerateToken("client-uuid", "client")
+		req := httptest.NewRequest("GET", "/flat/1/history", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Moderator gets history", func(t *testing.T) {
+		token, _ := authS.GenerateToken("moderator-uuid", "moderator")
+		req := httptest.NewRequest("GET", "/flat/1/history", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var actualResponse map[string][]response.StatusChangeResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &actualResponse); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+
+		history := actualResponse["history"]
+		if assert.Len(t, history, 2) {
+			assert.Equal(t, models.StatusDeclined, history[1].NewStatus)
+			assert.Equal(t, "moderator-uuid", history[1].ActorID)
+			assert.Equal(t, &comment, history[1].Comment)
+		}
+	})
+}
+
+func TestRenewModeration(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	moderatorID := "moderator-uuid"
+	expiresAt := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+
+	flatRepoMock.On("RenewModeration", mock.Anything, 1, moderatorID, time.Minute).
+		Return(&models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusOnModeration,
+			ModeratorID: &moderatorID, ModerationExpiresAt: &expiresAt}, nil)
+	flatRepoMock.On("RenewModeration", mock.Anything, 2, moderatorID, time.Minute).
+		Return(nil, repositories.ErrFlatStatusChanged)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 2, false).
+		Return(&models.Flat{ID: 2, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusCreated}, nil)
+	flatRepoMock.On("RenewModeration", mock.Anything, 404, moderatorID, time.Minute).
+		Return(nil, repositories.ErrFlatStatusChanged)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 404, false).
+		Return(nil, nil)
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	token, err := authS.GenerateToken(moderatorID, "moderator")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	cases := []struct {
+		name   string
+		flatID int
+		code   int
+	}{
+		{"Lease is extended", 1, http.StatusOK},
+		{"Flat is not held by the moderator", 2, http.StatusConflict},
+		{"Unknown flat", 404, http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", fmt.Sprintf("/flat/%d/renew", tc.flatID), nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+
+			if tc.code == http.StatusOK {
+				var flatResponse response.FlatResponse
+				if err := json.Unmarshal(resp.Body.Bytes(), &flatResponse); err != nil {
+					t.Fatal("Failed to unmarshal response:", err)
+				}
+				if assert.NotNil(t, flatResponse.ModerationExpiresAt) {
+					assert.True(t, expiresAt.Equal(*flatResponse.ModerationExpiresAt))
+				}
+			}
+		})
+	}
+}
+
+func TestLeaseReaper(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	flatRepoMock := mocks.NewFlatRepo(t)
+	flatRepoMock.On("ReleaseExpiredModerations", mock.Anything).
+		Return([]models.Flat{
+			{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusCreated},
+			{ID: 3, HouseID: 2, Price: 300, Rooms: 3, Status: models.StatusCreated},
+		}, nil).Once()
+
+	reaper := flatService.NewLeaseReaper(flatRepoMock, time.Minute, log)
+
+	released, err := reaper.ReleaseExpired(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, released)
+}
+
+func TestModerationQueue(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	moderatorID := "moderator-uuid"
+	houseID := 2
+
+	flatRepoMock.On("GetModerationQueue", mock.Anything, (*int)(nil), common.DefaultLimit, 0).
+		Return([]models.Flat{
+			{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusCreated},
+			{ID: 2, HouseID: 3, Price: 200, Rooms: 2, Status: models.StatusCreated},
+		}, nil)
+	flatRepoMock.On("GetModerationQueue", mock.Anything, &houseID, common.MaxLimit, 10).
+		Return([]models.Flat{}, nil)
+	flatRepoMock.On("ClaimNextFlat", mock.Anything, moderatorID, (*int)(nil), time.Minute).
+		Return(&models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 1, Status: models.StatusOnModeration,
+			ModeratorID: &moderatorID}, nil).Once()
+	flatRepoMock.On("ClaimNextFlat", mock.Anything, moderatorID, &houseID, time.Minute).
+		Return(nil, nil).Once()
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	token, err := authS.GenerateToken(moderatorID, "moderator")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	cases := []struct {
+		name    string
+		method  string
+		url     string
+		code    int
+		flatIDs []int
+	}{
+		{"Queue with defaults", "GET", "/moderation/queue", http.StatusOK, []int{1, 2}},
+		{"Queue filtered by house, limit capped", "GET", "/moderation/queue?house_id=2&limit=1000&offset=10", http.StatusOK, []int{}},
+		{"Queue with invalid limit", "GET", "/moderation/queue?limit=-1", http.StatusBadRequest, nil},
+		{"Queue with invalid house", "GET", "/moderation/queue?house_id=abc", http.StatusBadRequest, nil},
+		{"Claim next", "POST", "/moderation/claim-next", http.StatusOK, []int{1}},
+		{"Claim next from empty queue", "POST", "/moderation/claim-next?house_id=2", http.StatusNoContent, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+
+			var flatIDs []int
+			if tc.method == "GET" {
+				var queueResponse struct {
+					Flats []response.FlatResponse `json:"flats"`
+				}
+				if err := json.Unmarshal(resp.Body.Bytes(), &queueResponse); err != nil {
+					t.Fatal("Failed to unmarshal response:", err)
+				}
+				flatIDs = []int{}
+				for _, flat := range queueResponse.Flats {
+					flatIDs = append(flatIDs, flat.ID)
+				}
+			} else {
+				var flatResponse response.FlatResponse
+				if err := json.Unmarshal(resp.Body.Bytes(), &flatResponse); err != nil {
+					t.Fatal("Failed to unmarshal response:", err)
+				}
+				assert.Equal(t, models.StatusOnModeration, flatResponse.Status)
+				flatIDs = []int{flatResponse.ID}
+			}
+			assert.Equal(t, tc.flatIDs, flatIDs)
+		})
+	}
+
+	clientToken, err := authS.GenerateToken("client-uuid", "client")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	req := httptest.NewRequest("POST", "/moderation/claim-next", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", clientToken))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestDeclineAndResubmit(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	moderatorID := "moderator-uuid"
+	ownerID := "owner-uuid"
+	reason := models.DeclineReasonWrongPrice
+	comment := "price is too low"
+
+	onModeration := &models.Flat{ID: 1, HouseID: 2, Price: 1, Rooms: 1, Status: models.StatusOnModeration,
+		ModeratorID: &moderatorID, CreatedBy: &ownerID}
+	declined := &models.Flat{ID: 1, HouseID: 2, Price: 1, Rooms: 1, Status: models.StatusDeclined,
+		CreatedBy: &ownerID, DeclineReason: &reason, DeclineComment: &comment}
+
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).Return(onModeration, nil).Once()
+	flatRepoMock.On("UpdateFlatStatus", mock.Anything, models.StatusUpdate{
+		FlatID: 1, From: models.StatusOnModeration, To: models.StatusDeclined,
+		ActorID: moderatorID, Comment: &comment, DeclineReason: &reason,
+	}).Return(declined, false, nil).Once()
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).Return(declined, nil)
+	flatRepoMock.On("UpdateFlatStatus", mock.Anything, models.StatusUpdate{
+		FlatID: 1, From: models.StatusDeclined, To: models.StatusCreated, ActorID: ownerID,
+	}).Return(&models.Flat{ID: 1, HouseID: 2, Price: 1, Rooms: 1, Status: models.StatusCreated, CreatedBy: &ownerID}, false, nil).Once()
+	flatRepoMock.On("GetFlatByID", mock.Anything, 2, false).
+		Return(&models.Flat{ID: 2, HouseID: 2, Price: 1, Rooms: 1, Status: models.StatusApproved, CreatedBy: &ownerID}, nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 404, false).Return(nil, nil)
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	tokens := make(map[string]string)
+	for userID, role := range map[string]models.Role{moderatorID: "moderator", ownerID: "client", "stranger-uuid": "client"} {
+		token, err := authS.GenerateToken(userID, role)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens[userID] = token
+	}
+
+	cases := []struct {
+		name          string
+		user          string
+		method        string
+		url           string
+		body          string
+		code          int
+		declineReason string
+	}{
+		{"Moderator declines with reason", moderatorID, "POST", "/flat/update",
+			`{"id": 1, "status": "declined", "reason": "wrong_price", "comment": "price is too low"}`, http.StatusOK, reason},
+		{"Owner sees the reason", ownerID, "GET", "/flat/1", "", http.StatusOK, reason},
+		{"Moderator sees the reason", moderatorID, "GET", "/flat/1", "", http.StatusOK, reason},
+		{"Declined flat is hidden from others", "stranger-uuid", "GET", "/flat/1", "", http.StatusNotFound, ""},
+		{"Approved flat is visible to others", "stranger-uuid", "GET", "/flat/2", "", http.StatusOK, ""},
+		{"Only the owner may resubmit", "stranger-uuid", "POST", "/flat/1/resubmit", "", http.StatusForbidden, ""},
+		{"Owner resubmits", ownerID, "POST", "/flat/1/resubmit", "", http.StatusOK, ""},
+		{"Approved flat can not be resubmitted", ownerID, "POST", "/flat/2/resubmit", "", http.StatusConflict, ""},
+		{"Unknown flat", ownerID, "POST", "/flat/404/resubmit", "", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[tc.user]))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+
+			var flatResponse response.FlatResponse
+			if err := json.Unmarshal(resp.Body.Bytes(), &flatResponse); err != nil {
+				t.Fatal("Failed to unmarshal response:", err)
+			}
+			if tc.declineReason == "" {
+				assert.Nil(t, flatResponse.DeclineReason)
+				return
+			}
+			if assert.NotNil(t, flatResponse.DeclineReason) && assert.NotNil(t, flatResponse.DeclineComment) {
+				assert.Equal(t, tc.declineReason, *flatResponse.DeclineReason)
+				assert.Equal(t, comment, *flatResponse.DeclineComment)
+			}
+		})
+	}
+}
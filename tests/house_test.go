@@ -0,0 +1,254 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/handlers/response"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHouseInfoListUpdate(t *testing.T) {
+	houseRepoMock := mocks.NewHouseRepo(t)
+
+	createdAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	lastFlatAdded := createdAt.Add(30 * time.Minute)
+	developer := "Мечта"
+	house := func() *models.House {
+		return &models.House{ID: 1, Address: "Лесная улица, 7", YearBuilt: 2000, Builder: &developer,
+			CreatedAt: createdAt, UpdatedAt: createdAt, LastFlatAdded: &lastFlatAdded}
+	}
+
+	houseRepoMock.On("GetHouseByID", mock.Anything, 1, false).
+		Return(func(context.Context, int, bool) *models.House { return house() }, nil)
+	houseRepoMock.On("GetHouseByID", mock.Anything, 404, false).Return(nil, nil)
+	houseRepoMock.On("ListHouses", mock.Anything, mock.MatchedBy(func(f models.HouseFilter) bool {
+		return f.YearFrom != nil && *f.YearFrom == 1990 && f.Developer != nil && *f.Developer == developer &&
+			!f.IncludeArchived && f.Limit == 5 && f.Offset == 0
+	})).Return([]models.House{*house()}, nil)
+	houseRepoMock.On("UpdateHouse", mock.Anything, mock.MatchedBy(func(h *models.House) bool {
+		return h.ID == 1 && h.YearBuilt == 2001 && h.Builder == nil && h.Address == "Лесная улица, 7"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.House).UpdatedAt = createdAt.Add(time.Hour)
+	}).Return(nil)
+
+	router, authS := newTestRouter(t, testDeps{houseRepo: houseRepoMock})
+
+	tokens := make(map[models.Role]string)
+	for userID, role := range map[string]models.Role{"client-uuid": "client", "moderator-uuid": "moderator"} {
+		token, err := authS.GenerateToken(userID, role)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens[role] = token
+	}
+
+	cases := []struct {
+		name   string
+		role   models.Role
+		method string
+		url    string
+		body   string
+		code   int
+	}{
+		{"House info", "client", "GET", "/house/1/info", "", http.StatusOK},
+		{"Unknown house info", "client", "GET", "/house/404/info", "", http.StatusNotFound},
+		{"List houses", "client", "GET", "/houses?year_from=1990&developer=Мечта&limit=5", "", http.StatusOK},
+		{"Empty year range", "client", "GET", "/houses?year_from=2010&year_to=2000", "", http.StatusBadRequest},
+		{"Invalid limit", "client", "GET", "/houses?limit=-1", "", http.StatusBadRequest},
+		{"Moderator updates house", "moderator", "PATCH", "/house/1", `{"year": 2001, "developer": ""}`, http.StatusOK},
+		{"Client can not update house", "client", "PATCH", "/house/1", `{"year": 2001}`, http.StatusForbidden},
+		{"Empty address", "moderator", "PATCH", "/house/1", `{"address": ""}`, http.StatusBadRequest},
+		{"Unknown house update", "moderator", "PATCH", "/house/404", `{"year": 2001}`, http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, bytes.NewBufferString(tc.body))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[tc.role]))
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+		})
+	}
+
+	// Info exposes last_flat_added and the real updated_at
+	req := httptest.NewRequest("GET", "/house/1/info", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens["client"]))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var infoResponse response.HouseResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &infoResponse); err != nil {
+		t.Fatal("Failed to unmarshal response:", err)
+	}
+	assert.Equal(t, developer, infoResponse.Developer)
+	assert.Equal(t, createdAt, infoResponse.UpdateAt.UTC())
+	if assert.NotNil(t, infoResponse.LastFlatAdded) {
+		assert.Equal(t, lastFlatAdded, infoResponse.LastFlatAdded.UTC())
+	}
+
+	// The update bumps updated_at and clears the developer
+	req = httptest.NewRequest("PATCH", "/house/1", bytes.NewBufferString(`{"year": 2001, "developer": ""}`))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens["moderator"]))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var updateResponse response.HouseResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &updateResponse); err != nil {
+		t.Fatal("Failed to unmarshal response:", err)
+	}
+	assert.Equal(t, 2001, updateResponse.Year)
+	assert.Empty(t, updateResponse.Developer)
+	assert.True(t, updateResponse.UpdateAt.After(infoResponse.UpdateAt))
+}
+
+func TestHouseFlatsPagination(t *testing.T) {
+	houseRepoMock := mocks.NewHouseRepo(t)
+
+	flat := func(id, price int) models.Flat {
+		return models.Flat{ID: id, HouseID: 1, Price: price, Rooms: 2, Status: models.StatusApproved}
+	}
+
+	// limit=2 asks the repository for 3 flats; the third one only proves there is a next page
+	houseRepoMock.On("GetFlatsByHouseID", mock.Anything, 1, "client-uuid", models.RoleClient, false,
+		models.FlatPage{SortBy: models.FlatSortPrice, Desc: true, Limit: 3}).
+		Return([]models.Flat{flat(4, 300), flat(2, 200), flat(7, 200)}, nil)
+	houseRepoMock.On("GetFlatsByHouseID", mock.Anything, 1, "client-uuid", models.RoleClient, false,
+		models.FlatPage{SortBy: models.FlatSortPrice, Desc: true, Limit: 3,
+			After: &models.FlatCursor{SortBy: models.FlatSortPrice, Desc: true, Value: 200, ID: 2}}).
+		Return([]models.Flat{flat(7, 200)}, nil)
+
+	router, authS := newTestRouter(t, testDeps{houseRepo: houseRepoMock})
+
+	token, err := authS.GenerateToken("client-uuid", "client")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	get := func(url string) (int, []response.FlatResponse, string) {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		var page struct {
+			Flats      []response.FlatResponse `json:"flats"`
+			NextCursor string                  `json:"next_cursor"`
+		}
+		if resp.Code == http.StatusOK {
+			if err := json.Unmarshal(resp.Body.Bytes(), &page); err != nil {
+				t.Fatal("Failed to unmarshal response:", err)
+			}
+		}
+		return resp.Code, page.Flats, page.NextCursor
+	}
+
+	code, flats, cursor := get("/house/1?sort=price&order=desc&limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, flats, 2) {
+		assert.Equal(t, 4, flats[0].ID)
+		assert.Equal(t, 2, flats[1].ID)
+	}
+	assert.NotEmpty(t, cursor)
+
+	code, flats, next := get("/house/1?sort=price&order=desc&limit=2&cursor=" + cursor)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, flats, 1) {
+		assert.Equal(t, 7, flats[0].ID)
+	}
+	assert.Empty(t, next, "Last page has no next cursor")
+
+	// A cursor is only valid for the ordering it was issued for
+	for _, url := range []string{
+		"/house/1?sort=price&order=asc&limit=2&cursor=" + cursor,
+		"/house/1?sort=rooms&order=desc&limit=2&cursor=" + cursor,
+		"/house/1?cursor=garbage",
+		"/house/1?sort=floor",
+		"/house/1?order=sideways",
+		"/house/1?limit=0",
+	} {
+		code, _, _ := get(url)
+		assert.Equal(t, http.StatusBadRequest, code, url)
+	}
+}
+
+func TestCreateDuplicateHouse(t *testing.T) {
+	houseRepoMock := mocks.NewHouseRepo(t)
+
+	const normalized = "москва, улица ленина, дом 5, корпус 2"
+	existing := &models.House{ID: 42, Address: "г. Москва, ул. Ленина, д. 5к2", NormalizedAddress: normalized, YearBuilt: 1970}
+
+	houseRepoMock.On("FindHouseByAddress", mock.Anything, normalized).Return(existing, nil).Once()
+	houseRepoMock.On("FindHouseByAddress", mock.Anything, "москва, улица ленина, дом 7").Return(nil, nil).Once()
+	// A concurrent request saved the same address between the check and the insert
+	houseRepoMock.On("CreateHouse", mock.Anything, mock.MatchedBy(func(h *models.House) bool {
+		return h.NormalizedAddress == "москва, улица ленина, дом 7"
+	})).Return(fmt.Errorf("repositories.house.CreateHouse: %w", repositories.ErrHouseExists))
+	houseRepoMock.On("FindHouseByAddress", mock.Anything, "москва, улица ленина, дом 7").
+		Return(&models.House{ID: 43}, nil).Once()
+	houseRepoMock.On("GetHouseByID", mock.Anything, 44, false).
+		Return(&models.House{ID: 44, Address: "ул. Ленина, 9", YearBuilt: 1975}, nil)
+	houseRepoMock.On("FindHouseByAddress", mock.Anything, normalized).Return(existing, nil).Once()
+
+	router, authS := newTestRouter(t, testDeps{houseRepo: houseRepoMock})
+
+	token, err := authS.GenerateToken("moderator-uuid", "moderator")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		code     int
+		existing int
+	}{
+		{"Same building spelled differently", "POST", "/house/create",
+			`{"address": "Ленина улица, 5 корп. 2, Москва, 125009", "year": 1970}`, http.StatusConflict, 42},
+		{"Duplicate saved concurrently", "POST", "/house/create",
+			`{"address": "Москва, ул. Ленина, 7", "year": 1980}`, http.StatusConflict, 43},
+		{"Address changed to an existing one", "PATCH", "/house/44",
+			`{"address": "Москва, Ленина ул., д. 5к2"}`, http.StatusConflict, 42},
+		{"Address without content", "POST", "/house/create",
+			`{"address": " , ", "year": 1980}`, http.StatusBadRequest, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, bytes.NewBufferString(tc.body))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+			if tc.code != http.StatusConflict {
+				return
+			}
+
+			var conflictResponse struct {
+				HouseID int `json:"house_id"`
+			}
+			if err := json.Unmarshal(resp.Body.Bytes(), &conflictResponse); err != nil {
+				t.Fatal("Failed to unmarshal response:", err)
+			}
+			assert.Equal(t, tc.existing, conflictResponse.HouseID)
+		})
+	}
+}
@@ -1,28 +1,38 @@
 package avito_test
 
 import (
-	"avito/internal/handlers/authHandler"
-	"avito/internal/handlers/flatHandler"
-	"avito/internal/handlers/houseHandler"
+	"avito/internal/domain/models"
+	"avito/internal/handlers/common"
+	"avito/internal/lib/blobstore"
 	"avito/internal/lib/logger"
+	"avito/internal/lib/sender"
+	"avito/internal/repositories"
 	"avito/internal/repositories/authRepo"
 	"avito/internal/repositories/flatRepo"
 	"avito/internal/repositories/houseRepo"
+	"avito/internal/repositories/searchRepo"
 	"avito/internal/services/authService"
 	"avito/internal/services/flatService"
 	"avito/internal/services/houseService"
-	"avito/internal/setup"
+	"avito/internal/services/searchService"
 	"avito/internal/storage"
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"avito/internal/config"
 	_ "github.com/lib/pq"
@@ -68,6 +78,22 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// createInviter stores a moderator directly, the way the first moderators are seeded, so that it can mint invites
+func createInviter(t *testing.T, authR authRepo.AuthRepo) string {
+	t.Helper()
+
+	inviter := &models.User{
+		Email:    fmt.Sprintf("inviter-%d@example.com", time.Now().UnixNano()),
+		Password: "not-a-bcrypt-hash",
+		Role:     models.RoleModerator,
+	}
+	userID, err := authR.CreateUser(context.Background(), inviter)
+	if err != nil {
+		t.Fatal("Failed to create inviter:", err)
+	}
+	return userID
+}
+
 func TestGetFlatsByIdWithRegistation(t *testing.T) {
 	log := logger.SetupLogger("debug")
 
@@ -75,18 +101,11 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 	houseR := houseRepo.NewRepository(conn, log)
 	flatR := flatRepo.NewRepository(conn, log)
 
-	authS := authService.NewService(authR, "secret", log)
-	houseS := houseService.NewService(houseR, log)
-	flatS := flatService.NewService(flatR, log)
-
-	authH := authHandler.NewHandler(authS, log)
-	houseH := houseHandler.NewHandler(houseS, log)
-	flatH := flatHandler.NewHandler(flatS, log)
-
-	router := setup.SetupRouter(authH, houseH, flatH, log)
+	router, authS := newTestRouter(t, testDeps{authRepo: authR, houseRepo: houseR, flatRepo: flatR, searchRepo: searchRepo.NewRepository(conn, log)})
 
 	var userID string
 	var houseID int
+	var flatID int
 
 	t.Run("Dummy login as client", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/dummyLogin?user_type=client", nil)
@@ -100,10 +119,10 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 		assert.Contains(t, resp.Body.String(), expectedSubstring)
 	})
 
-	t.Run("Register moderator", func(t *testing.T) {
+	t.Run("Register moderator without invite", func(t *testing.T) {
 		body := `{
             "email": "moderator@example.com",
-            "password": "pass",
+            "password": "passw0rd",
             "user_type": "moderator"
         }`
 		req := httptest.NewRequest("POST", "/register", strings.NewReader(body))
@@ -112,10 +131,30 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 
 		router.ServeHTTP(resp, req)
 
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Register moderator", func(t *testing.T) {
+		invite, err := authS.CreateInvite(context.Background(), createInviter(t, authR), models.RoleModerator)
+		if err != nil {
+			t.Fatal("Failed to create invite:", err)
+		}
+		body := fmt.Sprintf(`{
+            "email": "moderator@example.com",
+            "password": "passw0rd",
+            "user_type": "moderator",
+            "invite_code": %q
+        }`, invite.Code)
+		req := httptest.NewRequest("POST", "/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
 		assert.Equal(t, http.StatusOK, resp.Code)
 
 		var result map[string]string
-		err := json.Unmarshal(resp.Body.Bytes(), &result)
+		err = json.Unmarshal(resp.Body.Bytes(), &result)
 		if err != nil {
 			t.Fatal("Failed to unmarshal response:", err)
 		}
@@ -127,7 +166,7 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 	t.Run("Register client", func(t *testing.T) {
 		body := `{
             "email": "client@example.com",
-            "password": "pass",
+            "password": "passw0rd",
             "user_type": "client"
         }`
 		req := httptest.NewRequest("POST", "/register", strings.NewReader(body))
@@ -142,7 +181,7 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 	t.Run("Login as moderator", func(t *testing.T) {
 		body := `{
             "id": "` + userID + `",
-            "password": "pass"
+            "password": "passw0rd"
         }`
 		req := httptest.NewRequest("POST", "/login", strings.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -152,22 +191,28 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, resp.Code)
 
-		var result map[string]string
+		var result struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+		}
 		err := json.Unmarshal(resp.Body.Bytes(), &result)
 		if err != nil {
 			t.Fatal("Failed to unmarshal response:", err)
 		}
 
-		token = result["token"]
+		token = result.Token
+		assert.NotEmpty(t, result.RefreshToken, "Expected a refresh token")
 		assert.NotEmpty(t, token, "Expected a valid token")
 	})
 
 	t.Run("Create house as moderator", func(t *testing.T) {
-		body := `{
-            "address": "Лесная улица, 7, Москва, 125196",
+		// Houses with the same address are rejected, the test database outlives a single run
+		address := fmt.Sprintf("Лесная улица, %d, Москва, 125196", time.Now().UnixNano()%1000000)
+		body := fmt.Sprintf(`{
+            "address": %q,
             "year": 2000,
             "developer": "Мэрия города"
-        }`
+        }`, address)
 		req := httptest.NewRequest("POST", "/house/create", strings.NewReader(body))
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 		req.Header.Set("Content-Type", "application/json")
@@ -183,7 +228,7 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 			t.Fatal("Failed to unmarshal response:", err)
 		}
 
-		assert.Equal(t, "Лесная улица, 7, Москва, 125196", houseResponse["address"])
+		assert.Equal(t, address, houseResponse["address"])
 		assert.Equal(t, float64(2000), houseResponse["year"])
 		assert.Equal(t, "Мэрия города", houseResponse["developer"])
 
@@ -216,6 +261,14 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 		assert.Equal(t, float64(10000), flatResponse["price"])
 		assert.Equal(t, float64(4), flatResponse["rooms"])
 		assert.Equal(t, "created", flatResponse["status"])
+
+		flatID = int(flatResponse["id"].(float64))
+
+		var events int
+		err = conn.QueryRow("SELECT COUNT(*) FROM outbox WHERE event_type = 'flat.created' AND aggregate_id = $1",
+			flatID).Scan(&events)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, events, "Expected flat.created event in the outbox")
 	})
 
 	t.Run("Get flats in house as moderator", func(t *testing.T) {
@@ -242,4 +295,1016 @@ func TestGetFlatsByIdWithRegistation(t *testing.T) {
 		assert.Equal(t, float64(4), flats[0]["rooms"])
 		assert.Equal(t, "created", flats[0]["status"])
 	})
+
+	t.Run("Moderate flat and read its history", func(t *testing.T) {
+		for _, status := range []string{"on moderation", "approved"} {
+			body := fmt.Sprintf(`{"id": %d, "status": "%s", "comment": "checked"}`, flatID, status)
+			req := httptest.NewRequest("POST", "/flat/update", strings.NewReader(body))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+		}
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/flat/%d/history", flatID), nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var actualResponse map[string][]map[string]interface{}
+		err := json.Unmarshal(resp.Body.Bytes(), &actualResponse)
+		if err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+
+		history := actualResponse["history"]
+		if assert.Len(t, history, 2) {
+			assert.Equal(t, "created", history[0]["previous_status"])
+			assert.Equal(t, "approved", history[1]["new_status"])
+			assert.Equal(t, userID, history[1]["actor_id"])
+		}
+	})
+}
+
+// Many moderators try to take the same flat on moderation at once: exactly one must win
+func TestTakeFlatOnModerationConcurrently(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+
+	house := &models.House{Address: "Лесная улица, 9, Москва, 125196", YearBuilt: 2001}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	flatID, err := flatR.CreateFlat(context.Background(), &models.Flat{HouseID: house.ID, Price: 10000, Rooms: 2, Status: models.StatusCreated})
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+
+	const moderators = 20
+
+	var wg sync.WaitGroup
+	var winners, losers atomic.Int32
+	start := make(chan struct{})
+
+	for i := 0; i < moderators; i++ {
+		moderatorID := fmt.Sprintf("00000000-0000-4000-8000-%012d", i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			_, err := flatS.UpdateStatus(context.Background(), flatID, models.StatusOnModeration, moderatorID, nil, nil)
+			switch {
+			case err == nil:
+				winners.Add(1)
+			case errors.Is(err, flatService.ErrLostRace), errors.Is(err, flatService.ErrFlatBeingModerated):
+				losers.Add(1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), winners.Load(), "Expected exactly one moderator to take the flat")
+	assert.Equal(t, int32(moderators-1), losers.Load())
+
+	var history int
+	err = conn.QueryRow("SELECT COUNT(*) FROM flat_status_history WHERE flat_id = $1", flatID).Scan(&history)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, history, "Expected a single status change in history")
+}
+
+func TestModerationLeaseExpires(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Millisecond, log)
+
+	house := &models.House{Address: "Лесная улица, 11, Москва, 125196", YearBuilt: 2002}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	flatID, err := flatR.CreateFlat(context.Background(), &models.Flat{HouseID: house.ID, Price: 10000, Rooms: 2, Status: models.StatusCreated})
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+
+	moderatorID := "00000000-0000-4000-8000-000000000100"
+	flat, err := flatS.UpdateStatus(context.Background(), flatID, models.StatusOnModeration, moderatorID, nil, nil)
+	if err != nil {
+		t.Fatal("Failed to take flat on moderation:", err)
+	}
+	assert.NotNil(t, flat.ModerationExpiresAt)
+
+	time.Sleep(10 * time.Millisecond)
+
+	reaper := flatService.NewLeaseReaper(flatR, time.Minute, log)
+	released, err := reaper.ReleaseExpired(context.Background())
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, released, 1)
+
+	flat, err = flatR.GetFlatByID(context.Background(), flatID, false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.StatusCreated, flat.Status)
+		assert.Nil(t, flat.ModeratorID)
+		assert.Nil(t, flat.ModerationExpiresAt)
+	}
+
+	history, err := flatR.GetStatusHistory(context.Background(), flatID)
+	if assert.NoError(t, err) && assert.Len(t, history, 2) {
+		assert.Equal(t, models.StatusCreated, history[1].NewStatus)
+		assert.Empty(t, history[1].ActorID)
+	}
+}
+
+func TestClaimNextFlatConcurrently(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+
+	house := &models.House{Address: "Лесная улица, 11, Москва, 125196", YearBuilt: 2003}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	const flats = 5
+	const moderators = 10
+
+	for i := 0; i < flats; i++ {
+		if _, err := flatR.CreateFlat(context.Background(), &models.Flat{HouseID: house.ID, Price: 10000, Rooms: 2, Status: models.StatusCreated}); err != nil {
+			t.Fatal("Failed to create flat:", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claimed := make(map[int]string)
+	var empty atomic.Int32
+	start := make(chan struct{})
+
+	for i := 0; i < moderators; i++ {
+		moderatorID := fmt.Sprintf("00000000-0000-4000-9000-%012d", i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			flat, err := flatS.ClaimNextFlat(context.Background(), moderatorID, &house.ID)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if flat == nil {
+				empty.Add(1)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if previous, ok := claimed[flat.ID]; ok {
+				t.Errorf("Flat %d claimed by both %s and %s", flat.ID, previous, moderatorID)
+			}
+			claimed[flat.ID] = moderatorID
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	assert.Len(t, claimed, flats, "Expected every flat to be claimed exactly once")
+	assert.Equal(t, int32(moderators-flats), empty.Load())
+
+	queue, err := flatS.GetModerationQueue(context.Background(), &house.ID, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+}
+
+func TestDeclineAndResubmitFlat(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+
+	house := &models.House{Address: "Лесная улица, 13, Москва, 125196", YearBuilt: 2004}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	ownerID := "00000000-0000-4000-a000-000000000001"
+	moderatorID := "00000000-0000-4000-a000-000000000002"
+
+	flat, err := flatS.Create(context.Background(), house.ID, nil, 1, 2, models.FlatDetails{}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+
+	_, err = flatS.UpdateStatus(context.Background(), flat.ID, models.StatusOnModeration, moderatorID, nil, nil)
+	if err != nil {
+		t.Fatal("Failed to take flat on moderation:", err)
+	}
+
+	reason := models.DeclineReasonWrongPrice
+	comment := "price is too low"
+	declined, err := flatS.UpdateStatus(context.Background(), flat.ID, models.StatusDeclined, moderatorID, &comment, &reason)
+	if err != nil {
+		t.Fatal("Failed to decline flat:", err)
+	}
+	if assert.NotNil(t, declined.DeclineReason) && assert.NotNil(t, declined.DeclineComment) {
+		assert.Equal(t, reason, *declined.DeclineReason)
+		assert.Equal(t, comment, *declined.DeclineComment)
+	}
+
+	seen, err := flatS.GetFlat(context.Background(), flat.ID, ownerID, "client", false)
+	assert.NoError(t, err)
+	if assert.NotNil(t, seen) && assert.NotNil(t, seen.DeclineReason) {
+		assert.Equal(t, reason, *seen.DeclineReason)
+	}
+
+	_, err = flatS.Resubmit(context.Background(), flat.ID, moderatorID)
+	assert.ErrorIs(t, err, flatService.ErrNotFlatOwner)
+
+	resubmitted, err := flatS.Resubmit(context.Background(), flat.ID, ownerID)
+	if err != nil {
+		t.Fatal("Failed to resubmit flat:", err)
+	}
+	assert.Equal(t, models.StatusCreated, resubmitted.Status)
+	assert.Nil(t, resubmitted.DeclineReason)
+	assert.Nil(t, resubmitted.DeclineComment)
+
+	history, err := flatS.GetStatusHistory(context.Background(), flat.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 3) {
+		assert.Equal(t, ownerID, history[2].ActorID)
+		assert.Equal(t, models.StatusCreated, history[2].NewStatus)
+	}
+}
+
+// Sellers see their own flats in any status, other clients only approved ones
+func TestOwnFlatsVisibility(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+
+	house := &models.House{Address: "Лесная улица, 15, Москва, 125196", YearBuilt: 2005}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	ownerID := "00000000-0000-4000-b000-000000000001"
+	otherID := "00000000-0000-4000-b000-000000000002"
+
+	flat, err := flatS.Create(context.Background(), house.ID, nil, 10000, 2, models.FlatDetails{}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+
+	ownView, err := houseR.GetFlatsByHouseID(context.Background(), house.ID, ownerID, "client", false, models.FlatPage{})
+	assert.NoError(t, err)
+	if assert.Len(t, ownView, 1) {
+		assert.Equal(t, flat.ID, ownView[0].ID)
+		assert.Equal(t, models.StatusCreated, ownView[0].Status)
+	}
+
+	otherView, err := houseR.GetFlatsByHouseID(context.Background(), house.ID, otherID, "client", false, models.FlatPage{})
+	assert.NoError(t, err)
+	assert.Empty(t, otherView)
+
+	myFlats, err := flatS.GetUserFlats(context.Background(), ownerID, false)
+	assert.NoError(t, err)
+	if assert.Len(t, myFlats, 1) {
+		assert.Equal(t, flat.ID, myFlats[0].ID)
+		if assert.NotNil(t, myFlats[0].CreatedBy) {
+			assert.Equal(t, ownerID, *myFlats[0].CreatedBy)
+		}
+	}
+}
+
+func TestEditApprovedFlat(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+
+	house := &models.House{Address: "Лесная улица, 17, Москва, 125196", YearBuilt: 2006}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	ownerID := "00000000-0000-4000-c000-000000000001"
+	moderatorID := "00000000-0000-4000-c000-000000000002"
+
+	flat, err := flatS.Create(context.Background(), house.ID, nil, 10000, 2, models.FlatDetails{}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+	for _, status := range []string{models.StatusOnModeration, models.StatusApproved} {
+		if _, err := flatS.UpdateStatus(context.Background(), flat.ID, status, moderatorID, nil, nil); err != nil {
+			t.Fatal("Failed to moderate flat:", err)
+		}
+	}
+
+	price := 12000
+	edited, err := flatS.UpdateFlat(context.Background(), flat.ID, ownerID, flatService.FlatChanges{Price: &price})
+	if err != nil {
+		t.Fatal("Failed to edit flat:", err)
+	}
+	assert.Equal(t, price, edited.Price)
+	assert.Equal(t, models.StatusCreated, edited.Status)
+
+	edits, err := flatS.GetFlatEdits(context.Background(), flat.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, edits, 1) {
+		assert.Equal(t, ownerID, edits[0].EditorID)
+		assert.Equal(t, models.FieldChange{Old: float64(10000), New: float64(price)}, edits[0].Changes["price"])
+	}
+
+	history, err := flatS.GetStatusHistory(context.Background(), flat.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 3) {
+		assert.Equal(t, models.StatusApproved, history[2].PreviousStatus)
+		assert.Equal(t, models.StatusCreated, history[2].NewStatus)
+	}
+}
+
+func TestArchivedFlatsAreHidden(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+
+	house := &models.House{Address: "Лесная улица, 19, Москва, 125196", YearBuilt: 2007}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	ownerID := "00000000-0000-4000-d000-000000000001"
+	moderatorID := "00000000-0000-4000-d000-000000000002"
+
+	flat, err := flatS.Create(context.Background(), house.ID, nil, 10000, 2, models.FlatDetails{}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+
+	_, err = flatS.Archive(context.Background(), flat.ID, ownerID, "client")
+	if err != nil {
+		t.Fatal("Failed to archive flat:", err)
+	}
+
+	found, err := flatR.GetFlatByID(context.Background(), flat.ID, false)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	found, err = flatR.GetFlatByID(context.Background(), flat.ID, true)
+	assert.NoError(t, err)
+	if assert.NotNil(t, found) {
+		assert.NotNil(t, found.ArchivedAt)
+	}
+
+	queue, err := flatR.GetModerationQueue(context.Background(), &house.ID, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+
+	_, _, err = flatR.UpdateFlatStatus(context.Background(), models.StatusUpdate{
+		FlatID: flat.ID, From: models.StatusCreated, To: models.StatusOnModeration, ModeratorID: &moderatorID, ActorID: moderatorID, LeaseTTL: time.Minute,
+	})
+	assert.ErrorIs(t, err, repositories.ErrFlatNotFound, "An archived flat is not found rather than changed concurrently")
+
+	flats, err := houseR.GetFlatsByHouseID(context.Background(), house.ID, moderatorID, "moderator", false, models.FlatPage{})
+	assert.NoError(t, err)
+	assert.Empty(t, flats)
+
+	flats, err = houseR.GetFlatsByHouseID(context.Background(), house.ID, moderatorID, "moderator", true, models.FlatPage{})
+	assert.NoError(t, err)
+	assert.Len(t, flats, 1)
+
+	_, err = flatS.Restore(context.Background(), flat.ID, ownerID, "client")
+	if err != nil {
+		t.Fatal("Failed to restore flat:", err)
+	}
+
+	_, err = houseR.SetHouseArchived(context.Background(), house.ID, true)
+	if err != nil {
+		t.Fatal("Failed to archive house:", err)
+	}
+
+	flats, err = houseR.GetFlatsByHouseID(context.Background(), house.ID, ownerID, "client", false, models.FlatPage{})
+	assert.NoError(t, err)
+	assert.Empty(t, flats, "Flats of an archived house must be hidden")
+}
+
+func TestListAndUpdateHouses(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	houseS := houseService.NewService(houseR, log)
+
+	developer := "Строй_100%"
+	house := &models.House{Address: "Тверская улица, 13, Москва, 125009", YearBuilt: 1953, Builder: &developer}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	// LIKE wildcards in the filter are matched literally
+	wildcard := "строй_100%"
+	year := 1953
+	houses, err := houseS.ListHouses(context.Background(), models.HouseFilter{
+		YearFrom: &year, YearTo: &year, Developer: &wildcard, Limit: 10,
+	}, "client")
+	assert.NoError(t, err)
+	if assert.Len(t, houses, 1) {
+		assert.Equal(t, house.ID, houses[0].ID)
+	}
+
+	address := "Тверская улица, 13"
+	houses, err = houseS.ListHouses(context.Background(), models.HouseFilter{Address: &address, Limit: 10}, "client")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, houses)
+
+	newYear := 1954
+	updated, err := houseS.UpdateHouse(context.Background(), house.ID, houseService.HouseChanges{YearBuilt: &newYear})
+	if err != nil {
+		t.Fatal("Failed to update house:", err)
+	}
+	assert.Equal(t, newYear, updated.YearBuilt)
+	assert.Equal(t, developer, *updated.Builder)
+
+	found, err := houseS.GetHouse(context.Background(), house.ID, "client", false)
+	assert.NoError(t, err)
+	assert.Equal(t, newYear, found.YearBuilt)
+	assert.True(t, found.UpdatedAt.After(found.CreatedAt))
+}
+
+// Walking next_cursor pages yields every flat exactly once, in order, ties on price broken by id
+func TestHouseFlatsKeysetPages(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	houseS := houseService.NewService(houseR, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+
+	house := &models.House{Address: "Новый Арбат, 21, Москва, 119019", YearBuilt: 2024}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	moderatorID := "00000000-0000-4000-e000-000000000001"
+	for _, price := range []int{5000, 3000, 5000, 1000, 3000, 7000, 5000} {
+		if _, err := flatS.Create(context.Background(), house.ID, nil, price, 1, models.FlatDetails{}, moderatorID); err != nil {
+			t.Fatal("Failed to create flat:", err)
+		}
+	}
+
+	var prices []int
+	seen := make(map[int]bool)
+	listing := houseService.FlatListing{SortBy: models.FlatSortPrice, Desc: true, Limit: 3}
+	for pages := 0; pages < 10; pages++ {
+		flats, next, err := houseS.GetFlatsByHouseID(context.Background(), house.ID, moderatorID, "moderator", false, listing)
+		if err != nil {
+			t.Fatal("Failed to get flats:", err)
+		}
+		for _, flat := range flats {
+			assert.False(t, seen[flat.ID], "Flat %d is returned twice", flat.ID)
+			seen[flat.ID] = true
+			prices = append(prices, flat.Price)
+		}
+		if next == "" {
+			break
+		}
+		listing.Cursor = next
+	}
+
+	assert.Equal(t, []int{7000, 5000, 5000, 5000, 3000, 3000, 1000}, prices)
+}
+
+// Search sees the same flats as the house listing: clients get approved flats and their own ones
+func TestSearchFlatsAcrossHouses(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+	searchS := searchService.NewService(searchRepo.NewRepository(conn, log), log)
+
+	developer := fmt.Sprintf("Поиск %d", time.Now().UnixNano())
+	oldHouse := &models.House{Address: "Покровка, 3, Москва, 101000", YearBuilt: 1960, Builder: &developer}
+	newHouse := &models.House{Address: "Мясницкая улица, 24, Москва, 101000", YearBuilt: 2015, Builder: &developer}
+	for _, house := range []*models.House{oldHouse, newHouse} {
+		if err := houseR.CreateHouse(context.Background(), house); err != nil {
+			t.Fatal("Failed to create house:", err)
+		}
+	}
+
+	ownerID := "00000000-0000-4000-f000-000000000001"
+	otherID := "00000000-0000-4000-f000-000000000002"
+	moderatorID := "00000000-0000-4000-f000-000000000003"
+
+	approve := func(flat *models.Flat) {
+		for _, step := range [][2]string{
+			{models.StatusCreated, models.StatusOnModeration},
+			{models.StatusOnModeration, models.StatusApproved},
+		} {
+			_, _, err := flatR.UpdateFlatStatus(context.Background(), models.StatusUpdate{
+				FlatID: flat.ID, From: step[0], To: step[1], ModeratorID: &moderatorID, ActorID: moderatorID, LeaseTTL: time.Minute,
+			})
+			if err != nil {
+				t.Fatal("Failed to approve flat:", err)
+			}
+		}
+	}
+
+	smallArea, largeArea := 30.0, 75.5
+	low, high, floors := 2, 5, 9
+	withBalcony, euro := true, models.RenovationEuro
+	cheap, err := flatS.Create(context.Background(), oldHouse.ID, nil, 3000, 1,
+		models.FlatDetails{TotalArea: &smallArea, Floor: &low, TotalFloors: &floors}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+	approve(cheap)
+	expensive, err := flatS.Create(context.Background(), newHouse.ID, nil, 9000, 3,
+		models.FlatDetails{TotalArea: &largeArea, Floor: &high, TotalFloors: &floors, Balcony: &withBalcony, Renovation: &euro}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+	approve(expensive)
+	pending, err := flatS.Create(context.Background(), newHouse.ID, nil, 5000, 2, models.FlatDetails{}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+
+	ids := func(search models.FlatSearch) []int {
+		search.Developer = &developer
+		search.Limit = 10
+		flats, err := searchS.SearchFlats(context.Background(), search)
+		if err != nil {
+			t.Fatal("Failed to search flats:", err)
+		}
+		var ids []int
+		for _, flat := range flats {
+			ids = append(ids, flat.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int{cheap.ID, expensive.ID}, ids(models.FlatSearch{UserID: otherID, Role: "client"}))
+	assert.Equal(t, []int{cheap.ID, expensive.ID, pending.ID}, ids(models.FlatSearch{UserID: ownerID, Role: "client"}))
+	assert.Equal(t, []int{cheap.ID, expensive.ID, pending.ID}, ids(models.FlatSearch{UserID: moderatorID, Role: "moderator"}))
+
+	year := 2000
+	price := 4000
+	assert.Equal(t, []int{expensive.ID, pending.ID}, ids(models.FlatSearch{Role: "moderator", YearFrom: &year}))
+	assert.Equal(t, []int{cheap.ID}, ids(models.FlatSearch{Role: "moderator", PriceTo: &price}))
+	assert.Equal(t, []int{cheap.ID, expensive.ID}, ids(models.FlatSearch{Role: "moderator", Rooms: []int{1, 3}}))
+
+	area := 50.0
+	assert.Equal(t, []int{expensive.ID}, ids(models.FlatSearch{Role: "moderator", AreaFrom: &area}))
+	assert.Equal(t, []int{cheap.ID}, ids(models.FlatSearch{Role: "moderator", AreaTo: &area, FloorTo: &low}))
+	assert.Equal(t, []int{expensive.ID}, ids(models.FlatSearch{Role: "moderator", Balcony: &withBalcony, Renovation: &euro}))
+
+	stored, err := flatS.GetFlat(context.Background(), expensive.ID, moderatorID, "moderator", false)
+	if err != nil {
+		t.Fatal("Failed to get flat:", err)
+	}
+	assert.Equal(t, expensive.FlatDetails, stored.FlatDetails)
+
+	address := "мясницкая"
+	assert.Equal(t, []int{expensive.ID, pending.ID}, ids(models.FlatSearch{Role: "moderator", Address: &address}))
+
+	if _, err := houseR.SetHouseArchived(context.Background(), newHouse.ID, true); err != nil {
+		t.Fatal("Failed to archive house:", err)
+	}
+	assert.Equal(t, []int{cheap.ID}, ids(models.FlatSearch{Role: "moderator"}))
+}
+
+// Abbreviated and full street types find each other, other streets with a similar name are not matched
+func TestSearchHousesByAddress(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	searchS := searchService.NewService(searchRepo.NewRepository(conn, log), log)
+
+	number := time.Now().UnixNano() % 1000000
+	abbreviated := &models.House{Address: fmt.Sprintf("г. Москва, ул. Ленина, д. %d", number), YearBuilt: 1970}
+	full := &models.House{Address: fmt.Sprintf("Ленина улица, %d, корпус 2", number), YearBuilt: 1985}
+	other := &models.House{Address: fmt.Sprintf("Ленинградский проспект, %d", number), YearBuilt: 1990}
+	for _, house := range []*models.House{abbreviated, full, other} {
+		if err := houseR.CreateHouse(context.Background(), house); err != nil {
+			t.Fatal("Failed to create house:", err)
+		}
+	}
+
+	ids := func(text string) []int {
+		houses, err := searchS.SearchHouses(context.Background(), text, 10, 0)
+		if err != nil {
+			t.Fatal("Failed to search houses:", err)
+		}
+		var ids []int
+		for _, house := range houses {
+			ids = append(ids, house.ID)
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []int{abbreviated.ID, full.ID}, ids(fmt.Sprintf("Ленина улица %d", number)))
+	assert.ElementsMatch(t, []int{abbreviated.ID, full.ID}, ids(fmt.Sprintf("ул. Ленина %d", number)))
+	assert.Equal(t, []int{other.ID}, ids(fmt.Sprintf("Ленинградский пр-т %d", number)))
+
+	// Renaming the street refreshes the search vector
+	full.Address = fmt.Sprintf("Тверская улица, %d", number)
+	if err := houseR.UpdateHouse(context.Background(), full); err != nil {
+		t.Fatal("Failed to update house:", err)
+	}
+	assert.Equal(t, []int{abbreviated.ID}, ids(fmt.Sprintf("Ленина улица %d", number)))
+	assert.Equal(t, []int{full.ID}, ids(fmt.Sprintf("Тверская %d", number)))
+}
+
+func TestCreateDuplicateHouseAddress(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseS := houseService.NewService(houseRepo.NewRepository(conn, log), log)
+
+	number := time.Now().UnixNano() % 1000000
+	house, err := houseS.Create(context.Background(), fmt.Sprintf("г. Москва, ул. Лесная, д. %d, корп. 2", number), 2001, nil, nil)
+	if err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+	assert.Equal(t, fmt.Sprintf("москва, улица лесная, дом %d, корпус 2", number), house.NormalizedAddress)
+
+	_, err = houseS.Create(context.Background(), fmt.Sprintf("Лесная улица, %dк2, Москва, 125047", number), 2001, nil, nil)
+	var dupErr *houseService.DuplicateAddressError
+	if assert.ErrorAs(t, err, &dupErr) {
+		assert.Equal(t, house.ID, dupErr.HouseID)
+	}
+
+	// Another building of the same house number is not a duplicate
+	_, err = houseS.Create(context.Background(), fmt.Sprintf("г. Москва, ул. Лесная, д. %d, корп. 3", number), 2001, nil, nil)
+	assert.NoError(t, err)
+}
+
+func TestNearbyHousesByDistance(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	searchS := searchService.NewService(searchRepo.NewRepository(conn, log), log)
+
+	point := func(lat, lon float64) (*float64, *float64) { return &lat, &lon }
+
+	kremlin := models.GeoPoint{Lat: 55.7520, Lon: 37.6175}
+	near := &models.House{Address: "Красная площадь, 3, Москва", YearBuilt: 1893}
+	near.Latitude, near.Longitude = point(55.7547, 37.6215) // ~400 m
+	farther := &models.House{Address: "Тверская улица, 13, Москва", YearBuilt: 1782}
+	farther.Latitude, farther.Longitude = point(55.7616, 37.6094) // ~1.2 km
+	outside := &models.House{Address: "Невский проспект, 28, Санкт-Петербург", YearBuilt: 1904}
+	outside.Latitude, outside.Longitude = point(59.9357, 30.3260)
+	for _, house := range []*models.House{farther, outside, near} {
+		if err := houseR.CreateHouse(context.Background(), house); err != nil {
+			t.Fatal("Failed to create house:", err)
+		}
+	}
+
+	houses, err := searchS.NearbyHouses(context.Background(), kremlin, 2000, common.MaxLimit)
+	if err != nil {
+		t.Fatal("Failed to find nearby houses:", err)
+	}
+
+	// Other tests may leave houses around, only the order of ours matters
+	distances := make(map[int]float64)
+	var order []int
+	for i, house := range houses {
+		if i > 0 {
+			assert.LessOrEqual(t, houses[i-1].DistanceM, house.DistanceM)
+		}
+		switch house.ID {
+		case near.ID, farther.ID, outside.ID:
+			order = append(order, house.ID)
+			distances[house.ID] = house.DistanceM
+		}
+	}
+	assert.Equal(t, []int{near.ID, farther.ID}, order)
+	assert.InDelta(t, 400, distances[near.ID], 50)
+	assert.InDelta(t, 1200, distances[farther.ID], 100)
+}
+
+// Photos are stored with the flat, come back with it in display order and send an approved flat back to moderation
+func TestFlatPhotosRoundTrip(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatR := flatRepo.NewRepository(conn, log)
+	mediaDir := t.TempDir()
+	flatS := flatService.NewService(flatR, houseR, sender.NewFileSender("", log),
+		blobstore.NewLocalStore(mediaDir, "/media"), time.Minute, log)
+
+	house := &models.House{Address: fmt.Sprintf("Фотографическая улица, %d", time.Now().UnixNano()%1000000), YearBuilt: 2001}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	ownerID := "00000000-0000-4000-f000-000000000011"
+	moderatorID := "00000000-0000-4000-f000-000000000012"
+	flat, err := flatS.Create(context.Background(), house.ID, nil, 5000, 1, models.FlatDetails{}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+
+	first, err := flatS.AddPhoto(context.Background(), flat.ID, ownerID, models.PhotoKindPhoto, bytes.NewReader(pngImage(t, 40, 30)))
+	if err != nil {
+		t.Fatal("Failed to add photo:", err)
+	}
+	assert.Equal(t, models.StatusCreated, first.Status)
+
+	for _, step := range [][2]string{
+		{models.StatusCreated, models.StatusOnModeration},
+		{models.StatusOnModeration, models.StatusApproved},
+	} {
+		_, _, err := flatR.UpdateFlatStatus(context.Background(), models.StatusUpdate{
+			FlatID: flat.ID, From: step[0], To: step[1], ModeratorID: &moderatorID, ActorID: moderatorID, LeaseTTL: time.Minute,
+		})
+		if err != nil {
+			t.Fatal("Failed to approve flat:", err)
+		}
+	}
+
+	second, err := flatS.AddPhoto(context.Background(), flat.ID, ownerID, models.PhotoKindFloorPlan, bytes.NewReader(pngImage(t, 30, 40)))
+	if err != nil {
+		t.Fatal("Failed to add photo:", err)
+	}
+	assert.Equal(t, models.StatusCreated, second.Status, "A new photo must be moderated")
+	if !assert.Len(t, second.Photos, 2) {
+		return
+	}
+	firstID, secondID := second.Photos[0].ID, second.Photos[1].ID
+	assert.Equal(t, models.PhotoKindFloorPlan, second.Photos[1].Kind)
+	assert.FileExists(t, filepath.Join(mediaDir, filepath.FromSlash(second.Photos[1].ThumbnailKey)))
+
+	reordered, err := flatS.ReorderPhotos(context.Background(), flat.ID, ownerID, []int{secondID, firstID})
+	if err != nil {
+		t.Fatal("Failed to reorder photos:", err)
+	}
+	assert.Equal(t, []int{secondID, firstID}, []int{reordered.Photos[0].ID, reordered.Photos[1].ID})
+
+	remaining, err := flatS.DeletePhoto(context.Background(), flat.ID, secondID, ownerID)
+	if err != nil {
+		t.Fatal("Failed to delete photo:", err)
+	}
+	assert.Len(t, remaining.Photos, 1)
+	assert.Equal(t, firstID, remaining.Photos[0].ID)
+	assert.NoFileExists(t, filepath.Join(mediaDir, filepath.FromSlash(second.Photos[1].Key)))
+}
+
+// Every price of the flat is kept; the flat itself carries the price before the last change
+func TestFlatPriceHistoryTracksEdits(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	houseR := houseRepo.NewRepository(conn, log)
+	flatS := flatService.NewService(flatRepo.NewRepository(conn, log), houseR, sender.NewFileSender("", log), nil, time.Minute, log)
+
+	house := &models.House{Address: fmt.Sprintf("Ценовая улица, %d", time.Now().UnixNano()%1000000), YearBuilt: 1999}
+	if err := houseR.CreateHouse(context.Background(), house); err != nil {
+		t.Fatal("Failed to create house:", err)
+	}
+
+	ownerID := "00000000-0000-4000-f000-000000000021"
+	flat, err := flatS.Create(context.Background(), house.ID, nil, 5000, 2, models.FlatDetails{}, ownerID)
+	if err != nil {
+		t.Fatal("Failed to create flat:", err)
+	}
+	assert.Nil(t, flat.PreviousPrice)
+
+	lower, lowest, rooms := 4500, 4000, 3
+	for _, changes := range []flatService.FlatChanges{{Price: &lower}, {Rooms: &rooms}, {Price: &lowest}} {
+		if flat, err = flatS.UpdateFlat(context.Background(), flat.ID, ownerID, changes); err != nil {
+			t.Fatal("Failed to update flat:", err)
+		}
+	}
+
+	if assert.NotNil(t, flat.PreviousPrice) {
+		assert.Equal(t, 4500, *flat.PreviousPrice)
+		assert.NotNil(t, flat.PriceChangedAt)
+	}
+
+	history, err := flatS.GetPriceHistory(context.Background(), flat.ID, ownerID, "client")
+	if err != nil {
+		t.Fatal("Failed to get price history:", err)
+	}
+	var prices []int
+	for _, change := range history {
+		prices = append(prices, change.Price)
+	}
+	assert.Equal(t, []int{5000, 4500, 4000}, prices, "Editing other fields must not add a price")
+
+	// The flat shows the same last change as its history
+	stored, err := flatS.GetFlat(context.Background(), flat.ID, ownerID, "client", false)
+	if err != nil {
+		t.Fatal("Failed to get flat:", err)
+	}
+	last := history[len(history)-1]
+	if assert.NotNil(t, stored.PreviousPrice) && assert.NotNil(t, stored.PriceChangedAt) {
+		assert.Equal(t, history[len(history)-2].Price, *stored.PreviousPrice)
+		assert.True(t, last.ChangedAt.Equal(*stored.PriceChangedAt), "price_changed_at is the time of the last history entry")
+		assert.True(t, last.ChangedAt.Equal(*flat.PriceChangedAt))
+	}
+}
+
+// A refresh token can be exchanged once; logging out revokes the access token on every instance
+func TestRefreshAndLogout(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	authR := authRepo.NewRepository(conn, log)
+	authS := authService.NewService(authR, authService.NewDenylist(authR, time.Minute, log), nil, "secret", time.Hour, 24*time.Hour, 72*time.Hour, log)
+
+	email := fmt.Sprintf("refresh-%d@example.com", time.Now().UnixNano())
+	userID, err := authS.Register(context.Background(), email, "passw0rd", "client", "")
+	if err != nil {
+		t.Fatal("Failed to register user:", err)
+	}
+	user, err := authS.Login(context.Background(), userID, "passw0rd", "127.0.0.1")
+	if err != nil {
+		t.Fatal("Failed to log in:", err)
+	}
+
+	first, err := authS.IssueTokens(context.Background(), user)
+	if err != nil {
+		t.Fatal("Failed to issue tokens:", err)
+	}
+	second, err := authS.Refresh(context.Background(), first.RefreshToken)
+	if err != nil {
+		t.Fatal("Failed to refresh tokens:", err)
+	}
+
+	_, err = authS.Refresh(context.Background(), first.RefreshToken)
+	assert.ErrorIs(t, err, authService.ErrInvalidRefreshToken)
+	_, err = authS.Refresh(context.Background(), second.RefreshToken)
+	assert.ErrorIs(t, err, authService.ErrInvalidRefreshToken, "Reuse revokes the whole family")
+
+	claims, err := authS.ValidateToken(second.AccessToken)
+	if err != nil {
+		t.Fatal("Failed to validate token:", err)
+	}
+	if err := authS.Logout(context.Background(), claims, "", true); err != nil {
+		t.Fatal("Failed to log out:", err)
+	}
+	assert.True(t, authS.IsRevoked(claims))
+
+	// Another instance learns about the revocation from the database
+	other := authService.NewDenylist(authR, time.Minute, log)
+	if err := other.Reload(context.Background()); err != nil {
+		t.Fatal("Failed to reload denylist:", err)
+	}
+	assert.True(t, other.IsRevoked(claims.ID))
+}
+
+// An invite code registers exactly one moderator, even when redeemed concurrently
+func TestInviteIsSingleUse(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	authR := authRepo.NewRepository(conn, log)
+	authS := authService.NewService(authR, authService.NewDenylist(authR, time.Minute, log), nil, "secret", time.Hour, 24*time.Hour, 72*time.Hour, log)
+
+	inviterID := createInviter(t, authR)
+	invite, err := authS.CreateInvite(context.Background(), inviterID, models.RoleModerator)
+	if err != nil {
+		t.Fatal("Failed to create invite:", err)
+	}
+
+	const attempts = 5
+	var registered, rejected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			email := fmt.Sprintf("invited-%d-%d@example.com", time.Now().UnixNano(), i)
+			_, err := authS.Register(context.Background(), email, "passw0rd", "moderator", invite.Code)
+			switch {
+			case err == nil:
+				registered.Add(1)
+			case errors.Is(err, authService.ErrInvalidInvite):
+				rejected.Add(1)
+			default:
+				t.Error("Unexpected error:", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), registered.Load())
+	assert.Equal(t, int32(attempts-1), rejected.Load())
+
+	// A failed registration does not burn the code
+	fresh, err := authS.CreateInvite(context.Background(), inviterID, models.RoleModerator)
+	if err != nil {
+		t.Fatal("Failed to create invite:", err)
+	}
+	email := fmt.Sprintf("invited-dup-%d@example.com", time.Now().UnixNano())
+	if _, err := authS.Register(context.Background(), email, "passw0rd", "client", ""); err != nil {
+		t.Fatal("Failed to register user:", err)
+	}
+	_, err = authS.Register(context.Background(), email, "passw0rd", "moderator", fresh.Code)
+	assert.ErrorIs(t, err, repositories.ErrUserExists)
+	_, err = authS.Register(context.Background(), "other-"+email, "passw0rd", "moderator", fresh.Code)
+	assert.NoError(t, err)
+}
+
+// Disabling a user ends their sessions on every instance and forbids logging in
+func TestAdminDisablesUser(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	authR := authRepo.NewRepository(conn, log)
+	authS := authService.NewService(authR, authService.NewDenylist(authR, time.Minute, log), nil, "secret", time.Hour, 24*time.Hour, 72*time.Hour, log)
+
+	email := fmt.Sprintf("disabled-%d@example.com", time.Now().UnixNano())
+	userID, err := authS.Register(context.Background(), email, "passw0rd", models.RoleClient, "")
+	if err != nil {
+		t.Fatal("Failed to register user:", err)
+	}
+	user, err := authS.Login(context.Background(), userID, "passw0rd", "127.0.0.1")
+	if err != nil {
+		t.Fatal("Failed to log in:", err)
+	}
+	session, err := authS.IssueTokens(context.Background(), user)
+	if err != nil {
+		t.Fatal("Failed to issue tokens:", err)
+	}
+	claims, err := authS.ValidateToken(session.AccessToken)
+	if err != nil {
+		t.Fatal("Failed to validate token:", err)
+	}
+
+	disabled, err := authS.SetDisabled(context.Background(), "00000000-0000-4000-f000-000000000023", userID, true)
+	if err != nil {
+		t.Fatal("Failed to disable user:", err)
+	}
+	assert.NotNil(t, disabled.DisabledAt)
+
+	_, err = authS.Login(context.Background(), userID, "passw0rd", "127.0.0.1")
+	assert.ErrorIs(t, err, authService.ErrInvalidCredentials, "A disabled user can not tell a right password from a wrong one")
+	_, err = authS.Refresh(context.Background(), session.RefreshToken)
+	assert.ErrorIs(t, err, authService.ErrInvalidRefreshToken)
+
+	other := authService.NewDenylist(authR, time.Minute, log)
+	if err := other.Reload(context.Background()); err != nil {
+		t.Fatal("Failed to reload denylist:", err)
+	}
+	assert.True(t, other.IsUserRevoked(userID, claims.IssuedAt.Time))
+
+	users, err := authS.ListUsers(context.Background(), models.UserFilter{Email: &email, Limit: 10})
+	if err != nil {
+		t.Fatal("Failed to list users:", err)
+	}
+	if assert.Len(t, users, 1) {
+		assert.NotNil(t, users[0].DisabledAt)
+	}
+
+	_, err = authS.SetDisabled(context.Background(), "00000000-0000-4000-f000-000000000023", "not-a-uuid", true)
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound)
+}
+
+// Emails are unique and matched regardless of case
+func TestLoginByEmail(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	authR := authRepo.NewRepository(conn, log)
+	authS := authService.NewService(authR, authService.NewDenylist(authR, time.Minute, log), nil, "secret", time.Hour, 24*time.Hour, 72*time.Hour, log)
+
+	email := fmt.Sprintf("Mixed.Case-%d@Example.com", time.Now().UnixNano())
+	userID, err := authS.Register(context.Background(), email, "passw0rd", models.RoleClient, "")
+	if err != nil {
+		t.Fatal("Failed to register user:", err)
+	}
+
+	user, err := authS.LoginByEmail(context.Background(), strings.ToLower(email), "passw0rd", "127.0.0.1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, userID, user.ID)
+	}
+	_, err = authS.LoginByEmail(context.Background(), email, "wrong-passw0rd", "127.0.0.1")
+	assert.ErrorIs(t, err, authService.ErrInvalidCredentials)
+
+	_, err = authS.Register(context.Background(), strings.ToUpper(email), "passw0rd", models.RoleClient, "")
+	assert.ErrorIs(t, err, repositories.ErrUserExists)
 }
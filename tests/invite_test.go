@@ -0,0 +1,151 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestModeratorInvites(t *testing.T) {
+	authRepoMock := mocks.NewAuthRepo(t)
+
+	var stored *models.Invite
+	authRepoMock.On("CreateInvite", mock.Anything, mock.AnythingOfType("*models.Invite")).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.Invite)
+			stored.ID = 1
+		}).
+		Return(nil).Once()
+
+	// Only registered users may mint invites; dummy tokens carry random IDs that are not in users
+	disabledAt := time.Now()
+	authRepoMock.On("GetUserByID", mock.Anything, "moderator-token-uuid").
+		Return(&models.User{ID: "moderator-token-uuid", Role: models.RoleModerator}, nil)
+	authRepoMock.On("GetUserByID", mock.Anything, "disabled-token-uuid").
+		Return(&models.User{ID: "disabled-token-uuid", Role: models.RoleModerator, DisabledAt: &disabledAt}, nil)
+	authRepoMock.On("GetUserByID", mock.Anything, mock.AnythingOfType("string")).
+		Return(nil, fmt.Errorf("repositories.auth.GetUserByID: %w", repositories.ErrUserNotFound))
+
+	validCode := "valid-code"
+	validHash := sha256.Sum256([]byte(validCode))
+	authRepoMock.On("CreateUserWithInvite", mock.Anything, mock.AnythingOfType("*models.User"), hex.EncodeToString(validHash[:])).
+		Return("moderator-uuid", nil).Once()
+	authRepoMock.On("CreateUserWithInvite", mock.Anything, mock.AnythingOfType("*models.User"), mock.AnythingOfType("string")).
+		Return("", fmt.Errorf("repositories.auth.CreateUserWithInvite: %w", repositories.ErrInvalidInvite))
+	authRepoMock.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.Role == "client" })).
+		Return("client-uuid", nil).Once()
+
+	router, authS := newTestRouter(t, testDeps{authRepo: authRepoMock})
+
+	tokens := make(map[models.Role]string)
+	for _, role := range []models.Role{models.RoleClient, models.RoleModerator} {
+		token, err := authS.GenerateToken(string(role)+"-token-uuid", role)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens[role] = token
+	}
+	post := func(url string, role models.Role, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if role != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[role]))
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Moderator mints an invite", func(t *testing.T) {
+		resp := post("/invites", "moderator", "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var invite struct {
+			Code      string    `json:"invite_code"`
+			Role      string    `json:"user_type"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &invite); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		assert.NotEmpty(t, invite.Code)
+		assert.Equal(t, "moderator", invite.Role)
+		assert.WithinDuration(t, time.Now().Add(72*time.Hour), invite.ExpiresAt, time.Minute)
+
+		if assert.NotNil(t, stored) {
+			sum := sha256.Sum256([]byte(invite.Code))
+			assert.Equal(t, hex.EncodeToString(sum[:]), stored.CodeHash, "Only the hash of the code is stored")
+			assert.Equal(t, "moderator-token-uuid", stored.CreatedBy)
+		}
+	})
+
+	t.Run("Clients can not mint invites", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, post("/invites", "client", "").Code)
+	})
+
+	t.Run("Dummy moderators can not mint invites", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/dummyLogin?user_type=moderator", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		if !assert.Equal(t, http.StatusOK, resp.Code) {
+			return
+		}
+		var dummy struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &dummy); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+
+		req = httptest.NewRequest("POST", "/invites", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", dummy.Token))
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Disabled moderators can not mint invites", func(t *testing.T) {
+		token, err := authS.GenerateToken("disabled-token-uuid", models.RoleModerator)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		req := httptest.NewRequest("POST", "/invites", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post("/invites", "moderator", `{"user_type": "superuser"}`).Code)
+	})
+
+	cases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"Moderator without invite", `{"email": "m@example.com", "password": "passw0rd", "user_type": "moderator"}`, http.StatusForbidden},
+		{"Moderator with unknown invite", `{"email": "m@example.com", "password": "passw0rd", "user_type": "moderator", "invite_code": "nope"}`, http.StatusForbidden},
+		{"Moderator with invite", `{"email": "m@example.com", "password": "passw0rd", "user_type": "moderator", "invite_code": "valid-code"}`, http.StatusOK},
+		{"Clients need no invite", `{"email": "c@example.com", "password": "passw0rd", "user_type": "client"}`, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.code, post("/register", "", tc.body).Code)
+		})
+	}
+}
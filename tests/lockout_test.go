@@ -0,0 +1,237 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/lib/loginguard"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeClock is a clock the test moves by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestLoginGuardBackoff(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	account := loginguard.Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second, Window: time.Hour}
+	ip := loginguard.Policy{FreeAttempts: 10, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	guard := loginguard.NewGuard(loginguard.NewMemoryStore(clock.Now), account, ip, clock.Now)
+
+	attempt := func(account, ip string) time.Duration {
+		d, err := guard.Attempt(ctx, account, ip)
+		assert.NoError(t, err)
+		return d
+	}
+
+	t.Run("Free attempts do not lock", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			assert.Zero(t, attempt("alice", ""))
+		}
+	})
+
+	t.Run("Delay doubles up to the maximum", func(t *testing.T) {
+		for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+			assert.Zero(t, attempt("alice", ""))
+			assert.Equal(t, want, attempt("alice", ""), "Attempts while locked are rejected")
+			clock.Advance(want / 2)
+			assert.Equal(t, want/2, attempt("alice", ""), "Rejected attempts are not counted")
+			clock.Advance(want / 2)
+		}
+		assert.Zero(t, attempt("bob", ""), "Other accounts are not locked")
+	})
+
+	t.Run("Success resets the account", func(t *testing.T) {
+		assert.NoError(t, guard.Succeed(ctx, "alice", ""))
+		for i := 0; i < 4; i++ {
+			assert.Zero(t, attempt("alice", ""))
+		}
+		assert.Equal(t, time.Second, attempt("alice", ""))
+	})
+
+	t.Run("Failures are forgotten after the window", func(t *testing.T) {
+		clock.Advance(time.Hour + time.Second)
+		assert.Zero(t, attempt("alice", ""))
+		assert.Zero(t, attempt("alice", ""))
+	})
+
+	t.Run("IP is counted across accounts", func(t *testing.T) {
+		for i := 0; i < 11; i++ {
+			assert.Zero(t, attempt(fmt.Sprintf("user-%d", i), "10.0.0.1"))
+		}
+		assert.Equal(t, time.Minute, attempt("", "10.0.0.1"))
+		assert.Zero(t, attempt("", "10.0.0.2"))
+		assert.Zero(t, attempt("user-0", ""), "One failure does not lock the account")
+	})
+
+	t.Run("Success forgives the IP only its own attempt", func(t *testing.T) {
+		assert.NoError(t, guard.Succeed(ctx, "user-10", "10.0.0.1"))
+		assert.Zero(t, attempt("", "10.0.0.1"))
+		assert.Equal(t, time.Minute, attempt("", "10.0.0.1"))
+	})
+}
+
+func TestLoginGuardConcurrentAttempts(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	policy := loginguard.Policy{FreeAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	store := loginguard.NewMemoryStore(clock.Now)
+	guard := loginguard.NewGuard(store, policy, policy, clock.Now)
+
+	const attempts = 100
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			retryAfter, err := guard.Attempt(ctx, "alice", "")
+			assert.NoError(t, err)
+			if retryAfter == 0 {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(policy.FreeAttempts+1), allowed.Load(), "The attempt that sets the lock is the last one let through")
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Update(ctx, "counter", func(state loginguard.State) loginguard.State {
+				state.Failures++
+				state.ExpiresAt = clock.Now().Add(time.Hour)
+				return state
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	state, err := store.Update(ctx, "counter", func(state loginguard.State) loginguard.State { return state })
+	assert.NoError(t, err)
+	assert.Equal(t, attempts, state.Failures, "No increment is lost")
+}
+
+func TestLoginLockout(t *testing.T) {
+	authRepoMock := mocks.NewAuthRepo(t)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("passw0rd"), bcrypt.DefaultCost)
+	user := &models.User{ID: "user-uuid", Email: "user@example.com", Password: string(hashedPassword), Role: models.RoleClient}
+	authRepoMock.On("GetUserByEmail", mock.Anything, "user@example.com").Return(user, nil)
+	disabledAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	authRepoMock.On("GetUserByEmail", mock.Anything, "disabled@example.com").
+		Return(&models.User{ID: "disabled-uuid", Email: "disabled@example.com", Password: string(hashedPassword), Role: models.RoleClient, DisabledAt: &disabledAt}, nil)
+	authRepoMock.On("GetUserByEmail", mock.Anything, mock.AnythingOfType("string")).
+		Return(nil, fmt.Errorf("repositories.auth.GetUserByEmail: %w", repositories.ErrUserNotFound))
+	authRepoMock.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	guard := loginguard.NewGuard(
+		loginguard.NewMemoryStore(clock.Now),
+		loginguard.Policy{FreeAttempts: 2, BaseDelay: 30 * time.Second, MaxDelay: time.Hour, Window: time.Hour},
+		loginguard.Policy{FreeAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: time.Hour, Window: time.Hour},
+		clock.Now,
+	)
+
+	router, _ := newTestRouter(t, testDeps{authRepo: authRepoMock, guard: guard})
+
+	login := func(email, password, ip string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"email": %q, "password": %q}`, email, password)
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":12345"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Account is locked after free attempts", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, login("user@example.com", "wrong", "10.0.0.1").Code)
+		assert.Equal(t, http.StatusNotFound, login("user@example.com", "wrong", "10.0.0.1").Code)
+		assert.Equal(t, http.StatusNotFound, login("user@example.com", "wrong", "10.0.0.1").Code)
+
+		resp := login("user@example.com", "passw0rd", "10.0.0.2")
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code, "The right password does not bypass the lock")
+		assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+
+		clock.Advance(10*time.Second + 500*time.Millisecond)
+		assert.Equal(t, "20", login("user@example.com", "passw0rd", "10.0.0.2").Header().Get("Retry-After"),
+			"Retry-After is rounded up to whole seconds")
+	})
+
+	t.Run("Login succeeds after the lock and resets it", func(t *testing.T) {
+		clock.Advance(20 * time.Second)
+		assert.Equal(t, http.StatusOK, login("user@example.com", "passw0rd", "10.0.0.2").Code)
+		assert.Equal(t, http.StatusNotFound, login("user@example.com", "wrong", "10.0.0.2").Code)
+		assert.Equal(t, http.StatusNotFound, login("user@example.com", "wrong", "10.0.0.2").Code)
+		assert.Equal(t, http.StatusOK, login("user@example.com", "passw0rd", "10.0.0.2").Code)
+	})
+
+	t.Run("Unknown accounts are throttled too", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusNotFound, login("ghost@example.com", "wrong", "10.0.0.3").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, login("ghost@example.com", "wrong", "10.0.0.4").Code)
+	})
+
+	t.Run("Disabled accounts answer like a wrong password", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, login("disabled@example.com", "passw0rd", "10.2.0.1").Code)
+		assert.Equal(t, http.StatusNotFound, login("disabled@example.com", "wrong", "10.2.0.2").Code)
+		assert.Equal(t, http.StatusNotFound, login("disabled@example.com", "passw0rd", "10.2.0.3").Code)
+		assert.Equal(t, http.StatusTooManyRequests, login("disabled@example.com", "passw0rd", "10.2.0.4").Code,
+			"Logins of a disabled account are counted as failures")
+	})
+
+	t.Run("Parallel guesses are counted", func(t *testing.T) {
+		const guesses = 10
+		codes := make(chan int, guesses)
+		var wg sync.WaitGroup
+		for i := 0; i < guesses; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				codes <- login("burst@example.com", "wrong", fmt.Sprintf("10.1.0.%d", i)).Code
+			}(i)
+		}
+		wg.Wait()
+		close(codes)
+
+		counts := make(map[int]int)
+		for code := range codes {
+			counts[code]++
+		}
+		assert.Equal(t, 3, counts[http.StatusNotFound], "Only the free attempts and the one that locks reach the password check")
+		assert.Equal(t, guesses-3, counts[http.StatusTooManyRequests])
+	})
+
+	t.Run("IP is locked across accounts", func(t *testing.T) {
+		for i := 0; i < 6; i++ {
+			assert.Equal(t, http.StatusNotFound, login(fmt.Sprintf("nobody%d@example.com", i), "wrong", "10.0.0.5").Code)
+		}
+		resp := login("user@example.com", "passw0rd", "10.0.0.5")
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+		assert.Equal(t, http.StatusOK, login("user@example.com", "passw0rd", "10.0.0.6").Code)
+	})
+}
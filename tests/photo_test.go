@@ -0,0 +1,284 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/handlers/response"
+	"avito/internal/lib/blobstore"
+	"avito/internal/lib/thumbnail"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"avito/internal/services/flatService"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func pngImage(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal("Failed to encode image:", err)
+	}
+	return buf.Bytes()
+}
+
+func photoUpload(t *testing.T, kind string, data []byte) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if kind != "" {
+		if err := form.WriteField("kind", kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := form.CreateFormFile("file", "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	form.Close()
+	return &body, form.FormDataContentType()
+}
+
+func TestFlatPhotos(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	mediaDir := t.TempDir()
+	store := blobstore.NewLocalStore(mediaDir, "/media")
+
+	ownerID := "owner-uuid"
+	stored := models.FlatPhoto{ID: 10, FlatID: 1, Kind: models.PhotoKindPhoto, Key: "flats/1/old.png",
+		ThumbnailKey: "flats/1/old_thumb.png", URL: "/media/flats/1/old.png", Position: 1}
+	flat := func(id int, status string, photos ...models.FlatPhoto) func(context.Context, int, bool) *models.Flat {
+		return func(context.Context, int, bool) *models.Flat {
+			return &models.Flat{ID: id, HouseID: 2, Price: 100, Rooms: 2, Status: status, CreatedBy: &ownerID, Photos: photos}
+		}
+	}
+	flatRepoMock.On("GetFlatByID", mock.Anything, 1, false).Return(flat(1, models.StatusApproved, stored), nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 3, false).Return(flat(3, models.StatusOnModeration), nil)
+	flatRepoMock.On("GetFlatByID", mock.Anything, 4, false).Return(flat(4, models.StatusCreated), nil)
+	unapproved := models.FlatPhoto{ID: 20, FlatID: 5, Kind: models.PhotoKindPhoto, Key: "flats/5/new.png",
+		ThumbnailKey: "flats/5/new_thumb.png", URL: "/media/flats/5/new.png", Position: 1}
+	flatRepoMock.On("GetFlatByID", mock.Anything, 5, mock.AnythingOfType("bool")).Return(flat(5, models.StatusCreated, unapproved), nil)
+	// Concurrent uploads filled the flat after it was read
+	flatRepoMock.On("AddFlatPhoto", mock.Anything, mock.MatchedBy(func(p *models.FlatPhoto) bool { return p.FlatID == 4 }),
+		mock.Anything, flatService.MaxFlatPhotos).
+		Return(nil, fmt.Errorf("repository.flat.AddFlatPhoto: %w", repositories.ErrTooManyPhotos))
+
+	var added *models.FlatPhoto
+	flatRepoMock.On("AddFlatPhoto", mock.Anything, mock.AnythingOfType("*models.FlatPhoto"), models.FlatUpdate{
+		FlatID: 1, From: models.StatusApproved, To: models.StatusCreated, EditorID: ownerID, Price: 100, Rooms: 2,
+		Changes: map[string]models.FieldChange{"photos": {Old: 1, New: 2}},
+	}, flatService.MaxFlatPhotos).Return(func(_ context.Context, photo *models.FlatPhoto, _ models.FlatUpdate, _ int) *models.Flat {
+		added = photo
+		photo.ID, photo.Position = 11, 2
+		return &models.Flat{ID: 1, HouseID: 2, Price: 100, Rooms: 2, Status: models.StatusCreated, CreatedBy: &ownerID,
+			Photos: []models.FlatPhoto{stored, *photo}}
+	}, nil).Once()
+
+	flatRepoMock.On("ReorderFlatPhotos", mock.Anything, 1, []int{10}).
+		Return(flat(1, models.StatusApproved, stored)(nil, 1, false), nil).Once()
+	flatRepoMock.On("DeleteFlatPhoto", mock.Anything, 1, 10).Return(&stored, nil).Once()
+	flatRepoMock.On("DeleteFlatPhoto", mock.Anything, 1, 99).Return(nil, fmt.Errorf("repo: %w", repositories.ErrPhotoNotFound)).Once()
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock, store: store})
+
+	tokens := make(map[string]string)
+	for userID, role := range map[string]models.Role{ownerID: models.RoleClient, "stranger-uuid": models.RoleClient, "moderator-uuid": models.RoleModerator} {
+		token, err := authS.GenerateToken(userID, role)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens[userID] = token
+	}
+
+	upload := func(userID string, flatID int, kind string, data []byte) *httptest.ResponseRecorder {
+		body, contentType := photoUpload(t, kind, data)
+		req := httptest.NewRequest("POST", fmt.Sprintf("/flat/%d/photos", flatID), body)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[userID]))
+		req.Header.Set("Content-Type", contentType)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Upload sends an approved flat back to moderation", func(t *testing.T) {
+		resp := upload(ownerID, 1, models.PhotoKindFloorPlan, pngImage(t, 800, 400))
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var actual response.FlatResponse
+		if err := json.Unmarshal(resp.Body.Bytes(), &actual); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		assert.Equal(t, models.StatusCreated, actual.Status)
+		if !assert.Len(t, actual.Photos, 2) {
+			return
+		}
+		photo := actual.Photos[1]
+		assert.Equal(t, models.PhotoKindFloorPlan, photo.Kind)
+		assert.Equal(t, "/media/"+added.Key, photo.URL)
+		assert.Equal(t, "/media/"+added.ThumbnailKey, photo.ThumbnailURL)
+		assert.Equal(t, "image/png", added.ContentType)
+
+		thumb, err := os.Open(filepath.Join(mediaDir, filepath.FromSlash(added.ThumbnailKey)))
+		if err != nil {
+			t.Fatal("Thumbnail is not stored:", err)
+		}
+		defer thumb.Close()
+		cfg, err := png.DecodeConfig(thumb)
+		assert.NoError(t, err)
+		assert.Equal(t, flatService.ThumbnailSide, cfg.Width)
+		assert.Equal(t, flatService.ThumbnailSide/2, cfg.Height)
+	})
+
+	t.Run("Photos are served like their flat", func(t *testing.T) {
+		for _, key := range []string{stored.Key, unapproved.Key, unapproved.ThumbnailKey, "flats/1/unknown.png"} {
+			if _, err := store.Put(context.Background(), key, "image/png", bytes.NewReader(pngImage(t, 2, 2))); err != nil {
+				t.Fatal("Failed to store photo:", err)
+			}
+		}
+		download := func(userID, url string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", url, nil)
+			if userID != "" {
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[userID]))
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			return resp
+		}
+
+		resp := download("stranger-uuid", stored.URL)
+		assert.Equal(t, http.StatusOK, resp.Code, "Photos of approved flats are seen by everybody")
+		assert.Equal(t, "image/png", resp.Header().Get("Content-Type"))
+		assert.Equal(t, "private", resp.Header().Get("Cache-Control"))
+
+		assert.Equal(t, http.StatusNotFound, download("stranger-uuid", unapproved.URL).Code,
+			"Photos of flats that are not approved are hidden from other clients")
+		assert.Equal(t, http.StatusOK, download(ownerID, unapproved.URL).Code)
+		assert.Equal(t, http.StatusOK, download(ownerID, "/media/"+unapproved.ThumbnailKey).Code)
+		assert.Equal(t, http.StatusOK, download("moderator-uuid", unapproved.URL).Code)
+
+		assert.Equal(t, http.StatusUnauthorized, download("", stored.URL).Code)
+		assert.Equal(t, http.StatusNotFound, download(ownerID, "/media/flats/1/unknown.png").Code,
+			"Files that are not photos of the flat are not served")
+		assert.Equal(t, http.StatusNotFound, download(ownerID, "/media/flats/1/").Code, "Directory listings must not be served")
+	})
+
+	rejected := []struct {
+		name   string
+		userID string
+		flatID int
+		kind   string
+		data   []byte
+		code   int
+	}{
+		{"Not an image", ownerID, 1, "", []byte("%PDF-1.4 not an image"), http.StatusBadRequest},
+		{"Broken image", ownerID, 1, "", pngImage(t, 10, 10)[:40], http.StatusBadRequest},
+		{"Unknown kind", ownerID, 1, "selfie", pngImage(t, 10, 10), http.StatusBadRequest},
+		{"Too large", ownerID, 1, "", bytes.Repeat([]byte{0}, flatService.MaxPhotoSize+1), http.StatusRequestEntityTooLarge},
+		{"Not the owner", "stranger-uuid", 1, "", pngImage(t, 10, 10), http.StatusForbidden},
+		{"Flat on moderation", ownerID, 3, "", pngImage(t, 10, 10), http.StatusConflict},
+		{"Photo limit reached", ownerID, 4, "", pngImage(t, 10, 10), http.StatusConflict},
+	}
+	for _, tc := range rejected {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.code, upload(tc.userID, tc.flatID, tc.kind, tc.data).Code)
+		})
+	}
+
+	t.Run("Rejected photos are not kept", func(t *testing.T) {
+		entries, err := os.ReadDir(filepath.Join(mediaDir, "flats", "4"))
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		assert.Empty(t, entries)
+	})
+
+	request := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[ownerID]))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Reorder", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("PUT", "/flat/1/photos/order", `{"photo_ids": [10]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, request("PUT", "/flat/1/photos/order", `{"photo_ids": [10, 10]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, request("PUT", "/flat/1/photos/order", `{"photo_ids": [11]}`).Code)
+	})
+
+	t.Run("Delete removes the files", func(t *testing.T) {
+		for _, key := range []string{stored.Key, stored.ThumbnailKey} {
+			if _, err := store.Put(context.Background(), key, "image/png", bytes.NewReader(pngImage(t, 2, 2))); err != nil {
+				t.Fatal("Failed to store photo:", err)
+			}
+		}
+
+		assert.Equal(t, http.StatusOK, request("DELETE", "/flat/1/photos/10", "").Code)
+		_, err := os.Stat(filepath.Join(mediaDir, "flats", "1", "old.png"))
+		assert.True(t, os.IsNotExist(err), "Expected the photo file to be deleted")
+
+		assert.Equal(t, http.StatusNotFound, request("DELETE", "/flat/1/photos/99", "").Code)
+	})
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	store := blobstore.NewLocalStore(t.TempDir(), "/media")
+
+	for _, key := range []string{"", "/etc/passwd", "../outside.png", "flats/../../outside.png", "flats//1.png"} {
+		_, err := store.Put(context.Background(), key, "image/png", strings.NewReader("x"))
+		assert.ErrorIs(t, err, blobstore.ErrInvalidKey, key)
+	}
+}
+
+// The typed paths for decoded JPEG and PNG photos scale the same way as the generic one
+func TestThumbnailFastPaths(t *testing.T) {
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 90, 60), image.YCbCrSubsampleRatio420)
+	for i := range ycbcr.Y {
+		ycbcr.Y[i] = uint8(i * 7)
+	}
+	for i := range ycbcr.Cb {
+		ycbcr.Cb[i], ycbcr.Cr[i] = uint8(i*3), uint8(255-i)
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 90, 60))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = uint8(i * 5)
+	}
+	for i := 3; i < len(rgba.Pix); i += 4 {
+		rgba.Pix[i] = 0xff
+	}
+
+	for name, img := range map[string]image.Image{"ycbcr": ycbcr, "rgba": rgba} {
+		t.Run(name, func(t *testing.T) {
+			// Wrapping hides the concrete type, so the generic path is taken
+			generic := thumbnail.Make(struct{ image.Image }{img}, 30)
+			fast := thumbnail.Make(img, 30)
+
+			assert.Equal(t, image.Rect(0, 0, 30, 20), fast.Bounds())
+			for i := range fast.Pix {
+				assert.InDelta(t, generic.Pix[i], fast.Pix[i], 1, "byte %d", i)
+			}
+		})
+	}
+}
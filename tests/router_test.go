@@ -0,0 +1,79 @@
+package avito_test
+
+import (
+	"avito/internal/handlers/authHandler"
+	"avito/internal/handlers/flatHandler"
+	"avito/internal/handlers/houseHandler"
+	"avito/internal/handlers/searchHandler"
+	"avito/internal/lib/blobstore"
+	"avito/internal/lib/logger"
+	"avito/internal/lib/loginguard"
+	"avito/internal/lib/sender"
+	"avito/internal/repositories/authRepo"
+	"avito/internal/repositories/flatRepo"
+	"avito/internal/repositories/houseRepo"
+	"avito/internal/repositories/mocks"
+	"avito/internal/repositories/searchRepo"
+	"avito/internal/services/authService"
+	"avito/internal/services/flatService"
+	"avito/internal/services/houseService"
+	"avito/internal/services/searchService"
+	"avito/internal/setup"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// testDeps are what a test puts behind the router. Repositories left nil are replaced by mocks
+// that expect no calls; zero settings get the defaults of newTestRouter.
+type testDeps struct {
+	authRepo   authRepo.AuthRepo
+	houseRepo  houseRepo.HouseRepo
+	flatRepo   flatRepo.FlatRepo
+	searchRepo searchRepo.SearchRepo
+	store      *blobstore.LocalStore
+	guard      *loginguard.Guard
+	accessTTL  time.Duration
+}
+
+// newTestRouter wires the services and handlers over deps like the application does
+// and returns the router with the auth service that issues its tokens
+func newTestRouter(t *testing.T, deps testDeps) (*chi.Mux, authService.AuthService) {
+	log := logger.SetupLogger("prod")
+
+	if deps.authRepo == nil {
+		deps.authRepo = mocks.NewAuthRepo(t)
+	}
+	if deps.houseRepo == nil {
+		deps.houseRepo = mocks.NewHouseRepo(t)
+	}
+	if deps.flatRepo == nil {
+		deps.flatRepo = mocks.NewFlatRepo(t)
+	}
+	if deps.searchRepo == nil {
+		deps.searchRepo = mocks.NewSearchRepo(t)
+	}
+	if deps.store == nil {
+		deps.store = blobstore.NewLocalStore(t.TempDir(), "/media")
+	}
+	if deps.accessTTL == 0 {
+		deps.accessTTL = time.Hour
+	}
+
+	authS := authService.NewService(deps.authRepo, authService.NewDenylist(deps.authRepo, time.Minute, log), deps.guard,
+		"jwt_secret", deps.accessTTL, 24*time.Hour, 72*time.Hour, log)
+	houseS := houseService.NewService(deps.houseRepo, log)
+	flatS := flatService.NewService(deps.flatRepo, deps.houseRepo, sender.NewFileSender("", log), deps.store, time.Minute, log)
+	searchS := searchService.NewService(deps.searchRepo, log)
+
+	router := setup.SetupRouter(
+		authHandler.NewHandler(authS, log),
+		houseHandler.NewHandler(houseS, log),
+		flatHandler.NewHandler(flatS, log),
+		searchHandler.NewHandler(searchS, log),
+		deps.store,
+		log,
+	)
+	return router, authS
+}
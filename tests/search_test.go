@@ -0,0 +1,203 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/handlers/response"
+	"avito/internal/repositories/mocks"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSearchFlats(t *testing.T) {
+	searchRepoMock := mocks.NewSearchRepo(t)
+
+	developer := "Мечта"
+	searchRepoMock.On("SearchFlats", mock.Anything, mock.MatchedBy(func(s models.FlatSearch) bool {
+		return s.UserID == "client-uuid" && s.Role == "client" &&
+			*s.PriceFrom == 1000 && *s.PriceTo == 5000 && assert.ObjectsAreEqual([]int{1, 2}, s.Rooms) &&
+			*s.YearFrom == 2000 && s.YearTo == nil && *s.Developer == developer && s.Address == nil &&
+			s.Limit == 10 && s.Offset == 20
+	})).Return([]models.Flat{
+		{ID: 1, HouseID: 3, Price: 2000, Rooms: 1, Status: models.StatusApproved},
+		{ID: 5, HouseID: 8, Price: 4500, Rooms: 2, Status: models.StatusApproved},
+	}, nil)
+	searchRepoMock.On("SearchFlats", mock.Anything, mock.MatchedBy(func(s models.FlatSearch) bool {
+		return s.Role == "moderator" && s.Address != nil && *s.Address == "Лесная"
+	})).Return(nil, nil)
+	searchRepoMock.On("SearchFlats", mock.Anything, mock.MatchedBy(func(s models.FlatSearch) bool {
+		return s.AreaFrom != nil && *s.AreaFrom == 40.5 && *s.AreaTo == 80 && *s.FloorFrom == 2 && s.FloorTo == nil &&
+			*s.Balcony && *s.Renovation == models.RenovationEuro
+	})).Return([]models.Flat{{ID: 7, HouseID: 3, Price: 9000, Rooms: 2, Status: models.StatusApproved}}, nil)
+
+	router, authS := newTestRouter(t, testDeps{searchRepo: searchRepoMock})
+
+	tokens := make(map[models.Role]string)
+	for userID, role := range map[string]models.Role{"client-uuid": "client", "moderator-uuid": "moderator"} {
+		token, err := authS.GenerateToken(userID, role)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		tokens[role] = token
+	}
+
+	cases := []struct {
+		name  string
+		role  models.Role
+		url   string
+		code  int
+		flats int
+	}{
+		{"Client searches with every filter", "client",
+			"/flats/search?price_from=1000&price_to=5000&rooms=1,2&year_from=2000&developer=Мечта&limit=10&offset=20",
+			http.StatusOK, 2},
+		{"Nothing found", "moderator", "/flats/search?address=Лесная", http.StatusOK, 0},
+		{"Empty price range", "client", "/flats/search?price_from=5000&price_to=1000", http.StatusBadRequest, 0},
+		{"Empty year range", "client", "/flats/search?year_from=2020&year_to=2000", http.StatusBadRequest, 0},
+		{"Invalid rooms", "client", "/flats/search?rooms=1,two", http.StatusBadRequest, 0},
+		{"Zero rooms", "client", "/flats/search?rooms=0", http.StatusBadRequest, 0},
+		{"Invalid offset", "client", "/flats/search?offset=-5", http.StatusBadRequest, 0},
+		{"Area, floor and renovation filters", "client",
+			"/flats/search?area_from=40.5&area_to=80&floor_from=2&balcony=true&renovation=euro", http.StatusOK, 1},
+		{"Empty area range", "client", "/flats/search?area_from=80&area_to=40", http.StatusBadRequest, 0},
+		{"Empty floor range", "client", "/flats/search?floor_from=9&floor_to=2", http.StatusBadRequest, 0},
+		{"Invalid area", "client", "/flats/search?area_from=big", http.StatusBadRequest, 0},
+		{"Invalid balcony", "client", "/flats/search?balcony=maybe", http.StatusBadRequest, 0},
+		{"Unknown renovation", "client", "/flats/search?renovation=luxury", http.StatusBadRequest, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokens[tc.role]))
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.code, resp.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+
+			var actualResponse map[string][]response.FlatResponse
+			if err := json.Unmarshal(resp.Body.Bytes(), &actualResponse); err != nil {
+				t.Fatal("Failed to unmarshal response:", err)
+			}
+			flats, ok := actualResponse["flats"]
+			assert.True(t, ok, "Expected 'flats' key in response")
+			assert.Len(t, flats, tc.flats)
+		})
+	}
+}
+
+func TestSearchHouses(t *testing.T) {
+	searchRepoMock := mocks.NewSearchRepo(t)
+
+	searchRepoMock.On("SearchHouses", mock.Anything, "Ленина улица", 20, 0).Return([]models.House{
+		{ID: 7, Address: "ул. Ленина, 5", YearBuilt: 1970},
+		{ID: 3, Address: "Ленина улица, 12к2", YearBuilt: 1985},
+	}, nil)
+
+	router, authS := newTestRouter(t, testDeps{searchRepo: searchRepoMock})
+
+	token, err := authS.GenerateToken("client-uuid", "client")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	search := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := search("/houses/search?q=" + url.QueryEscape(" Ленина улица "))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var actualResponse map[string][]response.HouseResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &actualResponse); err != nil {
+		t.Fatal("Failed to unmarshal response:", err)
+	}
+	if assert.Len(t, actualResponse["houses"], 2) {
+		assert.Equal(t, 7, actualResponse["houses"][0].Id, "Houses keep the relevance order")
+	}
+
+	assert.Equal(t, http.StatusBadRequest, search("/houses/search?q=%20").Code)
+	assert.Equal(t, http.StatusBadRequest, search("/houses/search").Code)
+}
+
+func TestNearbyHouses(t *testing.T) {
+	houseRepoMock := mocks.NewHouseRepo(t)
+	searchRepoMock := mocks.NewSearchRepo(t)
+
+	kremlin := models.GeoPoint{Lat: 55.752, Lon: 37.6175}
+	lat, lon := 55.7558, 37.6173
+	searchRepoMock.On("NearbyHouses", mock.Anything, kremlin, 1500.0, 20).Return([]models.NearbyHouse{
+		{House: models.House{ID: 2, Address: "Красная площадь, 1", YearBuilt: 1875, Latitude: &lat, Longitude: &lon}, DistanceM: 422.71},
+		{House: models.House{ID: 1, Address: "Моховая улица, 9", YearBuilt: 1900}, DistanceM: 1020.2},
+	}, nil)
+	houseRepoMock.On("FindHouseByAddress", mock.Anything, "улица никольская, дом 10").Return(nil, nil)
+	houseRepoMock.On("CreateHouse", mock.Anything, mock.MatchedBy(func(h *models.House) bool {
+		return h.Latitude != nil && *h.Latitude == 55.7573 && h.Longitude != nil && *h.Longitude == 37.6232
+	})).Return(nil)
+
+	router, authS := newTestRouter(t, testDeps{houseRepo: houseRepoMock, searchRepo: searchRepoMock})
+
+	token, err := authS.GenerateToken("moderator-uuid", "moderator")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send("GET", "/houses/nearby?lat=55.752&lon=37.6175&radius_m=1500", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var actualResponse map[string][]response.NearbyHouseResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &actualResponse); err != nil {
+		t.Fatal("Failed to unmarshal response:", err)
+	}
+	if houses := actualResponse["houses"]; assert.Len(t, houses, 2) {
+		assert.Equal(t, 2, houses[0].Id, "Nearest house goes first")
+		assert.Equal(t, 423.0, houses[0].DistanceM)
+		if assert.NotNil(t, houses[0].Latitude) {
+			assert.Equal(t, lat, *houses[0].Latitude)
+		}
+		assert.Nil(t, houses[1].Latitude)
+	}
+
+	for _, target := range []string{
+		"/houses/nearby?lat=55.752&lon=37.6175",
+		"/houses/nearby?lat=north&lon=37.6175&radius_m=1500",
+		"/houses/nearby?lat=91&lon=37.6175&radius_m=1500",
+		"/houses/nearby?lat=55.752&lon=-181&radius_m=1500",
+		"/houses/nearby?lat=55.752&lon=37.6175&radius_m=0",
+		"/houses/nearby?lat=55.752&lon=37.6175&radius_m=100000",
+		"/houses/nearby?lat=NaN&lon=37.6175&radius_m=1500",
+	} {
+		assert.Equal(t, http.StatusBadRequest, send("GET", target, "").Code, target)
+	}
+
+	// Houses are created with an optional pair of coordinates
+	resp = send("POST", "/house/create", `{"address": "Никольская ул., 10", "year": 1900, "lat": 55.7573, "lon": 37.6232}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, http.StatusBadRequest,
+		send("POST", "/house/create", `{"address": "Никольская ул., 12", "year": 1900, "lat": 55.7573}`).Code)
+	assert.Equal(t, http.StatusBadRequest,
+		send("POST", "/house/create", `{"address": "Никольская ул., 14", "year": 1900, "lat": 155.7573, "lon": 37.6232}`).Code)
+}
@@ -0,0 +1,260 @@
+package avito_test
+
+import (
+	"avito/internal/domain/models"
+	"avito/internal/lib/logger"
+	"avito/internal/repositories"
+	"avito/internal/repositories/mocks"
+	"avito/internal/services/authService"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// refreshTokenStore keeps the refresh tokens the service stores in the mocked repository
+type refreshTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*models.RefreshToken // by hash
+	nextID int
+}
+
+func (s *refreshTokenStore) put(token *models.RefreshToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	token.ID = fmt.Sprint(s.nextID)
+	if token.FamilyID == "" {
+		token.FamilyID = "family-" + token.ID
+	}
+	stored := *token
+	s.tokens[token.TokenHash] = &stored
+}
+
+func (s *refreshTokenStore) get(_ context.Context, hash string) (*models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[hash]
+	if !ok {
+		return nil, nil
+	}
+	copied := *token
+	return &copied, nil
+}
+
+// revoke revokes the tokens matching the filter; it returns how many were active
+func (s *refreshTokenStore) revoke(match func(*models.RefreshToken) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	now := time.Now()
+	for _, token := range s.tokens {
+		if match(token) && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			n++
+		}
+	}
+	return n
+}
+
+func TestRefreshTokens(t *testing.T) {
+	authRepoMock := mocks.NewAuthRepo(t)
+	store := &refreshTokenStore{tokens: make(map[string]*models.RefreshToken)}
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
+	authRepoMock.On("GetUserByID", mock.Anything, "user-uuid").
+		Return(&models.User{ID: "user-uuid", Password: string(hashedPassword), Role: "moderator"}, nil)
+	authRepoMock.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).
+		Run(func(args mock.Arguments) { store.put(args.Get(1).(*models.RefreshToken)) }).
+		Return(nil)
+	authRepoMock.On("GetRefreshToken", mock.Anything, mock.AnythingOfType("string")).Return(store.get)
+	authRepoMock.On("RotateRefreshToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*models.RefreshToken")).
+		Return(func(_ context.Context, usedID string, next *models.RefreshToken) error {
+			if store.revoke(func(token *models.RefreshToken) bool { return token.ID == usedID }) == 0 {
+				return repositories.ErrRefreshTokenUsed
+			}
+			store.put(next)
+			return nil
+		})
+	authRepoMock.On("RevokeRefreshFamily", mock.Anything, mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) {
+			familyID := args.String(1)
+			store.revoke(func(token *models.RefreshToken) bool { return token.FamilyID == familyID })
+		}).
+		Return(nil)
+	authRepoMock.On("RevokeAccessToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+		Return(nil)
+
+	router, authS := newTestRouter(t, testDeps{authRepo: authRepoMock, accessTTL: 15 * time.Minute})
+
+	type tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	post := func(url, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(t *testing.T, resp *httptest.ResponseRecorder) tokens {
+		var result tokens
+		if err := json.Unmarshal(resp.Body.Bytes(), &result); err != nil {
+			t.Fatal("Failed to unmarshal response:", err)
+		}
+		return result
+	}
+	login := func(t *testing.T) tokens {
+		resp := post("/login", "", `{"id": "user-uuid", "password": "qwerty"}`)
+		if !assert.Equal(t, http.StatusOK, resp.Code) {
+			t.FailNow()
+		}
+		return decode(t, resp)
+	}
+
+	t.Run("Login issues a short-lived token and a refresh token", func(t *testing.T) {
+		session := login(t)
+
+		assert.NotEmpty(t, session.RefreshToken)
+		assert.Equal(t, 900, session.ExpiresIn)
+
+		claims, err := authS.ValidateToken(session.Token)
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, claims.ID, "Access tokens carry a jti")
+			assert.WithinDuration(t, time.Now().Add(15*time.Minute), claims.ExpiresAt.Time, time.Minute)
+		}
+	})
+
+	t.Run("Refresh rotates the refresh token", func(t *testing.T) {
+		session := login(t)
+
+		resp := post("/token/refresh", "", fmt.Sprintf(`{"refresh_token": %q}`, session.RefreshToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		next := decode(t, resp)
+		assert.NotEqual(t, session.RefreshToken, next.RefreshToken)
+		claims, err := authS.ValidateToken(next.Token)
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.RoleModerator, claims.Role)
+		}
+
+		resp = post("/token/refresh", "", fmt.Sprintf(`{"refresh_token": %q}`, next.RefreshToken))
+		assert.Equal(t, http.StatusOK, resp.Code, "The successor can be used once")
+	})
+
+	t.Run("Reused refresh token ends the session", func(t *testing.T) {
+		session := login(t)
+
+		resp := post("/token/refresh", "", fmt.Sprintf(`{"refresh_token": %q}`, session.RefreshToken))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		next := decode(t, resp)
+
+		resp = post("/token/refresh", "", fmt.Sprintf(`{"refresh_token": %q}`, session.RefreshToken))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+		resp = post("/token/refresh", "", fmt.Sprintf(`{"refresh_token": %q}`, next.RefreshToken))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, "The whole family is revoked")
+	})
+
+	t.Run("Unknown refresh token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, post("/token/refresh", "", `{"refresh_token": "nope"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, post("/token/refresh", "", `{}`).Code)
+	})
+
+	t.Run("Logout revokes the access and the refresh token", func(t *testing.T) {
+		session := login(t)
+
+		resp := post("/logout", session.Token, fmt.Sprintf(`{"refresh_token": %q}`, session.RefreshToken))
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, post("/logout", session.Token, "").Code,
+			"The access token is rejected before it expires")
+
+		resp = post("/token/refresh", "", fmt.Sprintf(`{"refresh_token": %q}`, session.RefreshToken))
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("Logout without a body", func(t *testing.T) {
+		session := login(t)
+
+		assert.Equal(t, http.StatusNoContent, post("/logout", session.Token, "").Code)
+	})
+}
+
+func TestDenylistReload(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	authRepoMock := mocks.NewAuthRepo(t)
+	authRepoMock.On("GetRevokedAccessTokens", mock.Anything).Return(map[string]time.Time{
+		"revoked-elsewhere": time.Now().Add(time.Hour),
+	}, nil).Once()
+	authRepoMock.On("GetRevokedUsers", mock.Anything).Return(nil, nil).Once()
+
+	denylist := authService.NewDenylist(authRepoMock, time.Minute, log)
+	denylist.Add("revoked-here", time.Now().Add(time.Hour))
+	denylist.Add("expired", time.Now().Add(-time.Second))
+
+	if err := denylist.Reload(context.Background()); err != nil {
+		t.Fatal("Failed to reload denylist:", err)
+	}
+
+	assert.True(t, denylist.IsRevoked("revoked-elsewhere"))
+	assert.True(t, denylist.IsRevoked("revoked-here"), "Tokens revoked by this instance are kept")
+	assert.False(t, denylist.IsRevoked("expired"))
+	assert.False(t, denylist.IsRevoked("never-revoked"))
+	assert.False(t, denylist.IsRevoked(""))
+}
+
+func TestDenylistUserRevocation(t *testing.T) {
+	log := logger.SetupLogger("prod")
+
+	denylist := authService.NewDenylist(mocks.NewAuthRepo(t), time.Minute, log)
+
+	revokedAt := time.Date(2024, 5, 1, 12, 0, 0, 700_000_000, time.UTC)
+	denylist.SetUser(models.User{ID: "user-uuid", SessionsRevokedAt: &revokedAt})
+
+	assert.False(t, denylist.IsUserRevoked("user-uuid", revokedAt.Add(100*time.Millisecond)),
+		"A token issued right after the logout is accepted")
+	assert.True(t, denylist.IsUserRevoked("user-uuid", revokedAt.Add(-100*time.Millisecond)),
+		"A token issued in the same second before the logout is rejected")
+	assert.True(t, denylist.IsUserRevoked("user-uuid", revokedAt.Add(-time.Second)))
+	assert.False(t, denylist.IsUserRevoked("other-uuid", revokedAt.Add(-time.Hour)))
+
+	t.Run("Token revoked right after it was issued", func(t *testing.T) {
+		authS := authService.NewService(mocks.NewAuthRepo(t), denylist, nil, "jwt_secret", time.Hour, 24*time.Hour, 72*time.Hour, log)
+		token, err := authS.GenerateToken("fresh-uuid", models.RoleClient)
+		if err != nil {
+			t.Fatal("Failed to generate token:", err)
+		}
+		claims, err := authS.ValidateToken(token)
+		if err != nil {
+			t.Fatal("Failed to validate token:", err)
+		}
+		assert.False(t, authS.IsRevoked(claims))
+
+		logoutAt := time.Now()
+		denylist.SetUser(models.User{ID: "fresh-uuid", SessionsRevokedAt: &logoutAt})
+		assert.True(t, authS.IsRevoked(claims), "iat keeps sub-second precision")
+	})
+
+	disabledAt := revokedAt
+	denylist.SetUser(models.User{ID: "user-uuid", DisabledAt: &disabledAt, SessionsRevokedAt: &revokedAt})
+	assert.True(t, denylist.IsUserRevoked("user-uuid", revokedAt.Add(time.Hour)), "All tokens of a disabled user are rejected")
+}
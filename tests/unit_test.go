@@ -2,15 +2,10 @@ package avito_test
 
 import (
 	"avito/internal/domain/models"
-	"avito/internal/handlers/authHandler"
 	"avito/internal/handlers/common"
-	"avito/internal/handlers/flatHandler"
-	"avito/internal/handlers/houseHandler"
 	"avito/internal/handlers/response"
 	"avito/internal/repositories/mocks"
-	"avito/internal/services/authService"
-	"avito/internal/services/flatService"
-	"avito/internal/services/houseService"
+	"avito/internal/services/outboxService"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -25,38 +20,30 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"avito/internal/lib/logger"
-	"avito/internal/setup"
 )
 
-func TestRegisterMod(t *testing.T) {
-	log := logger.SetupLogger("debug")
+// firstFlatPage is what the repository gets for /house/{id} without listing parameters
+var firstFlatPage = models.FlatPage{SortBy: models.FlatSortID, Limit: common.DefaultLimit + 1}
 
+func TestRegisterMod(t *testing.T) {
 	authRepoMock := mocks.NewAuthRepo(t)
-	houseRepoMock := mocks.NewHouseRepo(t)
-	flatRepoMock := mocks.NewFlatRepo(t)
 
-	authRepoMock.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.User")).
+	authRepoMock.On("CreateUserWithInvite", mock.Anything, mock.AnythingOfType("*models.User"), mock.AnythingOfType("string")).
 		Return("1", nil)
 
-	authS := authService.NewService(authRepoMock, "jwt_secret", log)
-	houseS := houseService.NewService(houseRepoMock, log)
-	flatS := flatService.NewService(flatRepoMock, log)
-
-	authH := authHandler.NewHandler(authS, log)
-	houseH := houseHandler.NewHandler(houseS, log)
-	flatH := flatHandler.NewHandler(flatS, log)
-
-	router := setup.SetupRouter(authH, houseH, flatH, log)
+	router, _ := newTestRouter(t, testDeps{authRepo: authRepoMock})
 
 	t.Run("Register moderator", func(t *testing.T) {
 		body := `{
-            "id_user": "moderator@example.com",
-            "password": "pass",
-            "user_type": "moderator"
+            "email": "moderator@example.com",
+            "password": "passw0rd",
+            "user_type": "moderator",
+            "invite_code": "invite"
         }`
 		req := httptest.NewRequest("POST", "/register", strings.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -71,22 +58,20 @@ func TestRegisterMod(t *testing.T) {
 // Тесты для сценариев получения списка квартир
 // /house/{id} "client"
 func TestGetFlatsByHouseIDAsClient(t *testing.T) {
-	log := logger.SetupLogger("debug")
-
 	authRepoMock := mocks.NewAuthRepo(t)
 	houseRepoMock := mocks.NewHouseRepo(t)
-	flatRepoMock := mocks.NewFlatRepo(t)
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
 
-	authRepoMock.On("GetUserByEmail", mock.Anything, "client-uuid").
+	authRepoMock.On("GetUserByID", mock.Anything, "client-uuid").
 		Return(&models.User{
 			ID:       "client-uuid",
 			Password: string(hashedPassword),
 			Role:     "client",
 		}, nil)
+	authRepoMock.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
 
-	houseRepoMock.On("GetFlatsByHouseID", mock.Anything, 12345, "client").
+	houseRepoMock.On("GetFlatsByHouseID", mock.Anything, 12345, "client-uuid", models.RoleClient, false, firstFlatPage).
 		Return([]models.Flat{
 			{
 				ID:      123456,
@@ -97,15 +82,7 @@ func TestGetFlatsByHouseIDAsClient(t *testing.T) {
 			},
 		}, nil)
 
-	authS := authService.NewService(authRepoMock, "jwt_secret", log)
-	houseS := houseService.NewService(houseRepoMock, log)
-	flatS := flatService.NewService(flatRepoMock, log)
-
-	authH := authHandler.NewHandler(authS, log)
-	houseH := houseHandler.NewHandler(houseS, log)
-	flatH := flatHandler.NewHandler(flatS, log)
-
-	router := setup.SetupRouter(authH, houseH, flatH, log)
+	router, _ := newTestRouter(t, testDeps{authRepo: authRepoMock, houseRepo: houseRepoMock})
 
 	t.Run("Login as client", func(t *testing.T) {
 		body := `{
@@ -155,22 +132,20 @@ func TestGetFlatsByHouseIDAsClient(t *testing.T) {
 // Тесты для сценариев получения списка квартир
 // /house/{id} "moderator"
 func TestGetFlatsByHouseIDAsModerator(t *testing.T) {
-	log := logger.SetupLogger("debug")
-
 	authRepoMock := mocks.NewAuthRepo(t)
 	houseRepoMock := mocks.NewHouseRepo(t)
-	flatRepoMock := mocks.NewFlatRepo(t)
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
 
-	authRepoMock.On("GetUserByEmail", mock.Anything, "moderator-uuid").
+	authRepoMock.On("GetUserByID", mock.Anything, "moderator-uuid").
 		Return(&models.User{
 			ID:       "moderator-uuid",
 			Password: string(hashedPassword),
 			Role:     "moderator",
 		}, nil)
+	authRepoMock.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
 
-	houseRepoMock.On("GetFlatsByHouseID", mock.Anything, 12345, "moderator").
+	houseRepoMock.On("GetFlatsByHouseID", mock.Anything, 12345, "moderator-uuid", models.RoleModerator, false, firstFlatPage).
 		Return([]models.Flat{
 			{
 				ID:      123456,
@@ -188,15 +163,7 @@ func TestGetFlatsByHouseIDAsModerator(t *testing.T) {
 			},
 		}, nil)
 
-	authS := authService.NewService(authRepoMock, "jwt_secret", log)
-	houseS := houseService.NewService(houseRepoMock, log)
-	flatS := flatService.NewService(flatRepoMock, log)
-
-	authH := authHandler.NewHandler(authS, log)
-	houseH := houseHandler.NewHandler(houseS, log)
-	flatH := flatHandler.NewHandler(flatS, log)
-
-	router := setup.SetupRouter(authH, houseH, flatH, log)
+	router, _ := newTestRouter(t, testDeps{authRepo: authRepoMock, houseRepo: houseRepoMock})
 
 	t.Run("Login as moderator", func(t *testing.T) {
 		body := `{
@@ -251,35 +218,28 @@ func TestGetFlatsByHouseIDAsModerator(t *testing.T) {
 
 // тесты для сценариев публикации новой квартиры
 func TestCreateHouseFlat(t *testing.T) {
-	log := logger.SetupLogger("debug")
-
 	authRepoMock := mocks.NewAuthRepo(t)
 	houseRepoMock := mocks.NewHouseRepo(t)
 	flatRepoMock := mocks.NewFlatRepo(t)
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
 
-	authRepoMock.On("GetUserByEmail", mock.Anything, "cae36e0f-69e5-4fa8-a179-a52d083c5549").
+	authRepoMock.On("GetUserByID", mock.Anything, "cae36e0f-69e5-4fa8-a179-a52d083c5549").
 		Return(&models.User{
 			ID:       "cae36e0f-69e5-4fa8-a179-a52d083c5549",
 			Password: string(hashedPassword),
 			Role:     "moderator",
 		}, nil)
+	authRepoMock.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
 
+	houseRepoMock.On("FindHouseByAddress", mock.Anything, "москва, улица лесная, дом 7").
+		Return(nil, nil)
 	houseRepoMock.On("CreateHouse", mock.Anything, mock.AnythingOfType("*models.House")).
 		Return(nil)
 	flatRepoMock.On("CreateFlat", mock.Anything, mock.AnythingOfType("*models.Flat")).
 		Return(123456, nil)
 
-	authS := authService.NewService(authRepoMock, "jwt_secret", log)
-	houseS := houseService.NewService(houseRepoMock, log)
-	flatS := flatService.NewService(flatRepoMock, log)
-
-	authH := authHandler.NewHandler(authS, log)
-	houseH := houseHandler.NewHandler(houseS, log)
-	flatH := flatHandler.NewHandler(flatS, log)
-
-	router := setup.SetupRouter(authH, houseH, flatH, log)
+	router, _ := newTestRouter(t, testDeps{authRepo: authRepoMock, houseRepo: houseRepoMock, flatRepo: flatRepoMock})
 
 	var token string
 
@@ -378,40 +338,43 @@ func TestUpdateFlatStatus(t *testing.T) {
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("qwerty"), bcrypt.DefaultCost)
 
-	authRepoMock.On("GetUserByEmail", mock.Anything, "cae36e0f-69e5-4fa8-a179-a52d083c5549").
+	authRepoMock.On("GetUserByID", mock.Anything, "cae36e0f-69e5-4fa8-a179-a52d083c5549").
 		Return(&models.User{
 			ID:       "cae36e0f-69e5-4fa8-a179-a52d083c5549",
 			Password: string(hashedPassword),
 			Role:     "moderator",
 		}, nil)
-
-	flatRepoMock.On("UpdateFlatStatus", mock.Anything, 123456, "approved", mock.Anything).
+	authRepoMock.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+
+	flatRepoMock.On("UpdateFlatStatus", mock.Anything, models.StatusUpdate{
+		FlatID:  123456,
+		From:    "on moderation",
+		To:      "approved",
+		ActorID: "cae36e0f-69e5-4fa8-a179-a52d083c5549",
+	}).
 		Return(&models.Flat{
 			ID:      123456,
 			HouseID: 12345,
 			Price:   10000,
 			Rooms:   4,
 			Status:  "approved",
-		}, nil)
+		}, true, nil)
 
-	flatRepoMock.On("GetFlatByID", mock.Anything, 123456).
+	moderatorID := "cae36e0f-69e5-4fa8-a179-a52d083c5549"
+	flatRepoMock.On("GetFlatByID", mock.Anything, 123456, false).
 		Return(&models.Flat{
-			ID:      123456,
-			HouseID: 12345,
-			Price:   10000,
-			Rooms:   4,
-			Status:  "created",
+			ID:          123456,
+			HouseID:     12345,
+			Price:       10000,
+			Rooms:       4,
+			Status:      "on moderation",
+			ModeratorID: &moderatorID,
 		}, nil)
 
-	authS := authService.NewService(authRepoMock, "jwt_secret", log)
-	houseS := houseService.NewService(houseRepoMock, log)
-	flatS := flatService.NewService(flatRepoMock, log)
+	houseRepoMock.On("GetSubscribers", mock.Anything, 12345).
+		Return([]string{"subscriber@example.com"}, nil)
 
-	authH := authHandler.NewHandler(authS, log)
-	houseH := houseHandler.NewHandler(houseS, log)
-	flatH := flatHandler.NewHandler(flatS, log)
-
-	router := setup.SetupRouter(authH, houseH, flatH, log)
+	router, _ := newTestRouter(t, testDeps{authRepo: authRepoMock, houseRepo: houseRepoMock, flatRepo: flatRepoMock})
 
 	var token string
 
@@ -476,6 +439,138 @@ func TestUpdateFlatStatus(t *testing.T) {
 	})
 }
 
+func TestSubscribeToHouse(t *testing.T) {
+	houseRepoMock := mocks.NewHouseRepo(t)
+
+	houseRepoMock.On("SubscribeToHouse", mock.Anything, 12345, "subscriber@example.com").
+		Return(nil)
+
+	router, authS := newTestRouter(t, testDeps{houseRepo: houseRepoMock})
+
+	token, err := authS.GenerateToken("client-uuid", "client")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	t.Run("Subscribe with valid email", func(t *testing.T) {
+		body := `{"email": "subscriber@example.com"}`
+		req := httptest.NewRequest("POST", "/house/12345/subscribe", strings.NewReader(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Subscribe with invalid email", func(t *testing.T) {
+		body := `{"email": "not-an-email"}`
+		req := httptest.NewRequest("POST", "/house/12345/subscribe", strings.NewReader(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
+// /me/flats
+func TestGetMyFlats(t *testing.T) {
+	flatRepoMock := mocks.NewFlatRepo(t)
+
+	ownerID := "client-uuid"
+	reason := models.DeclineReasonDuplicate
+
+	flatRepoMock.On("GetFlatsByOwner", mock.Anything, ownerID, false).
+		Return([]models.Flat{
+			{ID: 2, HouseID: 12345, Price: 15000, Rooms: 5, Status: "declined", CreatedBy: &ownerID, DeclineReason: &reason},
+			{ID: 1, HouseID: 12345, Price: 10000, Rooms: 4, Status: "created", CreatedBy: &ownerID},
+		}, nil)
+
+	router, authS := newTestRouter(t, testDeps{flatRepo: flatRepoMock})
+
+	token, err := authS.GenerateToken(ownerID, "client")
+	if err != nil {
+		t.Fatal("Failed to generate token:", err)
+	}
+
+	req := httptest.NewRequest("GET", "/me/flats", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var actualResponse map[string][]response.FlatResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &actualResponse); err != nil {
+		t.Fatal("Failed to unmarshal response:", err)
+	}
+
+	flats := actualResponse["flats"]
+	if assert.Len(t, flats, 2) {
+		assert.Equal(t, "declined", flats[0].Status)
+		assert.Equal(t, &reason, flats[0].DeclineReason)
+		assert.Equal(t, "created", flats[1].Status)
+	}
+}
+
+type failingPublisher struct {
+	failOn    int64
+	published []int64
+}
+
+func (p *failingPublisher) Publish(_ context.Context, event models.Event) error {
+	if event.ID == p.failOn {
+		return errors.New("broker is unavailable")
+	}
+	p.published = append(p.published, event.ID)
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	log := logger.SetupLogger("debug")
+
+	events := []models.Event{
+		{ID: 1, Type: models.EventHouseCreated, AggregateID: 5},
+		{ID: 2, Type: models.EventFlatCreated, AggregateID: 123456},
+		{ID: 3, Type: models.EventFlatStatusChanged, AggregateID: 123456},
+	}
+
+	t.Run("Publishes and marks every event", func(t *testing.T) {
+		outboxRepoMock := mocks.NewOutboxRepo(t)
+		outboxRepoMock.On("FetchUnpublished", mock.Anything, 10).Return(events, nil)
+		outboxRepoMock.On("MarkPublished", mock.Anything, mock.AnythingOfType("int64")).Return(nil).Times(3)
+
+		pub := &failingPublisher{}
+		relay := outboxService.NewRelay(outboxRepoMock, pub, time.Second, 10, log)
+
+		published, err := relay.ProcessBatch(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, published)
+		assert.Equal(t, []int64{1, 2, 3}, pub.published)
+	})
+
+	t.Run("Stops at the first failed event", func(t *testing.T) {
+		outboxRepoMock := mocks.NewOutboxRepo(t)
+		outboxRepoMock.On("FetchUnpublished", mock.Anything, 10).Return(events, nil)
+		outboxRepoMock.On("MarkPublished", mock.Anything, int64(1)).Return(nil).Once()
+
+		pub := &failingPublisher{failOn: 2}
+		relay := outboxService.NewRelay(outboxRepoMock, pub, time.Second, 10, log)
+
+		published, err := relay.ProcessBatch(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, published)
+		assert.Equal(t, []int64{1}, pub.published)
+	})
+}
+
 func TestWriteErrorResponse(t *testing.T) {
 	var logBuffer bytes.Buffer
 	logger := slog.New(slog.NewTextHandler(&logBuffer, &slog.HandlerOptions{}))
@@ -510,11 +605,13 @@ func TestWriteErrorResponse(t *testing.T) {
 }
 
 func extractTokenFromResponse(response string) string {
-	var result map[string]string
+	var result struct {
+		Token string `json:"token"`
+	}
 	err := json.Unmarshal([]byte(response), &result)
 	if err != nil {
 		logOff.Println("extractTokenFromResponse: err", err)
 		return ""
 	}
-	return result["token"]
+	return result.Token
 }